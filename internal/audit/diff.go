@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// Diff compares the JSON representations of before and after and returns
+// the top-level fields that differ, sorted by name. A nil before or after
+// stands for a created or deleted target.
+func Diff(before, after interface{}) ([]Change, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(b)+len(a))
+	for name := range b {
+		names = append(names, name)
+	}
+	for name := range a {
+		if _, ok := b[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []Change
+	for _, name := range names {
+		if bytes.Equal(b[name], a[name]) {
+			continue
+		}
+		changes = append(changes, Change{Field: name, Before: b[name], After: a[name]})
+	}
+	return changes, nil
+}
+
+// fields splits the JSON object form of v into its members
+func fields(v interface{}) (map[string]json.RawMessage, error) {
+	m := make(map[string]json.RawMessage)
+	if v == nil {
+		return m, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(data, []byte("null")) {
+		return m, nil
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type diffTarget struct {
+	Name string   `json:"name"`
+	Age  int      `json:"age,omitempty"`
+	Tags []string `json:"tags"`
+}
+
+func TestDiff(t *testing.T) {
+	raw := func(s string) json.RawMessage { return json.RawMessage(s) }
+
+	tests := []struct {
+		name          string
+		before, after interface{}
+		want          []Change
+	}{
+		{
+			name:   "unchanged",
+			before: diffTarget{Name: "a", Tags: []string{"x"}},
+			after:  diffTarget{Name: "a", Tags: []string{"x"}},
+		},
+		{
+			name:   "created",
+			before: nil,
+			after:  diffTarget{Name: "a", Age: 3},
+			want: []Change{
+				{Field: "age", After: raw("3")},
+				{Field: "name", After: raw(`"a"`)},
+				{Field: "tags", After: raw("null")},
+			},
+		},
+		{
+			name:   "deleted",
+			before: &diffTarget{Name: "a"},
+			after:  nil,
+			want: []Change{
+				{Field: "name", Before: raw(`"a"`)},
+				{Field: "tags", Before: raw("null")},
+			},
+		},
+		{
+			name:   "typed nil pointer is treated as absent",
+			before: (*diffTarget)(nil),
+			after:  &diffTarget{Name: "a", Tags: []string{}},
+			want: []Change{
+				{Field: "name", After: raw(`"a"`)},
+				{Field: "tags", After: raw("[]")},
+			},
+		},
+		{
+			name:   "changed fields sorted by name",
+			before: diffTarget{Name: "a", Age: 3, Tags: []string{"x"}},
+			after:  diffTarget{Name: "b", Age: 3, Tags: []string{"x", "y"}},
+			want: []Change{
+				{Field: "name", Before: raw(`"a"`), After: raw(`"b"`)},
+				{Field: "tags", Before: raw(`["x"]`), After: raw(`["x","y"]`)},
+			},
+		},
+		{
+			name:   "omitted field appears and disappears",
+			before: map[string]interface{}{"a": 1, "b": 2},
+			after:  map[string]interface{}{"b": 2, "c": 3},
+			want: []Change{
+				{Field: "a", Before: raw("1")},
+				{Field: "c", After: raw("3")},
+			},
+		},
+		{
+			name:   "nested values compare as a whole",
+			before: map[string]interface{}{"owner": map[string]int{"id": 1}},
+			after:  map[string]interface{}{"owner": map[string]int{"id": 2}},
+			want:   []Change{{Field: "owner", Before: raw(`{"id":1}`), After: raw(`{"id":2}`)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Diff(tt.before, tt.after)
+			if err != nil {
+				t.Fatalf("Diff() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %s, want %s", changesJSON(got), changesJSON(tt.want))
+			}
+		})
+	}
+}
+
+func TestDiffRejectsNonObjects(t *testing.T) {
+	for _, v := range []interface{}{42, "text", []int{1}, func() {}} {
+		if _, err := Diff(nil, v); err == nil {
+			t.Errorf("Diff(nil, %T) returned no error", v)
+		}
+	}
+}
+
+func changesJSON(changes []Change) string {
+	data, _ := json.Marshal(changes)
+	return string(data)
+}
@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	appErr "example.com/myapp/internal/errors"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the audit routes with appropriate middleware
+// Middleware is passed as parameters to avoid circular imports
+func (h *Handler) RegisterRoutes(r chi.Router, loggingMw, authMw, adminMw func(http.Handler) http.Handler) {
+	r.Route("/audit", func(r chi.Router) {
+		r.Use(loggingMw)
+		r.Use(authMw)
+		r.Use(adminMw)
+
+		r.Get("/", h.QueryAudit)
+	})
+}
+
+// QueryAudit lists audit entries, newest first -
+// GET /audit?actor=&target_type=&target_id=&from=&to=&limit=
+func (h *Handler) QueryAudit(w http.ResponseWriter, r *http.Request) {
+	filter, err := ParseFilter(r.URL.Query())
+	if err != nil {
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	entries, err := h.service.Query(r.Context(), *filter)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to query audit log", "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	response := &EntryListResponse{
+		Total:   len(entries),
+		Entries: entries,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
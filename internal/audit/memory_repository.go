@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"context"
+	"sync"
+
+	appErr "example.com/myapp/internal/errors"
+)
+
+// InMemoryRepository is an in-memory implementation of Repository
+type InMemoryRepository struct {
+	mu      sync.RWMutex
+	entries []*Entry
+}
+
+// NewInMemoryRepository creates a new in-memory audit repository
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{}
+}
+
+// Append adds an entry to the end of the log
+func (r *InMemoryRepository) Append(ctx context.Context, entry *Entry) error {
+	if err := ctx.Err(); err != nil {
+		return appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.ID = int64(len(r.entries)) + 1
+	stored := *entry
+	stored.Changes = append([]Change(nil), entry.Changes...)
+	r.entries = append(r.entries, &stored)
+	return nil
+}
+
+// Query scans the log from the newest entry backwards
+func (r *InMemoryRepository) Query(ctx context.Context, filter Filter) ([]*Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := make([]*Entry, 0)
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(entries) >= filter.Limit {
+			break
+		}
+		if filter.Matches(r.entries[i]) {
+			e := *r.entries[i]
+			entries = append(entries, &e)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Actions recorded by the services
+const (
+	ActionUserCreate  = "user.create"
+	ActionUserUpdate  = "user.update"
+	ActionUserDelete  = "user.delete"
+	ActionMediaCreate = "media.create"
+	ActionMediaUpdate = "media.update"
+	ActionMediaDelete = "media.delete"
+)
+
+// Target types
+const (
+	TargetUser  = "user"
+	TargetMedia = "media"
+)
+
+// Entry is one immutable record in the audit log
+type Entry struct {
+	ID         int64     `json:"id"`
+	Timestamp  time.Time `json:"timestamp"`
+	Actor      string    `json:"actor"`
+	Action     string    `json:"action"`
+	TargetType string    `json:"target_type"`
+	TargetID   string    `json:"target_id"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Changes    []Change  `json:"changes,omitempty"`
+}
+
+// Change is the before and after value of one top-level field. Before is
+// omitted for created fields and After for removed ones.
+type Change struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Filter selects audit entries. Empty fields match everything; From is
+// inclusive and To exclusive.
+type Filter struct {
+	Actor      string
+	TargetType string
+	TargetID   string
+	From       time.Time
+	To         time.Time
+	Limit      int
+}
+
+// Matches reports whether e satisfies every criterion of f except Limit
+func (f Filter) Matches(e *Entry) bool {
+	switch {
+	case f.Actor != "" && e.Actor != f.Actor:
+		return false
+	case f.TargetType != "" && e.TargetType != f.TargetType:
+		return false
+	case f.TargetID != "" && e.TargetID != f.TargetID:
+		return false
+	case !f.From.IsZero() && e.Timestamp.Before(f.From):
+		return false
+	case !f.To.IsZero() && !e.Timestamp.Before(f.To):
+		return false
+	}
+	return true
+}
+
+// EntryListResponse is the response when querying the audit log
+type EntryListResponse struct {
+	Total   int      `json:"total"`
+	Entries []*Entry `json:"entries"`
+}
@@ -0,0 +1,12 @@
+package audit
+
+import "context"
+
+// Repository defines the interface for audit log persistence. The log is
+// append-only: entries can never be modified or removed.
+type Repository interface {
+	// Append assigns the entry its ID and stores it
+	Append(ctx context.Context, entry *Entry) error
+	// Query returns matching entries, newest first, up to filter.Limit
+	Query(ctx context.Context, filter Filter) ([]*Entry, error)
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"example.com/myapp/internal/auth"
+	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/requestid"
+)
+
+// Query limits
+const (
+	DefaultQueryLimit = 100
+	MaxQueryLimit     = 1000
+)
+
+// Recorder records mutations of domain objects. Services depend on this
+// interface rather than on Service directly.
+type Recorder interface {
+	// Record stores who performed action on the target and what changed.
+	// before is nil for creations and after is nil for deletions.
+	Record(ctx context.Context, action, targetType, targetID string, before, after interface{})
+}
+
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+// Record appends an entry for a completed mutation. The actor and request
+// ID are taken from ctx. Failures are logged rather than returned because
+// the mutation has already been applied.
+func (s *Service) Record(ctx context.Context, action, targetType, targetID string, before, after interface{}) {
+	changes, err := Diff(before, after)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to diff audited target", "action", action, "target_id", targetID, "error", err)
+	}
+
+	entry := &Entry{
+		Timestamp:  time.Now().UTC(),
+		Actor:      auth.FromContext(ctx).ID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		RequestID:  requestid.FromContext(ctx),
+		Changes:    changes,
+	}
+
+	// The entry must be written even if the request is cancelled now
+	if err := s.repo.Append(context.WithoutCancel(ctx), entry); err != nil {
+		slog.ErrorContext(ctx, "Failed to append audit entry", "action", action, "target_id", targetID, "error", err)
+	}
+}
+
+// Query returns audit entries matching filter, newest first
+func (s *Service) Query(ctx context.Context, filter Filter) ([]*Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	entries, err := s.repo.Query(ctx, filter)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to query audit log", "error", err)
+		return nil, appErr.Internal("failed to query audit log", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,29 @@
+package audit
+
+import (
+	"net/url"
+
+	"example.com/myapp/internal/validation"
+)
+
+// ParseFilter validates the query parameters of GET /audit
+func ParseFilter(values url.Values) (*Filter, error) {
+	v := validation.New()
+
+	filter := &Filter{
+		Actor:      values.Get("actor"),
+		TargetType: values.Get("target_type"),
+		TargetID:   values.Get("target_id"),
+		From:       v.Time("from", values.Get("from")),
+		To:         v.Time("to", values.Get("to")),
+		Limit:      v.Int("limit", values.Get("limit"), DefaultQueryLimit),
+	}
+	v.OneOf("target_type", filter.TargetType, TargetUser, TargetMedia)
+	v.Range("limit", filter.Limit, 1, MaxQueryLimit)
+	v.Check(filter.From.IsZero() || filter.To.IsZero() || filter.From.Before(filter.To), "to", "must be after from")
+
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
@@ -0,0 +1,112 @@
+// Package auth carries the authenticated caller through request contexts.
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Anonymous identifies requests without credentials
+const Anonymous = "anonymous"
+
+// SessionCookie is the cookie browsers authenticate with in place of an
+// Authorization header
+const SessionCookie = "session"
+
+// Principal is the caller a request is made on behalf of
+type Principal struct {
+	ID string
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, or the anonymous
+// principal when there is none
+func FromContext(ctx context.Context) Principal {
+	if p, ok := ctx.Value(contextKey{}).(Principal); ok {
+		return p
+	}
+	return Principal{ID: Anonymous}
+}
+
+// ParseAuthorization derives the principal from an Authorization header.
+// Bearer tokens identify the caller directly; credentials are not verified.
+func ParseAuthorization(header string) Principal {
+	token := strings.TrimSpace(header)
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+	if token == "" {
+		return Principal{ID: Anonymous}
+	}
+	return Principal{ID: token}
+}
+
+// Authenticate verifies the credentials of r: a bearer token in the
+// Authorization header or, without one, a token in the session cookie.
+// Requests without credentials are anonymous; credentials that do not
+// verify are an error, never a fallback to anonymous. Without a secret
+// tokens cannot be verified, so credentials identify the caller directly
+// as they did before tokens were signed.
+func Authenticate(r *http.Request, secret []byte, now time.Time) (Principal, error) {
+	if len(secret) == 0 {
+		return unverified(r), nil
+	}
+	if header := strings.TrimSpace(r.Header.Get("Authorization")); header != "" {
+		scheme, token, _ := strings.Cut(header, " ")
+		if !strings.EqualFold(scheme, "bearer") {
+			return Principal{}, ErrInvalidToken
+		}
+		return VerifyToken(secret, strings.TrimSpace(token), now)
+	}
+	if session, ok := Session(r); ok {
+		return VerifyToken(secret, session, now)
+	}
+	return Principal{ID: Anonymous}, nil
+}
+
+// FromRequest returns the principal the Authenticate middleware stored for
+// r. Requests that did not pass through it are read from their credentials
+// without verification, as if no token secret were configured.
+func FromRequest(r *http.Request) Principal {
+	if p, ok := r.Context().Value(contextKey{}).(Principal); ok {
+		return p
+	}
+	return unverified(r)
+}
+
+// unverified derives the principal from the Authorization header, or from
+// the session cookie when there is no header, taking either at face value
+func unverified(r *http.Request) Principal {
+	if session, ok := Session(r); ok {
+		return Principal{ID: session}
+	}
+	return ParseAuthorization(r.Header.Get("Authorization"))
+}
+
+// Session returns the session cookie of a request authenticated by it.
+// Browsers attach cookies to cross-site requests on their own, so these
+// requests need CSRF protection; requests with an Authorization header do
+// not.
+func Session(r *http.Request) (string, bool) {
+	if r.Header.Get("Authorization") != "" {
+		return "", false
+	}
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil || strings.TrimSpace(cookie.Value) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(cookie.Value), true
+}
+
+// IsAnonymous reports whether p carries no credentials
+func (p Principal) IsAnonymous() bool {
+	return p.ID == Anonymous
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testSecret = []byte("0123456789abcdef0123456789abcdef")
+	testNow    = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+)
+
+func mustIssue(t *testing.T, secret []byte, subject string, expires time.Time) string {
+	t.Helper()
+	token, err := IssueToken(secret, subject, expires)
+	if err != nil {
+		t.Fatalf("IssueToken(%q) error = %v", subject, err)
+	}
+	return token
+}
+
+func TestIssueToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  []byte
+		subject string
+		wantErr error
+	}{
+		{name: "valid", secret: testSecret, subject: "alice"},
+		{name: "no secret", secret: nil, subject: "alice", wantErr: ErrNotConfigured},
+		{name: "empty subject", secret: testSecret, subject: " ", wantErr: ErrInvalidSubject},
+		{name: "anonymous subject", secret: testSecret, subject: Anonymous, wantErr: ErrInvalidSubject},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := IssueToken(tt.secret, tt.subject, testNow.Add(time.Hour))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("IssueToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyToken(t *testing.T) {
+	valid := mustIssue(t, testSecret, "alice", testNow.Add(time.Hour))
+	payload, sig, _ := strings.Cut(valid, ".")
+	forged := mustIssue(t, []byte("another-secret-of-32-characters!"), "alice", testNow.Add(time.Hour))
+	other := mustIssue(t, testSecret, "mallory", testNow.Add(time.Hour))
+	otherPayload, _, _ := strings.Cut(other, ".")
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		token   string
+		want    string
+		wantErr error
+	}{
+		{name: "valid", secret: testSecret, token: valid, want: "alice"},
+		{name: "expired", secret: testSecret, token: mustIssue(t, testSecret, "alice", testNow), wantErr: ErrTokenExpired},
+		{name: "no secret", secret: nil, token: valid, wantErr: ErrNotConfigured},
+		{name: "signed with another secret", secret: testSecret, token: forged, wantErr: ErrInvalidToken},
+		{name: "payload swapped", secret: testSecret, token: otherPayload + "." + sig, wantErr: ErrInvalidToken},
+		{name: "signature missing", secret: testSecret, token: payload, wantErr: ErrInvalidToken},
+		{name: "signature not base64", secret: testSecret, token: payload + ".!!", wantErr: ErrInvalidToken},
+		{name: "raw principal", secret: testSecret, token: "admin", wantErr: ErrInvalidToken},
+		{name: "empty", secret: testSecret, token: "", wantErr: ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyToken(tt.secret, tt.token, testNow)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if got.ID != tt.want {
+				t.Errorf("VerifyToken() = %q, want %q", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	alice := mustIssue(t, testSecret, "alice", testNow.Add(time.Hour))
+	bob := mustIssue(t, testSecret, "bob", testNow.Add(time.Hour))
+
+	tests := []struct {
+		name          string
+		noSecret      bool
+		authorization string
+		cookie        string
+		want          string
+		wantErr       error
+	}{
+		{name: "no credentials", want: Anonymous},
+		{name: "bearer token", authorization: "Bearer " + alice, want: "alice"},
+		{name: "scheme is case-insensitive", authorization: "bearer " + alice, want: "alice"},
+		{name: "session cookie", cookie: bob, want: "bob"},
+		{name: "header wins over cookie", authorization: "Bearer " + alice, cookie: bob, want: "alice"},
+		{name: "unsigned bearer", authorization: "Bearer admin", wantErr: ErrInvalidToken},
+		{name: "other scheme", authorization: "Basic YWRtaW46YWRtaW4=", wantErr: ErrInvalidToken},
+		{name: "bare token", authorization: alice, wantErr: ErrInvalidToken},
+		{name: "unsigned cookie", cookie: "admin", wantErr: ErrInvalidToken},
+		{name: "invalid header does not fall back to cookie", authorization: "Bearer admin", cookie: bob, wantErr: ErrInvalidToken},
+		{name: "no secret takes bearer at face value", noSecret: true, authorization: "Bearer admin", want: "admin"},
+		{name: "no secret takes cookie at face value", noSecret: true, cookie: "admin", want: "admin"},
+		{name: "no secret without credentials", noSecret: true, want: Anonymous},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: SessionCookie, Value: tt.cookie})
+			}
+
+			secret := testSecret
+			if tt.noSecret {
+				secret = nil
+			}
+			got, err := Authenticate(r, secret, testNow)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+			if got.ID != tt.want {
+				t.Errorf("Authenticate() = %q, want %q", got.ID, tt.want)
+			}
+		})
+	}
+}
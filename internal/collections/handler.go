@@ -0,0 +1,210 @@
+package collections
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	appErr "example.com/myapp/internal/errors"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers all collection-related routes with appropriate middleware
+// Middleware is passed as parameters to avoid circular imports
+func (h *Handler) RegisterRoutes(r chi.Router, loggingMw, authMw func(http.Handler) http.Handler) {
+	r.Route("/collections", func(r chi.Router) {
+		// Middleware for ALL collection routes
+		r.Use(loggingMw)
+		r.Use(authMw)
+
+		r.Post("/", h.CreateCollection)
+		r.Get("/", h.GetAllCollections)
+
+		r.Route("/{id}", func(r chi.Router) {
+			r.Get("/", h.GetCollection)
+			r.Put("/", h.UpdateCollection)
+			r.Delete("/", h.DeleteCollection)
+
+			r.Post("/media", h.AddMedia)
+			r.Put("/media", h.ReorderMedia)
+			r.Delete("/media/{mediaID}", h.RemoveMedia)
+			r.Put("/cover", h.SetCover)
+		})
+	})
+}
+
+// CreateCollection creates a new collection - POST /collections
+func (h *Handler) CreateCollection(w http.ResponseWriter, r *http.Request) {
+	var req CreateCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to decode request", "error", err)
+		appErr.WriteError(w, r, appErr.BadRequest("invalid request body"))
+		return
+	}
+
+	collection, err := h.service.CreateCollection(r.Context(), &req)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create collection", "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Collection created", "id", collection.ID)
+	respondJSON(w, http.StatusCreated, collection)
+}
+
+// GetAllCollections lists all collections - GET /collections
+func (h *Handler) GetAllCollections(w http.ResponseWriter, r *http.Request) {
+	collections, err := h.service.GetAllCollections(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get all collections", "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, collections)
+}
+
+// GetCollection retrieves a collection with its ordered media - GET /collections/{id}
+func (h *Handler) GetCollection(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	collection, err := h.service.GetCollection(r.Context(), id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get collection", "id", id, "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, collection)
+}
+
+// UpdateCollection renames a collection - PUT /collections/{id}
+func (h *Handler) UpdateCollection(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req UpdateCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to decode request", "error", err)
+		appErr.WriteError(w, r, appErr.BadRequest("invalid request body"))
+		return
+	}
+
+	collection, err := h.service.UpdateCollection(r.Context(), id, &req)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to update collection", "id", id, "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Collection updated", "id", id)
+	respondJSON(w, http.StatusOK, collection)
+}
+
+// DeleteCollection deletes a collection - DELETE /collections/{id}
+func (h *Handler) DeleteCollection(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := h.service.DeleteCollection(r.Context(), id); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to delete collection", "id", id, "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Collection deleted", "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddMedia adds a media item to a collection - POST /collections/{id}/media
+func (h *Handler) AddMedia(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req AddMediaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to decode request", "error", err)
+		appErr.WriteError(w, r, appErr.BadRequest("invalid request body"))
+		return
+	}
+
+	collection, err := h.service.AddMedia(r.Context(), id, &req)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to add media to collection", "id", id, "media_id", req.MediaID, "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, collection)
+}
+
+// ReorderMedia sets the order of a collection's media - PUT /collections/{id}/media
+func (h *Handler) ReorderMedia(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req ReorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to decode request", "error", err)
+		appErr.WriteError(w, r, appErr.BadRequest("invalid request body"))
+		return
+	}
+
+	collection, err := h.service.ReorderMedia(r.Context(), id, &req)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to reorder collection", "id", id, "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, collection)
+}
+
+// RemoveMedia removes a media item from a collection - DELETE /collections/{id}/media/{mediaID}
+func (h *Handler) RemoveMedia(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	mediaID := chi.URLParam(r, "mediaID")
+
+	collection, err := h.service.RemoveMedia(r.Context(), id, mediaID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to remove media from collection", "id", id, "media_id", mediaID, "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, collection)
+}
+
+// SetCover selects the cover image of a collection - PUT /collections/{id}/cover
+func (h *Handler) SetCover(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req SetCoverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to decode request", "error", err)
+		appErr.WriteError(w, r, appErr.BadRequest("invalid request body"))
+		return
+	}
+
+	collection, err := h.service.SetCover(r.Context(), id, &req)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to set collection cover", "id", id, "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, collection)
+}
+
+// Helper functions
+
+func respondJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,44 @@
+package collections
+
+import (
+	"context"
+
+	"example.com/myapp/internal/metrics"
+)
+
+// instrumentedRepository records the latency of every call to the wrapped
+// repository and traces it as a child span of the caller
+type instrumentedRepository struct {
+	next Repository
+}
+
+// NewInstrumentedRepository wraps repo with operation metrics and spans
+func NewInstrumentedRepository(repo Repository) Repository {
+	return &instrumentedRepository{next: repo}
+}
+
+func (r *instrumentedRepository) Save(ctx context.Context, collection *Collection) error {
+	return metrics.ObserveRepositoryCall(ctx, "collections", "save", func(ctx context.Context) error {
+		return r.next.Save(ctx, collection)
+	})
+}
+
+func (r *instrumentedRepository) GetByID(ctx context.Context, id string) (*Collection, error) {
+	return metrics.ObserveRepository(ctx, "collections", "get_by_id", func(ctx context.Context) (*Collection, error) {
+		return r.next.GetByID(ctx, id)
+	})
+}
+
+func (r *instrumentedRepository) GetAll(ctx context.Context) ([]*Collection, error) {
+	return metrics.ObserveRepository(ctx, "collections", "get_all", r.next.GetAll)
+}
+
+func (r *instrumentedRepository) Delete(ctx context.Context, id string) error {
+	return metrics.ObserveRepositoryCall(ctx, "collections", "delete", func(ctx context.Context) error {
+		return r.next.Delete(ctx, id)
+	})
+}
+
+func (r *instrumentedRepository) Ping(ctx context.Context) error {
+	return metrics.ObserveRepositoryCall(ctx, "collections", "ping", r.next.Ping)
+}
@@ -0,0 +1,111 @@
+package collections
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	appErr "example.com/myapp/internal/errors"
+)
+
+// InMemoryRepository is an in-memory implementation of Repository
+type InMemoryRepository struct {
+	mu          sync.RWMutex
+	collections map[string]*Collection
+}
+
+// NewInMemoryRepository creates a new in-memory collection repository
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		collections: make(map[string]*Collection),
+	}
+}
+
+// Save stores a collection, replacing any existing one with the same ID
+func (r *InMemoryRepository) Save(ctx context.Context, collection *Collection) error {
+	if err := ctx.Err(); err != nil {
+		return appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if collection.ID == "" {
+		return appErr.BadRequest("collection ID is required")
+	}
+
+	r.collections[collection.ID] = cloneCollection(collection)
+	return nil
+}
+
+// GetByID retrieves a collection by ID
+func (r *InMemoryRepository) GetByID(ctx context.Context, id string) (*Collection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	collection, exists := r.collections[id]
+	if !exists {
+		return nil, appErr.NotFound("collection not found")
+	}
+	return cloneCollection(collection), nil
+}
+
+// GetAll retrieves all collections, oldest first
+func (r *InMemoryRepository) GetAll(ctx context.Context) ([]*Collection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]*Collection, 0, len(r.collections))
+	for _, c := range r.collections {
+		list = append(list, cloneCollection(c))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list, nil
+}
+
+// Delete removes a collection
+func (r *InMemoryRepository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.collections[id]; !exists {
+		return appErr.NotFound("collection not found")
+	}
+	delete(r.collections, id)
+	return nil
+}
+
+// Ping checks that the repository lock can be taken, so a deadlocked
+// repository fails readiness
+func (r *InMemoryRepository) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.RLock()
+	r.mu.RUnlock()
+	return nil
+}
+
+// cloneCollection copies a collection so callers never share the stored
+// media ID slice
+func cloneCollection(c *Collection) *Collection {
+	clone := *c
+	clone.MediaIDs = make([]string, len(c.MediaIDs))
+	copy(clone.MediaIDs, c.MediaIDs)
+	return &clone
+}
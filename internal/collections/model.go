@@ -0,0 +1,55 @@
+package collections
+
+import (
+	"time"
+
+	"example.com/myapp/internal/media"
+)
+
+// Collection is an ordered group of media items (an album or gallery)
+type Collection struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description,omitempty"`
+	MediaIDs     []string  `json:"media_ids"`
+	CoverMediaID string    `json:"cover_media_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type CreateCollectionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type UpdateCollectionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type AddMediaRequest struct {
+	MediaID string `json:"media_id"`
+	// Position is the zero-based index to insert at; nil appends to the end
+	Position *int `json:"position,omitempty"`
+}
+
+type ReorderRequest struct {
+	MediaIDs []string `json:"media_ids"`
+}
+
+type SetCoverRequest struct {
+	MediaID string `json:"media_id"`
+}
+
+// CollectionResponse is a collection with its media resolved in order
+type CollectionResponse struct {
+	*Collection
+	Cover *CollectionItem   `json:"cover,omitempty"`
+	Media []*CollectionItem `json:"media"`
+}
+
+// CollectionItem is a media item of a collection with its variants
+type CollectionItem struct {
+	*media.Media
+	Variants []media.Variant `json:"variants"`
+}
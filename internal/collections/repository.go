@@ -0,0 +1,13 @@
+package collections
+
+import "context"
+
+// Repository defines the interface for collection persistence
+type Repository interface {
+	Save(ctx context.Context, collection *Collection) error
+	GetByID(ctx context.Context, id string) (*Collection, error)
+	GetAll(ctx context.Context) ([]*Collection, error)
+	Delete(ctx context.Context, id string) error
+	// Ping reports whether the underlying store can serve requests
+	Ping(ctx context.Context) error
+}
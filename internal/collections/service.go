@@ -0,0 +1,295 @@
+package collections
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/media"
+	"example.com/myapp/internal/validation"
+	"github.com/google/uuid"
+)
+
+// Field limits for collection requests
+const (
+	MaxNameLength        = 200
+	MaxDescriptionLength = 2000
+)
+
+type Service struct {
+	repo      Repository
+	mediaRepo media.Repository
+
+	// mu serializes read-modify-write cycles on collections
+	mu sync.Mutex
+}
+
+func NewService(repo Repository, mediaRepo media.Repository) *Service {
+	return &Service{
+		repo:      repo,
+		mediaRepo: mediaRepo,
+	}
+}
+
+// CreateCollection creates a new empty collection
+func (s *Service) CreateCollection(ctx context.Context, req *CreateCollectionRequest) (*Collection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if err := validateCollectionFields(name, req.Description); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	collection := &Collection{
+		ID:          uuid.New().String(),
+		Name:        name,
+		Description: req.Description,
+		MediaIDs:    []string{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := s.repo.Save(ctx, collection); err != nil {
+		slog.ErrorContext(ctx, "Failed to save collection", "error", err)
+		return nil, appErr.Internal("failed to save collection", err)
+	}
+	return collection, nil
+}
+
+// GetCollection retrieves a collection with its media resolved in order,
+// each with the variants clients can fetch. Media that no longer exist are
+// skipped.
+func (s *Service) GetCollection(ctx context.Context, id string) (*CollectionResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	collection, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get collection", "id", id, "error", err)
+		return nil, err
+	}
+
+	response := &CollectionResponse{
+		Collection: collection,
+		Media:      make([]*CollectionItem, 0, len(collection.MediaIDs)),
+	}
+	for _, mediaID := range collection.MediaIDs {
+		m, err := s.mediaRepo.GetByID(ctx, mediaID)
+		if err != nil {
+			if ae := appErr.GetAppError(err); ae != nil && ae.Code == appErr.ErrCodeNotFound {
+				slog.WarnContext(ctx, "Collection references missing media", "id", id, "media_id", mediaID)
+				continue
+			}
+			return nil, appErr.Internal("failed to load collection media", err)
+		}
+		item := &CollectionItem{Media: m, Variants: m.Variants()}
+		response.Media = append(response.Media, item)
+		if mediaID == collection.CoverMediaID {
+			response.Cover = item
+		}
+	}
+
+	// Fall back to the first item when no explicit cover is set
+	if response.Cover == nil && len(response.Media) > 0 {
+		response.Cover = response.Media[0]
+	}
+	return response, nil
+}
+
+// GetAllCollections retrieves all collections without resolving their media
+func (s *Service) GetAllCollections(ctx context.Context) ([]*Collection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	collections, err := s.repo.GetAll(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get all collections", "error", err)
+		return nil, appErr.Internal("failed to retrieve collections", err)
+	}
+	return collections, nil
+}
+
+// UpdateCollection renames a collection and replaces its description
+func (s *Service) UpdateCollection(ctx context.Context, id string, req *UpdateCollectionRequest) (*Collection, error) {
+	name := strings.TrimSpace(req.Name)
+	if err := validateCollectionFields(name, req.Description); err != nil {
+		return nil, err
+	}
+
+	return s.modify(ctx, id, func(c *Collection) error {
+		c.Name = name
+		c.Description = req.Description
+		return nil
+	})
+}
+
+// DeleteCollection deletes a collection. The media it contains are not affected.
+func (s *Service) DeleteCollection(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return appErr.Internal("context cancelled", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete collection", "id", id, "error", err)
+		return err
+	}
+	return nil
+}
+
+// AddMedia inserts a media item into a collection at the requested position
+func (s *Service) AddMedia(ctx context.Context, id string, req *AddMediaRequest) (*Collection, error) {
+	if err := validation.New().Required("media_id", req.MediaID).Err(); err != nil {
+		return nil, err
+	}
+	if err := s.ensureMediaExists(ctx, req.MediaID); err != nil {
+		return nil, err
+	}
+
+	return s.modify(ctx, id, func(c *Collection) error {
+		if indexOf(c.MediaIDs, req.MediaID) >= 0 {
+			return appErr.Conflict("media is already in the collection")
+		}
+
+		pos := len(c.MediaIDs)
+		if req.Position != nil {
+			if *req.Position < 0 || *req.Position > len(c.MediaIDs) {
+				return appErr.BadRequest("position is out of range")
+			}
+			pos = *req.Position
+		}
+
+		c.MediaIDs = append(c.MediaIDs, "")
+		copy(c.MediaIDs[pos+1:], c.MediaIDs[pos:])
+		c.MediaIDs[pos] = req.MediaID
+		return nil
+	})
+}
+
+// RemoveMedia removes a media item from a collection, clearing the cover if it
+// pointed at that item
+func (s *Service) RemoveMedia(ctx context.Context, id, mediaID string) (*Collection, error) {
+	return s.modify(ctx, id, func(c *Collection) error {
+		i := indexOf(c.MediaIDs, mediaID)
+		if i < 0 {
+			return appErr.NotFound("media is not in the collection")
+		}
+
+		c.MediaIDs = append(c.MediaIDs[:i], c.MediaIDs[i+1:]...)
+		if c.CoverMediaID == mediaID {
+			c.CoverMediaID = ""
+		}
+		return nil
+	})
+}
+
+// ReorderMedia replaces the order of a collection's media. The request must
+// list exactly the media already in the collection.
+func (s *Service) ReorderMedia(ctx context.Context, id string, req *ReorderRequest) (*Collection, error) {
+	return s.modify(ctx, id, func(c *Collection) error {
+		if len(req.MediaIDs) != len(c.MediaIDs) {
+			return appErr.BadRequest("media_ids must contain every media item in the collection exactly once")
+		}
+
+		seen := make(map[string]bool, len(req.MediaIDs))
+		for _, mediaID := range req.MediaIDs {
+			if seen[mediaID] || indexOf(c.MediaIDs, mediaID) < 0 {
+				return appErr.BadRequest("media_ids must contain every media item in the collection exactly once")
+			}
+			seen[mediaID] = true
+		}
+
+		c.MediaIDs = append([]string(nil), req.MediaIDs...)
+		return nil
+	})
+}
+
+// SetCover selects the cover image of a collection. An empty media ID clears
+// the cover so the first item is used instead.
+func (s *Service) SetCover(ctx context.Context, id string, req *SetCoverRequest) (*Collection, error) {
+	return s.modify(ctx, id, func(c *Collection) error {
+		if req.MediaID == "" {
+			c.CoverMediaID = ""
+			return nil
+		}
+		if indexOf(c.MediaIDs, req.MediaID) < 0 {
+			return appErr.BadRequest("cover must be a media item in the collection")
+		}
+
+		m, err := s.mediaRepo.GetByID(ctx, req.MediaID)
+		if err != nil {
+			return err
+		}
+		if m.Type != "image" {
+			return appErr.BadRequest("cover must be an image")
+		}
+
+		c.CoverMediaID = req.MediaID
+		return nil
+	})
+}
+
+// modify loads a collection, applies fn and saves the result
+func (s *Service) modify(ctx context.Context, id string, fn func(c *Collection) error) (*Collection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	collection, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get collection for update", "id", id, "error", err)
+		return nil, err
+	}
+
+	if err := fn(collection); err != nil {
+		return nil, err
+	}
+	collection.UpdatedAt = time.Now()
+
+	if err := s.repo.Save(ctx, collection); err != nil {
+		slog.ErrorContext(ctx, "Failed to save collection", "id", id, "error", err)
+		return nil, appErr.Internal("failed to save collection", err)
+	}
+	return collection, nil
+}
+
+func (s *Service) ensureMediaExists(ctx context.Context, mediaID string) error {
+	if _, err := s.mediaRepo.GetByID(ctx, mediaID); err != nil {
+		if ae := appErr.GetAppError(err); ae != nil && ae.Code == appErr.ErrCodeNotFound {
+			return appErr.BadRequest("media not found: " + mediaID)
+		}
+		return appErr.Internal("failed to look up media", err)
+	}
+	return nil
+}
+
+// Helper functions
+
+func validateCollectionFields(name, description string) error {
+	v := validation.New()
+	v.Required("name", name).MaxLength("name", name, MaxNameLength)
+	v.MaxLength("description", description, MaxDescriptionLength)
+	return v.Err()
+}
+
+func indexOf(ids []string, id string) int {
+	for i, v := range ids {
+		if v == id {
+			return i
+		}
+	}
+	return -1
+}
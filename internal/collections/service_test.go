@@ -0,0 +1,226 @@
+package collections
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/media"
+	"example.com/myapp/internal/outbox"
+)
+
+// newTestService returns a service whose media repository holds the given
+// media, keyed by ID
+func newTestService(t *testing.T, items ...*media.Media) *Service {
+	t.Helper()
+	mediaRepo := media.NewInMemoryRepository(outbox.NewInMemoryStore())
+	for _, m := range items {
+		if err := mediaRepo.Save(context.Background(), m); err != nil {
+			t.Fatalf("save media %s: %v", m.ID, err)
+		}
+	}
+	return NewService(NewInMemoryRepository(), mediaRepo)
+}
+
+func newTestCollection(t *testing.T, s *Service, mediaIDs ...string) *Collection {
+	t.Helper()
+	c, err := s.CreateCollection(context.Background(), &CreateCollectionRequest{Name: "Holiday"})
+	if err != nil {
+		t.Fatalf("create collection: %v", err)
+	}
+	for _, id := range mediaIDs {
+		if c, err = s.AddMedia(context.Background(), c.ID, &AddMediaRequest{MediaID: id}); err != nil {
+			t.Fatalf("add media %s: %v", id, err)
+		}
+	}
+	return c
+}
+
+func intPtr(n int) *int { return &n }
+
+func TestAddMedia(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      AddMediaRequest
+		want     []string
+		wantCode string
+	}{
+		{name: "appends by default", req: AddMediaRequest{MediaID: "d"}, want: []string{"a", "b", "c", "d"}},
+		{name: "inserts at the front", req: AddMediaRequest{MediaID: "d", Position: intPtr(0)}, want: []string{"d", "a", "b", "c"}},
+		{name: "inserts in the middle", req: AddMediaRequest{MediaID: "d", Position: intPtr(2)}, want: []string{"a", "b", "d", "c"}},
+		{name: "inserts at the end", req: AddMediaRequest{MediaID: "d", Position: intPtr(3)}, want: []string{"a", "b", "c", "d"}},
+		{name: "duplicate conflicts", req: AddMediaRequest{MediaID: "b"}, wantCode: appErr.ErrCodeConflict},
+		{name: "position past the end", req: AddMediaRequest{MediaID: "d", Position: intPtr(4)}, wantCode: appErr.ErrCodeBadRequest},
+		{name: "negative position", req: AddMediaRequest{MediaID: "d", Position: intPtr(-1)}, wantCode: appErr.ErrCodeBadRequest},
+		{name: "unknown media", req: AddMediaRequest{MediaID: "missing"}, wantCode: appErr.ErrCodeBadRequest},
+		{name: "missing media ID", req: AddMediaRequest{}, wantCode: appErr.ErrCodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t,
+				&media.Media{ID: "a", Type: "image"},
+				&media.Media{ID: "b", Type: "image"},
+				&media.Media{ID: "c", Type: "image"},
+				&media.Media{ID: "d", Type: "image"},
+			)
+			c := newTestCollection(t, s, "a", "b", "c")
+
+			got, err := s.AddMedia(context.Background(), c.ID, &tt.req)
+			if tt.wantCode != "" {
+				if !appErr.HasCode(err, tt.wantCode) {
+					t.Fatalf("AddMedia() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AddMedia() error = %v", err)
+			}
+			if !slices.Equal(got.MediaIDs, tt.want) {
+				t.Errorf("MediaIDs = %v, want %v", got.MediaIDs, tt.want)
+			}
+		})
+	}
+}
+
+func TestReorderMedia(t *testing.T) {
+	tests := []struct {
+		name    string
+		order   []string
+		wantErr bool
+	}{
+		{name: "permutation", order: []string{"c", "a", "b"}},
+		{name: "same order", order: []string{"a", "b", "c"}},
+		{name: "missing item", order: []string{"a", "b"}, wantErr: true},
+		{name: "duplicate item", order: []string{"a", "a", "b"}, wantErr: true},
+		{name: "foreign item", order: []string{"a", "b", "x"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, &media.Media{ID: "a"}, &media.Media{ID: "b"}, &media.Media{ID: "c"})
+			c := newTestCollection(t, s, "a", "b", "c")
+
+			got, err := s.ReorderMedia(context.Background(), c.ID, &ReorderRequest{MediaIDs: tt.order})
+			if tt.wantErr {
+				if !appErr.HasCode(err, appErr.ErrCodeBadRequest) {
+					t.Fatalf("ReorderMedia() error = %v, want BAD_REQUEST", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReorderMedia() error = %v", err)
+			}
+			if !slices.Equal(got.MediaIDs, tt.order) {
+				t.Errorf("MediaIDs = %v, want %v", got.MediaIDs, tt.order)
+			}
+		})
+	}
+}
+
+func TestSetCover(t *testing.T) {
+	tests := []struct {
+		name     string
+		mediaID  string
+		want     string
+		wantCode string
+	}{
+		{name: "image in collection", mediaID: "img", want: "img"},
+		{name: "empty clears the cover", mediaID: "", want: ""},
+		{name: "not an image", mediaID: "vid", wantCode: appErr.ErrCodeBadRequest},
+		{name: "not in collection", mediaID: "other", wantCode: appErr.ErrCodeBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t,
+				&media.Media{ID: "img", Type: "image"},
+				&media.Media{ID: "vid", Type: "video"},
+				&media.Media{ID: "other", Type: "image"},
+			)
+			c := newTestCollection(t, s, "img", "vid")
+
+			got, err := s.SetCover(context.Background(), c.ID, &SetCoverRequest{MediaID: tt.mediaID})
+			if tt.wantCode != "" {
+				if !appErr.HasCode(err, tt.wantCode) {
+					t.Fatalf("SetCover() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetCover() error = %v", err)
+			}
+			if got.CoverMediaID != tt.want {
+				t.Errorf("CoverMediaID = %q, want %q", got.CoverMediaID, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveMediaClearsCover(t *testing.T) {
+	s := newTestService(t, &media.Media{ID: "a", Type: "image"}, &media.Media{ID: "b", Type: "image"})
+	c := newTestCollection(t, s, "a", "b")
+	if _, err := s.SetCover(context.Background(), c.ID, &SetCoverRequest{MediaID: "b"}); err != nil {
+		t.Fatalf("SetCover() error = %v", err)
+	}
+
+	got, err := s.RemoveMedia(context.Background(), c.ID, "b")
+	if err != nil {
+		t.Fatalf("RemoveMedia() error = %v", err)
+	}
+	if !slices.Equal(got.MediaIDs, []string{"a"}) || got.CoverMediaID != "" {
+		t.Errorf("got MediaIDs %v and cover %q, want [a] and no cover", got.MediaIDs, got.CoverMediaID)
+	}
+
+	if _, err := s.RemoveMedia(context.Background(), c.ID, "b"); !appErr.HasCode(err, appErr.ErrCodeNotFound) {
+		t.Errorf("second RemoveMedia() error = %v, want NOT_FOUND", err)
+	}
+}
+
+func TestGetCollection(t *testing.T) {
+	s := newTestService(t,
+		&media.Media{ID: "img", Type: "image", Format: "webp"},
+		&media.Media{ID: "vid", Type: "video", Format: "mp4", PosterPath: "/srv/uploads/vid_poster.jpg", PosterURL: "/media/vid/poster"},
+		&media.Media{ID: "gone", Type: "image", Format: "webp"},
+	)
+	c := newTestCollection(t, s, "vid", "gone", "img")
+	if err := s.mediaRepo.Delete(context.Background(), "gone", 1); err != nil {
+		t.Fatalf("delete media: %v", err)
+	}
+
+	got, err := s.GetCollection(context.Background(), c.ID)
+	if err != nil {
+		t.Fatalf("GetCollection() error = %v", err)
+	}
+
+	var ids []string
+	for _, item := range got.Media {
+		ids = append(ids, item.ID)
+	}
+	if !slices.Equal(ids, []string{"vid", "img"}) {
+		t.Fatalf("media = %v, want [vid img] with the missing item skipped", ids)
+	}
+	if got.Cover == nil || got.Cover.ID != "vid" {
+		t.Errorf("cover = %v, want the first item", got.Cover)
+	}
+
+	tests := []struct {
+		item int
+		want []media.Variant
+	}{
+		{item: 0, want: []media.Variant{
+			{Name: "original", ContentType: "video/mp4", URL: "/media/vid/download"},
+			{Name: "stream", ContentType: "video/mp4", URL: "/media/vid/stream"},
+			{Name: "poster", ContentType: "image/jpeg", URL: "/media/vid/poster"},
+		}},
+		{item: 1, want: []media.Variant{
+			{Name: "original", ContentType: "image/webp", URL: "/media/img/download"},
+		}},
+	}
+	for _, tt := range tests {
+		if variants := got.Media[tt.item].Variants; !slices.Equal(variants, tt.want) {
+			t.Errorf("variants of %s = %v, want %v", got.Media[tt.item].ID, variants, tt.want)
+		}
+	}
+}
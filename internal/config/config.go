@@ -0,0 +1,385 @@
+// Package config loads the application configuration from defaults, a
+// YAML, TOML or JSON file, environment variables and command-line flags,
+// in increasing order of precedence, and validates it at startup.
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Config is the complete application configuration
+type Config struct {
+	Server    ServerConfig
+	Log       LogConfig
+	Media     MediaConfig
+	Auth      AuthConfig
+	Tracing   TracingConfig
+	Health    HealthConfig
+	RateLimit RateLimitConfig
+	CORS      CORSConfig
+	Security  SecurityConfig
+}
+
+// ServerConfig configures the HTTP server
+type ServerConfig struct {
+	Addr            string
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+	// DrainDelay is how long readiness reports failure before the server
+	// stops accepting connections, so load balancers stop routing to it
+	DrainDelay time.Duration
+}
+
+// LogConfig configures structured logging
+type LogConfig struct {
+	// Level is one of debug, info, warn or error
+	Level string
+	// AccessSampleRate is the fraction of successful requests written to
+	// the access log; failed requests are always logged
+	AccessSampleRate float64
+	// RedactQueryParams are query parameters whose values are replaced in
+	// logged URLs; "*" redacts every parameter
+	RedactQueryParams []string
+}
+
+// MediaConfig configures media storage and processing
+type MediaConfig struct {
+	StoragePath string
+	// MaxFileSize is the largest accepted upload in bytes
+	MaxFileSize int64
+	// MaxMultipartMemory is how much of a multipart form is held in memory;
+	// larger parts are spooled to temporary files
+	MaxMultipartMemory int64
+	// ImageQuality is the JPEG quality optimized images are encoded at
+	ImageQuality int
+	// AllowedFormats restricts uploads to these content types; empty
+	// allows every supported format
+	AllowedFormats []string
+	// MaxImagePixels rejects images with more pixels before they are
+	// decoded, defending against decompression bombs
+	MaxImagePixels int
+	// DecodeMemory bounds the estimated bitmap memory of images decoded at
+	// once; further uploads wait in a queue
+	DecodeMemory int64
+	// DecodeQueueSize is how many uploads may wait for decode memory;
+	// more are rejected with 503
+	DecodeQueueSize int
+	// DecodeQueueTimeout is how long an upload waits for decode memory
+	DecodeQueueTimeout time.Duration
+	// StorageQuota bounds the total size in bytes of stored media files;
+	// zero is unlimited
+	StorageQuota int64
+	// MaxFiles bounds the number of stored media files; zero is unlimited
+	MaxFiles int
+}
+
+// TracingConfig configures span export
+type TracingConfig struct {
+	// Exporter is none, stdout or otlp
+	Exporter string
+	// OTLPEndpoint is the OTLP/HTTP traces URL of the collector
+	OTLPEndpoint string
+	// ServiceName is reported as the service.name resource attribute
+	ServiceName string
+	// SampleRate is the fraction of new traces recorded
+	SampleRate float64
+}
+
+// HealthConfig configures the readiness checks
+type HealthConfig struct {
+	// CheckTimeout bounds each readiness check
+	CheckTimeout time.Duration
+	// MinFreeDisk is the free space required under the media storage path
+	MinFreeDisk int64
+	// MaxQueueLag is how far the outbox relay and webhook dispatcher may
+	// fall behind before readiness fails
+	MaxQueueLag time.Duration
+}
+
+// RateLimitConfig configures request rate limits. Each route group has its
+// own budget per principal, or per client IP for anonymous requests.
+type RateLimitConfig struct {
+	// Uploads limits POST /media/upload
+	Uploads Rate
+	// Writes limits other requests that change state
+	Writes Rate
+	// Reads limits GET and HEAD requests
+	Reads Rate
+	// TrustedProxies may set X-Forwarded-For to the client address
+	TrustedProxies []netip.Prefix
+}
+
+// Rate allows Requests per Period; zero disables the limit
+type Rate struct {
+	Requests int
+	Period   time.Duration
+}
+
+// String formats r the way ParseRate accepts it
+func (r Rate) String() string {
+	if r.Requests == 0 {
+		return "off"
+	}
+	// time.Duration prints 1m as 1m0s; drop the zero units
+	period := r.Period.String()
+	if strings.HasSuffix(period, "m0s") {
+		period = strings.TrimSuffix(period, "0s")
+	}
+	if strings.HasSuffix(period, "h0m") {
+		period = strings.TrimSuffix(period, "0m")
+	}
+	return fmt.Sprintf("%d/%s", r.Requests, period)
+}
+
+// Rate returns the limit of a route group: uploads, writes or reads.
+// Unknown groups are not limited.
+func (c RateLimitConfig) Rate(group string) Rate {
+	switch group {
+	case "uploads":
+		return c.Uploads
+	case "writes":
+		return c.Writes
+	case "reads":
+		return c.Reads
+	default:
+		return Rate{}
+	}
+}
+
+// CORSConfig configures which browser origins may call the API
+type CORSConfig struct {
+	// AllowedOrigins are origins such as https://app.example.com; "*"
+	// allows any origin. Empty disables CORS.
+	AllowedOrigins []string
+	AllowedMethods []string
+	// AllowedHeaders are the request headers browsers may send
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers scripts may read
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies with cross-origin requests
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// SecurityConfig configures security response headers and CSRF protection
+type SecurityConfig struct {
+	// ContentSecurityPolicy is sent as Content-Security-Policy; empty
+	// omits the header
+	ContentSecurityPolicy string
+	// FrameOptions is sent as X-Frame-Options: DENY or SAMEORIGIN
+	FrameOptions string
+	// HSTSMaxAge is sent in Strict-Transport-Security on HTTPS requests;
+	// zero omits the header
+	HSTSMaxAge time.Duration
+	// CSRFSecret signs CSRF tokens. It is required when auth.token_secret
+	// is set, since session cookies cannot be protected without it.
+	CSRFSecret string
+}
+
+// AuthConfig configures authentication and authorization
+type AuthConfig struct {
+	// TokenSecret signs and verifies bearer and session tokens. When empty
+	// credentials are not verified and name the principal directly.
+	TokenSecret string
+	// AdminPrincipals may use the admin endpoints
+	AdminPrincipals []string
+}
+
+// Default returns the configuration used when nothing is overridden
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:            ":8080",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+			DrainDelay:      5 * time.Second,
+		},
+		Log: LogConfig{
+			Level:             "info",
+			AccessSampleRate:  1,
+			RedactQueryParams: []string{"token", "access_token", "api_key", "password", "secret"},
+		},
+		Media: MediaConfig{
+			StoragePath:        "./uploads",
+			MaxFileSize:        200 * MB,
+			MaxMultipartMemory: 300 * MB,
+			ImageQuality:       85,
+			MaxImagePixels:     50_000_000,
+			DecodeMemory:       512 * MB,
+			DecodeQueueSize:    16,
+			DecodeQueueTimeout: 10 * time.Second,
+		},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			OTLPEndpoint: "http://localhost:4318/v1/traces",
+			ServiceName:  "myapp",
+			SampleRate:   1,
+		},
+		Health: HealthConfig{
+			CheckTimeout: 2 * time.Second,
+			MinFreeDisk:  100 * MB,
+			MaxQueueLag:  5 * time.Minute,
+		},
+		RateLimit: RateLimitConfig{
+			Uploads: Rate{Requests: 10, Period: time.Minute},
+			Writes:  Rate{Requests: 60, Period: time.Minute},
+			Reads:   Rate{Requests: 600, Period: time.Minute},
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "If-Match", "If-None-Match", "X-Request-ID", "X-CSRF-Token"},
+			ExposedHeaders: []string{"ETag", "Location", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "X-Request-ID", "X-CSRF-Token"},
+			MaxAge:         10 * time.Minute,
+		},
+		Security: SecurityConfig{
+			ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+			FrameOptions:          "DENY",
+			HSTSMaxAge:            365 * 24 * time.Hour,
+		},
+	}
+}
+
+// Problem is an invalid setting. Key names the setting, prefixed with its
+// source when it came from a file, environment variable or flag.
+type Problem struct {
+	Key     string
+	Message string
+}
+
+// Error lists every invalid setting of a configuration
+type Error struct {
+	Problems []Problem
+}
+
+func (e *Error) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = p.Key + ": " + p.Message
+	}
+	return "invalid configuration:\n  " + strings.Join(lines, "\n  ")
+}
+
+// Validate checks every setting and reports all problems at once
+func (c *Config) Validate() error {
+	var problems []Problem
+	check := func(ok bool, key, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr", "is required")
+	check(c.Server.ReadTimeout > 0, "server.read_timeout", "must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout", "must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout", "must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout", "must be positive")
+	check(c.Server.DrainDelay >= 0, "server.drain_delay", "must not be negative")
+
+	_, err := ParseLogLevel(c.Log.Level)
+	check(err == nil, "log.level", "must be one of debug, info, warn, error")
+	check(c.Log.AccessSampleRate >= 0 && c.Log.AccessSampleRate <= 1, "log.access_sample_rate", "must be between 0 and 1")
+
+	check(c.Media.StoragePath != "", "media.storage_path", "is required")
+	check(c.Media.MaxFileSize > 0, "media.max_file_size", "must be positive")
+	check(c.Media.MaxMultipartMemory > 0, "media.max_multipart_memory", "must be positive")
+	check(c.Media.ImageQuality >= 1 && c.Media.ImageQuality <= 100, "media.image_quality", "must be between 1 and 100")
+	check(c.Media.MaxImagePixels > 0, "media.max_image_pixels", "must be positive")
+	check(c.Media.DecodeMemory > 0, "media.decode_memory", "must be positive")
+	check(c.Media.DecodeQueueSize >= 0, "media.decode_queue_size", "must not be negative")
+	check(c.Media.DecodeQueueTimeout > 0, "media.decode_queue_timeout", "must be positive")
+	check(c.Media.StorageQuota >= 0, "media.storage_quota", "must not be negative")
+	check(c.Media.MaxFiles >= 0, "media.max_files", "must not be negative")
+	for i, f := range c.Media.AllowedFormats {
+		check(strings.Count(f, "/") == 1, fmt.Sprintf("media.allowed_formats[%d]", i), "must be a content type such as image/png, got %q", f)
+	}
+
+	check(c.Tracing.Exporter == "none" || c.Tracing.Exporter == "stdout" || c.Tracing.Exporter == "otlp", "tracing.exporter", "must be one of none, stdout, otlp")
+	if c.Tracing.Exporter == "otlp" {
+		u, err := url.Parse(c.Tracing.OTLPEndpoint)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "tracing.otlp_endpoint", "must be an http or https URL")
+	}
+	check(c.Tracing.ServiceName != "", "tracing.service_name", "is required")
+	check(c.Tracing.SampleRate >= 0 && c.Tracing.SampleRate <= 1, "tracing.sample_rate", "must be between 0 and 1")
+
+	check(c.Health.CheckTimeout > 0, "health.check_timeout", "must be positive")
+	check(c.Health.MaxQueueLag > 0, "health.max_queue_lag", "must be positive")
+
+	for i, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			check(!c.CORS.AllowCredentials, "cors.allowed_origins", "must list origins explicitly when cors.allow_credentials is true")
+			continue
+		}
+		u, err := url.Parse(origin)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && (u.Path == "" || u.Path == "/") && u.RawQuery == "",
+			fmt.Sprintf("cors.allowed_origins[%d]", i), "must be * or an origin such as https://app.example.com, got %q", origin)
+	}
+	check(c.CORS.MaxAge >= 0, "cors.max_age", "must not be negative")
+
+	check(c.Security.FrameOptions == "DENY" || c.Security.FrameOptions == "SAMEORIGIN" || c.Security.FrameOptions == "", "security.frame_options", "must be DENY, SAMEORIGIN or empty")
+	check(c.Security.HSTSMaxAge >= 0, "security.hsts_max_age", "must not be negative")
+	check(c.Security.CSRFSecret == "" || len(c.Security.CSRFSecret) >= 32, "security.csrf_secret", "must be at least 32 characters")
+
+	check(c.Auth.TokenSecret == "" || len(c.Auth.TokenSecret) >= 32, "auth.token_secret", "must be at least 32 characters")
+	if c.Auth.TokenSecret != "" {
+		check(c.Security.CSRFSecret != "", "security.csrf_secret", "is required when auth.token_secret is set")
+		check(c.Security.CSRFSecret != c.Auth.TokenSecret, "security.csrf_secret", "must differ from auth.token_secret")
+	}
+	for i, p := range c.Auth.AdminPrincipals {
+		check(strings.TrimSpace(p) != "", fmt.Sprintf("auth.admin_principals[%d]", i), "must not be empty")
+	}
+
+	if len(problems) > 0 {
+		return &Error{Problems: problems}
+	}
+	return nil
+}
+
+// restartRequired copies the settings that cannot change while the server
+// runs from current into c and returns the keys whose new values were
+// discarded
+func (c *Config) restartRequired(current *Config) []string {
+	var keys []string
+	if c.Server != current.Server {
+		keys = append(keys, "server")
+	}
+	if c.Media.StoragePath != current.Media.StoragePath {
+		keys = append(keys, "media.storage_path")
+	}
+	if c.Media.MaxMultipartMemory != current.Media.MaxMultipartMemory {
+		keys = append(keys, "media.max_multipart_memory")
+	}
+	if c.Media.DecodeMemory != current.Media.DecodeMemory {
+		keys = append(keys, "media.decode_memory")
+	}
+	if c.Media.DecodeQueueSize != current.Media.DecodeQueueSize {
+		keys = append(keys, "media.decode_queue_size")
+	}
+	if c.Auth.TokenSecret != current.Auth.TokenSecret {
+		keys = append(keys, "auth.token_secret")
+	}
+	if !slices.Equal(c.Auth.AdminPrincipals, current.Auth.AdminPrincipals) {
+		keys = append(keys, "auth.admin_principals")
+	}
+	if c.Tracing != current.Tracing {
+		keys = append(keys, "tracing")
+	}
+
+	c.Server = current.Server
+	c.Media.StoragePath = current.Media.StoragePath
+	c.Media.MaxMultipartMemory = current.Media.MaxMultipartMemory
+	c.Media.DecodeMemory = current.Media.DecodeMemory
+	c.Media.DecodeQueueSize = current.Media.DecodeQueueSize
+	c.Auth = current.Auth
+	c.Tracing = current.Tracing
+	return keys
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateSecrets(t *testing.T) {
+	const (
+		tokenSecret = "token-secret-0123456789abcdef0123"
+		csrfSecret  = "csrf-secret-0123456789abcdef01234"
+	)
+
+	tests := []struct {
+		name        string
+		tokenSecret string
+		csrfSecret  string
+		wantKeys    []string
+	}{
+		{name: "no authentication"},
+		{name: "both secrets", tokenSecret: tokenSecret, csrfSecret: csrfSecret},
+		{name: "CSRF secret without token secret", csrfSecret: csrfSecret},
+		{name: "token secret without CSRF secret", tokenSecret: tokenSecret, wantKeys: []string{"security.csrf_secret"}},
+		{name: "shared secret", tokenSecret: tokenSecret, csrfSecret: tokenSecret, wantKeys: []string{"security.csrf_secret"}},
+		{name: "short secrets", tokenSecret: "short", csrfSecret: "also short", wantKeys: []string{"security.csrf_secret", "auth.token_secret"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Auth.TokenSecret = tt.tokenSecret
+			cfg.Security.CSRFSecret = tt.csrfSecret
+
+			err := cfg.Validate()
+			var keys []string
+			var cfgErr *Error
+			if errors.As(err, &cfgErr) {
+				for _, p := range cfgErr.Problems {
+					keys = append(keys, p.Key)
+				}
+			} else if err != nil {
+				t.Fatalf("Validate() error = %v, want *Error", err)
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.wantKeys, ",") {
+				t.Errorf("invalid keys = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	appErr "example.com/myapp/internal/errors"
+)
+
+// ReloadResponse reports the outcome of a configuration reload
+type ReloadResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	// RestartRequired lists changed settings that were not applied
+	RestartRequired []string `json:"restart_required"`
+}
+
+type Handler struct {
+	store *Store
+}
+
+func NewHandler(store *Store) *Handler {
+	return &Handler{store: store}
+}
+
+// RegisterRoutes registers the configuration routes with appropriate middleware
+// Middleware is passed as parameters to avoid circular imports
+func (h *Handler) RegisterRoutes(r chi.Router, loggingMw, authMw, adminMw func(http.Handler) http.Handler) {
+	r.Route("/config", func(r chi.Router) {
+		r.Use(loggingMw)
+		r.Use(authMw)
+		r.Use(adminMw)
+
+		r.Post("/reload", h.Reload)
+	})
+}
+
+// Reload re-reads the configuration and applies it - POST /config/reload
+func (h *Handler) Reload(w http.ResponseWriter, r *http.Request) {
+	restartRequired, err := h.store.Reload()
+	if err != nil {
+		appErr.WriteError(w, r, reloadError(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ReloadResponse{
+		Success:         true,
+		Message:         "Configuration reloaded",
+		RestartRequired: append([]string{}, restartRequired...),
+	})
+}
+
+// reloadError reports each invalid setting of a rejected configuration as
+// a field error. Failures to read the configuration say nothing about its
+// values and are internal errors.
+func reloadError(err error) error {
+	var invalid *Error
+	if !errors.As(err, &invalid) {
+		return appErr.Internal("failed to load the configuration; the current configuration remains active", err)
+	}
+
+	fields := make([]appErr.FieldError, 0, len(invalid.Problems))
+	for _, p := range invalid.Problems {
+		fields = append(fields, appErr.FieldError{Field: p.Key, Message: p.Message})
+	}
+	ae := appErr.Validation(fields...)
+	ae.Message = "the new configuration is invalid; the current configuration remains active"
+	return ae
+}
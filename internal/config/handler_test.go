@@ -0,0 +1,163 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load := func() (*Config, error) {
+		return Load([]string{"-config", path}, func(string) string { return "" })
+	}
+
+	write("log:\n  level: info\n")
+	cfg, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(cfg, load)
+	h := NewHandler(store)
+
+	tests := []struct {
+		name           string
+		file           string // written before the reload; empty removes the file
+		wantStatus     int
+		wantCode       string
+		wantFields     []string
+		wantRestart    []string
+		wantLevel      string
+		wantAccessRate float64
+	}{
+		{
+			name:       "applies changeable settings",
+			file:       "log:\n  level: debug\n  access_sample_rate: 0.5\n",
+			wantStatus: http.StatusOK, wantRestart: []string{}, wantLevel: "debug", wantAccessRate: 0.5,
+		},
+		{
+			name:       "reports startup-only settings",
+			file:       "log:\n  level: warn\nserver:\n  addr: \":9999\"\n",
+			wantStatus: http.StatusOK, wantRestart: []string{"server"}, wantLevel: "warn", wantAccessRate: 1,
+		},
+		{
+			name:       "invalid values are a validation error",
+			file:       "log:\n  level: loud\n  access_sample_rate: 2\n",
+			wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED", wantFields: []string{"log.level", "log.access_sample_rate"},
+			wantLevel: "warn", wantAccessRate: 1,
+		},
+		{
+			name:       "syntax errors are a validation error",
+			file:       "log:\n  level: &anchor debug\n",
+			wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED", wantFields: []string{path},
+			wantLevel: "warn", wantAccessRate: 1,
+		},
+		{
+			name:       "unreadable file is an internal error",
+			wantStatus: http.StatusInternalServerError, wantCode: "INTERNAL_ERROR",
+			wantLevel: "warn", wantAccessRate: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.file == "" {
+				os.Remove(path)
+			} else {
+				write(tt.file)
+			}
+
+			w := httptest.NewRecorder()
+			h.Reload(w, httptest.NewRequest(http.MethodPost, "/config/reload", nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			var body struct {
+				Code            string   `json:"code"`
+				RestartRequired []string `json:"restart_required"`
+				Errors          []struct {
+					Field string `json:"field"`
+				} `json:"errors"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", body.Code, tt.wantCode)
+			}
+			if tt.wantStatus == http.StatusOK && !reflect.DeepEqual(body.RestartRequired, tt.wantRestart) {
+				t.Errorf("restart_required = %v, want %v", body.RestartRequired, tt.wantRestart)
+			}
+			var fields []string
+			for _, f := range body.Errors {
+				fields = append(fields, f.Field)
+			}
+			if len(fields) != len(tt.wantFields) {
+				t.Errorf("fields = %v, want %v", fields, tt.wantFields)
+			}
+
+			current := store.Current()
+			if current.Log.Level != tt.wantLevel || current.Log.AccessSampleRate != tt.wantAccessRate {
+				t.Errorf("active log.level %q, access_sample_rate %v; want %q, %v", current.Log.Level, current.Log.AccessSampleRate, tt.wantLevel, tt.wantAccessRate)
+			}
+			if current.Server.Addr != ":8080" {
+				t.Errorf("server.addr changed to %q without a restart", current.Server.Addr)
+			}
+		})
+	}
+}
+
+func TestReloadValidatesStartupSettingsKept(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load := func() (*Config, error) {
+		return Load([]string{"-config", path}, func(string) string { return "" })
+	}
+
+	write("auth:\n  token_secret: 0123456789abcdef0123456789abcdef\nsecurity:\n  csrf_secret: fedcba9876543210fedcba9876543210\n")
+	cfg, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(cfg, load)
+
+	// Without a token secret the file is valid on its own, but the running
+	// token secret stays and still needs a CSRF secret
+	write("log:\n  level: debug\n")
+	w := httptest.NewRecorder()
+	NewHandler(store).Reload(w, httptest.NewRequest(http.MethodPost, "/config/reload", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+	}
+
+	var body struct {
+		Errors []struct {
+			Field string `json:"field"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Errors) != 1 || body.Errors[0].Field != "security.csrf_secret" {
+		t.Errorf("errors = %+v, want security.csrf_secret", body.Errors)
+	}
+	if current := store.Current(); current.Security.CSRFSecret == "" || current.Log.Level != "info" {
+		t.Errorf("rejected configuration was applied: log.level %q, csrf secret set %v", current.Log.Level, current.Security.CSRFSecret != "")
+	}
+}
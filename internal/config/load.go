@@ -0,0 +1,139 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ConfigEnv names the environment variable holding the config file path;
+// the -config flag takes precedence over it
+const ConfigEnv = EnvPrefix + "CONFIG"
+
+// Check validates settings that depend on another package, such as content
+// types only the media package knows
+type Check func(*Config) error
+
+// Load builds the configuration from, in increasing order of precedence,
+// the defaults, the config file given by -config or APP_CONFIG, environment
+// variables and command-line flags, and validates the result with Validate
+// and every check
+func Load(args []string, getenv func(string) string, checks ...Check) (*Config, error) {
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	configPath := fs.String("config", getenv(ConfigEnv), "path to a YAML, TOML or JSON config file")
+	flagValues := make(map[string]string)
+	for _, s := range settings {
+		key := s.key
+		fs.Func(key, s.usage, func(value string) error {
+			flagValues[key] = value
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("parse flags: %w", err)
+	}
+
+	cfg := Default()
+	var problems []Problem
+
+	if *configPath != "" {
+		values, err := ReadFile(*configPath)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, apply(cfg, values, func(key string) string {
+			return *configPath + ": " + key
+		})...)
+	}
+
+	envValues := make(map[string]string)
+	for _, s := range settings {
+		if value := getenv(envName(s.key)); value != "" {
+			envValues[s.key] = value
+		}
+	}
+	problems = append(problems, apply(cfg, envValues, envName)...)
+	problems = append(problems, apply(cfg, flagValues, func(key string) string {
+		return "-" + key
+	})...)
+
+	for _, check := range append([]Check{(*Config).Validate}, checks...) {
+		err := check(cfg)
+		var invalid *Error
+		switch {
+		case errors.As(err, &invalid):
+			problems = append(problems, invalid.Problems...)
+		case err != nil:
+			return nil, err
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &Error{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// apply sets every value on cfg, naming each problem with the source the
+// value came from
+func apply(cfg *Config, values map[string]string, source func(key string) string) []Problem {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []Problem
+	for _, key := range keys {
+		s, ok := lookupSetting(key)
+		if !ok {
+			problems = append(problems, Problem{Key: source(key), Message: "unknown setting"})
+			continue
+		}
+		if err := s.set(cfg, values[key]); err != nil {
+			problems = append(problems, Problem{Key: source(key), Message: err.Error()})
+		}
+	}
+	return problems
+}
+
+// ReadFile reads a config file into flat "section.key" values. The format
+// is chosen by extension: .yaml/.yml, .toml or .json. Unsupported formats
+// and syntax errors are reported as an *Error naming the file; failures to
+// read it are returned as they are.
+func ReadFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	var values map[string]string
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		values, err = parseYAML(data)
+	case ".toml":
+		values, err = parseTOML(data)
+	case ".json":
+		values, err = parseJSON(data)
+	default:
+		err = fmt.Errorf("unsupported format %q, use .yaml, .toml or .json", ext)
+	}
+	if err != nil {
+		return nil, &Error{Problems: []Problem{{Key: path, Message: err.Error()}}}
+	}
+	return values, nil
+}
+
+// Usage lists every setting with its environment variable and flag
+func Usage(w io.Writer) {
+	fmt.Fprintf(w, "  -config (%s)\n\tpath to a YAML, TOML or JSON config file\n", ConfigEnv)
+	for _, s := range settings {
+		fmt.Fprintf(w, "  -%s (%s)\n\t%s\n", s.key, envName(s.key), s.usage)
+	}
+}
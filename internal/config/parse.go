@@ -0,0 +1,488 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The file parsers produce flat maps keyed "section.key". Lists are joined
+// with commas so every source feeds the same setters. Only the subset of
+// each format needed for a two-level configuration is supported; anything
+// else, such as nesting, anchors or multi-line strings, is rejected rather
+// than guessed at.
+
+// keyPattern matches section and setting names
+var keyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+var errUnterminatedList = errors.New("unterminated list")
+
+func parseJSON(data []byte) (map[string]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid JSON: unexpected data after the top-level object")
+	}
+
+	values := make(map[string]string)
+	for section, raw := range doc {
+		members, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: expected an object", section)
+		}
+		for key, v := range members {
+			value, err := jsonValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", section, key, err)
+			}
+			values[section+"."+key] = value
+		}
+	}
+	return values, nil
+}
+
+// jsonValue converts a scalar or a list of scalars
+func jsonValue(v interface{}) (string, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return jsonScalar(v)
+	}
+	items := make([]string, len(list))
+	for i, item := range list {
+		if _, nested := item.([]interface{}); nested {
+			return "", errors.New("nested lists are not supported")
+		}
+		s, err := jsonScalar(item)
+		if err != nil {
+			return "", err
+		}
+		items[i] = s
+	}
+	return joinList(items)
+}
+
+func jsonScalar(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case nil:
+		return "", errors.New("null values are not supported")
+	case map[string]interface{}:
+		return "", errors.New("nested objects are not supported")
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
+
+// parseYAML reads two-level mappings of scalars, flow [a, b] lists and
+// block "- item" lists. Deeper nesting, anchors, tags, block scalars, flow
+// mappings and multiple documents are rejected.
+func parseYAML(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	var section, listKey string
+	var list []string
+	// indent is the indentation of the settings in the current section
+	var indent int
+	var started bool
+
+	flushList := func() error {
+		if listKey == "" {
+			return nil
+		}
+		joined, err := joinList(list)
+		if err != nil {
+			return fmt.Errorf("%s: %w", listKey, err)
+		}
+		values[listKey] = joined
+		listKey, list = "", nil
+		return nil
+	}
+
+	for n, raw := range strings.Split(string(data), "\n") {
+		lineNo := n + 1
+		line := strings.TrimRight(stripComment(raw, true), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if trimmed == "---" && !started {
+			continue
+		}
+		started = true
+		if trimmed == "---" || trimmed == "..." {
+			return nil, fmt.Errorf("line %d: multiple documents are not supported", lineNo)
+		}
+		width := len(line) - len(strings.TrimLeft(line, " \t"))
+		if strings.ContainsRune(line[:width], '\t') {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in indentation", lineNo)
+		}
+
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if listKey == "" {
+				return nil, fmt.Errorf("line %d: list item outside of a list", lineNo)
+			}
+			if width < indent {
+				return nil, fmt.Errorf("line %d: list item is indented less than its key", lineNo)
+			}
+			item := strings.TrimSpace(trimmed[1:])
+			if item == "" {
+				return nil, fmt.Errorf("line %d: empty list item", lineNo)
+			}
+			if item[0] == '[' || item[0] == '-' {
+				return nil, fmt.Errorf("line %d: nested lists are not supported", lineNo)
+			}
+			value, rest, err := scanItem(item, true, false)
+			if err == nil && strings.TrimSpace(rest) != "" {
+				err = fmt.Errorf("unexpected %q after value", strings.TrimSpace(rest))
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			list = append(list, value)
+			continue
+		}
+		if err := flushList(); err != nil {
+			return nil, err
+		}
+
+		key, value, ok := cutYAMLKey(trimmed)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", lineNo)
+		}
+		if !keyPattern.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid key %q", lineNo, key)
+		}
+
+		if width == 0 {
+			if value != "" {
+				return nil, fmt.Errorf("line %d: top-level key %q must be a section", lineNo, key)
+			}
+			section, indent = key, 0
+			continue
+		}
+		if section == "" {
+			return nil, fmt.Errorf("line %d: %q is not inside a section", lineNo, key)
+		}
+		switch {
+		case indent == 0:
+			indent = width
+		case width > indent:
+			return nil, fmt.Errorf("line %d: nested mappings are not supported", lineNo)
+		case width < indent:
+			return nil, fmt.Errorf("line %d: inconsistent indentation", lineNo)
+		}
+
+		fullKey := section + "." + key
+		if _, dup := values[fullKey]; dup {
+			return nil, fmt.Errorf("line %d: %s is set twice", lineNo, fullKey)
+		}
+		if value == "" {
+			listKey = fullKey
+			continue
+		}
+		parsed, err := scanValue(value, true)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		values[fullKey] = parsed
+	}
+	if err := flushList(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// cutYAMLKey splits "key: value" at the first colon followed by a space or
+// the end of the line
+func cutYAMLKey(line string) (key, value string, ok bool) {
+	for i := 0; i < len(line); i++ {
+		if line[i] == ':' && (i+1 == len(line) || line[i+1] == ' ') {
+			return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// parseTOML reads [section] tables of strings, numbers, booleans and
+// arrays, which may span lines. Nested and array tables, dotted keys,
+// inline tables and multi-line strings are rejected.
+func parseTOML(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	tables := make(map[string]bool)
+	var section string
+
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		trimmed := strings.TrimSpace(stripComment(lines[i], false))
+		if trimmed == "" {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "[") {
+			if strings.HasPrefix(trimmed, "[[") {
+				return nil, fmt.Errorf("line %d: arrays of tables are not supported", lineNo)
+			}
+			if !strings.HasSuffix(trimmed, "]") {
+				return nil, fmt.Errorf("line %d: unterminated table header", lineNo)
+			}
+			name := strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			if strings.Contains(name, ".") {
+				return nil, fmt.Errorf("line %d: nested tables are not supported", lineNo)
+			}
+			if !keyPattern.MatchString(name) {
+				return nil, fmt.Errorf("line %d: invalid table name %q", lineNo, name)
+			}
+			if tables[name] {
+				return nil, fmt.Errorf("line %d: table [%s] is defined twice", lineNo, name)
+			}
+			tables[name], section = true, name
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key = value\"", lineNo)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if strings.Contains(key, ".") {
+			return nil, fmt.Errorf("line %d: dotted keys are not supported", lineNo)
+		}
+		if !keyPattern.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid key %q", lineNo, key)
+		}
+		if section == "" {
+			return nil, fmt.Errorf("line %d: %q is not inside a table", lineNo, key)
+		}
+		fullKey := section + "." + key
+		if _, dup := values[fullKey]; dup {
+			return nil, fmt.Errorf("line %d: %s is set twice", lineNo, fullKey)
+		}
+
+		parsed, err := scanValue(value, false)
+		// Arrays may continue on the following lines
+		for errors.Is(err, errUnterminatedList) && i+1 < len(lines) {
+			i++
+			value += " " + strings.TrimSpace(stripComment(lines[i], false))
+			parsed, err = scanValue(value, false)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		values[fullKey] = parsed
+	}
+	return values, nil
+}
+
+// scanValue parses a complete scalar or inline list value
+func scanValue(value string, yaml bool) (string, error) {
+	if value == "" {
+		return "", errors.New("missing value")
+	}
+
+	var parsed, rest string
+	var err error
+	if value[0] == '[' {
+		var items []string
+		items, rest, err = parseList(value, yaml)
+		if err == nil {
+			parsed, err = joinList(items)
+		}
+	} else {
+		parsed, rest, err = scanItem(value, yaml, false)
+	}
+	if err != nil {
+		return "", err
+	}
+	if rest = strings.TrimSpace(rest); rest != "" {
+		return "", fmt.Errorf("unexpected %q after value", rest)
+	}
+	return parsed, nil
+}
+
+// parseList parses the [a, "b", 'c'] list at the start of s and returns its
+// items and the rest of s. A trailing comma is allowed.
+func parseList(s string, yaml bool) ([]string, string, error) {
+	rest := strings.TrimSpace(s[1:])
+	items := make([]string, 0)
+	for {
+		if rest == "" {
+			return nil, "", errUnterminatedList
+		}
+		switch rest[0] {
+		case ']':
+			return items, rest[1:], nil
+		case '[':
+			return nil, "", errors.New("nested lists are not supported")
+		}
+
+		item, r, err := scanItem(rest, yaml, true)
+		if err != nil {
+			return nil, "", err
+		}
+		items = append(items, item)
+
+		rest = strings.TrimSpace(r)
+		switch {
+		case rest == "":
+			return nil, "", errUnterminatedList
+		case rest[0] == ',':
+			rest = strings.TrimSpace(rest[1:])
+		case rest[0] == ']':
+			return items, rest[1:], nil
+		default:
+			return nil, "", fmt.Errorf("expected \",\" or \"]\" before %q", rest)
+		}
+	}
+}
+
+// scanItem parses the quoted or plain scalar at the start of s and returns
+// its value and the rest of s. Inside lists a plain scalar ends at the next
+// comma or closing bracket.
+func scanItem(s string, yaml, inList bool) (string, string, error) {
+	if s[0] == '"' || s[0] == '\'' {
+		if !yaml && (strings.HasPrefix(s, `"""`) || strings.HasPrefix(s, "'''")) {
+			return "", "", errors.New("multi-line strings are not supported")
+		}
+		return scanQuoted(s, yaml)
+	}
+
+	end := len(s)
+	if inList {
+		if i := strings.IndexAny(s, ",]"); i >= 0 {
+			end = i
+		}
+	}
+	plain := strings.TrimSpace(s[:end])
+	if plain == "" {
+		return "", "", errors.New("missing value")
+	}
+	var value string
+	var err error
+	if yaml {
+		value, err = yamlPlain(plain)
+	} else {
+		value, err = tomlBare(plain)
+	}
+	return value, s[end:], err
+}
+
+// scanQuoted parses the quoted string at the start of s. Double-quoted
+// strings use backslash escapes. Single-quoted strings are literal, except
+// that YAML doubles a single quote written inside them.
+func scanQuoted(s string, yaml bool) (string, string, error) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case s[i] != quote:
+		case quote == '\'' && yaml && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case quote == '\'':
+			value := s[1:i]
+			if yaml {
+				value = strings.ReplaceAll(value, "''", "'")
+			}
+			return value, s[i+1:], nil
+		default:
+			value, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid string %s", s[:i+1])
+			}
+			return value, s[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unterminated string %s", s)
+}
+
+// yamlPlain checks an unquoted YAML scalar, rejecting the syntax of
+// constructs the parser does not support
+func yamlPlain(s string) (string, error) {
+	switch {
+	case s == "~" || strings.EqualFold(s, "null"):
+		return "", errors.New("null values are not supported")
+	case strings.ContainsAny(s[:1], "&*!|>{}@`%?"):
+		return "", fmt.Errorf("unsupported YAML value %q; quote it if it is a string", s)
+	case strings.Contains(s, ": ") || strings.HasSuffix(s, ":"):
+		return "", errors.New("nested mappings are not supported")
+	}
+	return s, nil
+}
+
+// tomlBare checks an unquoted TOML value, which must be a boolean or a
+// number; numbers are normalised so the setters can parse them
+func tomlBare(s string) (string, error) {
+	if s == "true" || s == "false" {
+		return s, nil
+	}
+	if s[0] == '{' {
+		return "", errors.New("inline tables are not supported")
+	}
+	digits := strings.TrimLeft(s, "+-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] >= '0' && digits[1] <= '9' {
+		return "", fmt.Errorf("number %s must not have leading zeros", s)
+	}
+	if n, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return strconv.FormatInt(n, 10), nil
+	}
+	if !strings.ContainsAny(s, "xX") {
+		if f, err := strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), 64); err == nil {
+			return strconv.FormatFloat(f, 'g', -1, 64), nil
+		}
+	}
+	return "", fmt.Errorf("string %s must be quoted", s)
+}
+
+// joinList joins list items with commas, which therefore must not appear
+// in an item
+func joinList(items []string) (string, error) {
+	for _, item := range items {
+		if strings.Contains(item, ",") {
+			return "", fmt.Errorf("list item %q must not contain a comma", item)
+		}
+	}
+	return strings.Join(items, ","), nil
+}
+
+// stripComment removes a # comment that is not inside a quoted string. In
+// YAML a comment must start the line or follow whitespace, and quotes only
+// open a string at the start of a value.
+func stripComment(line string, yaml bool) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		afterSpace := i == 0 || line[i-1] == ' ' || line[i-1] == '\t'
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote == '\'' && yaml && c == '\'' && i+1 < len(line) && line[i+1] == '\'':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if !yaml || afterSpace || line[i-1] == '[' || line[i-1] == ',' {
+				quote = c
+			}
+		case c == '#' && (!yaml || afterSpace):
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type parseCase struct {
+	name    string
+	input   string
+	want    map[string]string
+	wantErr string
+}
+
+func runParseCases(t *testing.T, parse func([]byte) (map[string]string, error), tests []parseCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse([]byte(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q (got %v)", err, tt.wantErr, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	runParseCases(t, parseYAML, []parseCase{
+		{
+			name: "sections, scalars and comments",
+			input: `---
+# server settings
+server:
+  addr: ":9090"   # quoted
+  write_timeout: 30s
+media:
+  max_file_size: 500MB
+`,
+			want: map[string]string{"server.addr": ":9090", "server.write_timeout": "30s", "media.max_file_size": "500MB"},
+		},
+		{
+			name: "block and flow lists",
+			input: `auth:
+  admin_principals:
+    - alice
+    - "ops"
+cors:
+  allowed_methods: [GET, 'POST', "PUT",]
+  allowed_origins: []
+log:
+  redact_query_params:
+  - token
+`,
+			want: map[string]string{"auth.admin_principals": "alice,ops", "cors.allowed_methods": "GET,POST,PUT", "cors.allowed_origins": "", "log.redact_query_params": "token"},
+		},
+		{
+			name: "quoting and hashes",
+			input: `security:
+  content_security_policy: "default-src 'none'; frame-ancestors 'none'"
+  frame_options: 'it''s # not a comment'
+  csrf_secret: abc#def
+  hsts_max_age: "a \"quoted\" \u00e9"
+`,
+			want: map[string]string{
+				"security.content_security_policy": "default-src 'none'; frame-ancestors 'none'",
+				"security.frame_options":           "it's # not a comment",
+				"security.csrf_secret":             "abc#def",
+				"security.hsts_max_age":            `a "quoted" é`,
+			},
+		},
+		{name: "nested mapping", input: "server:\n  tls:\n    cert: x\n", wantErr: "line 3: nested mappings are not supported"},
+		{name: "mapping as value", input: "server:\n  addr: a: b\n", wantErr: "nested mappings"},
+		{name: "mapping in list", input: "auth:\n  admin_principals:\n    - name: alice\n", wantErr: "nested mappings"},
+		{name: "nested list", input: "auth:\n  admin_principals:\n    - - alice\n", wantErr: "nested lists"},
+		{name: "nested flow list", input: "auth:\n  admin_principals: [[a]]\n", wantErr: "nested lists"},
+		{name: "flow mapping", input: "server: {addr: x}\n", wantErr: "must be a section"},
+		{name: "flow mapping value", input: "server:\n  addr: {a: 1}\n", wantErr: "unsupported YAML value"},
+		{name: "anchor", input: "server:\n  addr: &a x\n", wantErr: "unsupported YAML value"},
+		{name: "alias", input: "server:\n  addr: *a\n", wantErr: "unsupported YAML value"},
+		{name: "block scalar", input: "server:\n  addr: |\n    x\n", wantErr: "unsupported YAML value"},
+		{name: "tag", input: "server:\n  addr: !!str x\n", wantErr: "unsupported YAML value"},
+		{name: "null", input: "server:\n  addr: ~\n", wantErr: "null values"},
+		{name: "multiple documents", input: "server:\n  addr: x\n---\nlog:\n  level: info\n", wantErr: "multiple documents"},
+		{name: "tab indentation", input: "server:\n\taddr: x\n", wantErr: "tabs"},
+		{name: "inconsistent indentation", input: "server:\n    addr: x\n  write_timeout: 1s\n", wantErr: "inconsistent indentation"},
+		{name: "duplicate key", input: "server:\n  addr: x\n  addr: y\n", wantErr: "server.addr is set twice"},
+		{name: "top-level scalar", input: "addr: x\n", wantErr: "must be a section"},
+		{name: "key outside section", input: "  addr: x\n", wantErr: "not inside a section"},
+		{name: "list item outside list", input: "server:\n  - x\n", wantErr: "outside of a list"},
+		{name: "comma in list item", input: "auth:\n  admin_principals:\n    - \"a,b\"\n", wantErr: "must not contain a comma"},
+		{name: "comma in flow list item", input: "auth:\n  admin_principals: [\"a,b\"]\n", wantErr: "must not contain a comma"},
+		{name: "unterminated flow list", input: "auth:\n  admin_principals: [a, b\n", wantErr: "unterminated list"},
+		{name: "unterminated string", input: "server:\n  addr: \"x\n", wantErr: "unterminated string"},
+		{name: "text after string", input: "server:\n  addr: \"x\" y\n", wantErr: "unexpected"},
+		{name: "invalid key", input: "server:\n  \"addr\": x\n", wantErr: "invalid key"},
+		{name: "missing colon", input: "server:\n  addr\n", wantErr: "expected \"key: value\""},
+	})
+}
+
+func TestParseTOML(t *testing.T) {
+	runParseCases(t, parseTOML, []parseCase{
+		{
+			name: "tables, scalars and comments",
+			input: `# settings
+[server]
+addr = ":9090" # listen
+write_timeout = '30s'
+
+[media]
+image_quality = 90
+max_image_pixels = 40_000_000
+
+[log]
+access_sample_rate = 0.5
+
+[cors]
+allow_credentials = true
+`,
+			want: map[string]string{
+				"server.addr": ":9090", "server.write_timeout": "30s", "media.image_quality": "90",
+				"media.max_image_pixels": "40000000", "log.access_sample_rate": "0.5", "cors.allow_credentials": "true",
+			},
+		},
+		{
+			name: "multi-line arrays",
+			input: `[auth]
+admin_principals = [
+  "alice", # first
+  "ops",
+]
+
+[cors]
+allowed_methods = ["GET",
+                   "POST"]
+allowed_origins = []
+`,
+			want: map[string]string{"auth.admin_principals": "alice,ops", "cors.allowed_methods": "GET,POST", "cors.allowed_origins": ""},
+		},
+		{
+			name:  "quotes, escapes and hashes",
+			input: "[security]\ncontent_security_policy = \"default-src 'none'; a, b\"\nframe_options = \"x \\\"#\\\" \\u00e9\"\ncsrf_secret = 'C:\\path#1'\n",
+			want: map[string]string{
+				"security.content_security_policy": "default-src 'none'; a, b",
+				"security.frame_options":           `x "#" é`,
+				"security.csrf_secret":             `C:\path#1`,
+			},
+		},
+		{name: "comma in array item", input: "[auth]\nadmin_principals = [\"a,b\", \"c\"]\n", wantErr: "must not contain a comma"},
+		{name: "unquoted string", input: "[server]\naddr = localhost\n", wantErr: "must be quoted"},
+		{name: "unquoted array item", input: "[auth]\nadmin_principals = [alice]\n", wantErr: "must be quoted"},
+		{name: "nested table", input: "[server.tls]\ncert = \"x\"\n", wantErr: "nested tables"},
+		{name: "array of tables", input: "[[server]]\n", wantErr: "arrays of tables"},
+		{name: "dotted key", input: "[server]\ntls.cert = \"x\"\n", wantErr: "dotted keys"},
+		{name: "inline table", input: "[server]\ntls = { cert = \"x\" }\n", wantErr: "inline tables"},
+		{name: "multi-line string", input: "[server]\naddr = \"\"\"x\"\"\"\n", wantErr: "multi-line strings"},
+		{name: "nested array", input: "[auth]\nadmin_principals = [[\"a\"]]\n", wantErr: "nested lists"},
+		{name: "unterminated array", input: "[auth]\nadmin_principals = [\n  \"a\",\n", wantErr: "line 2: unterminated list"},
+		{name: "text after value", input: "[server]\naddr = \"x\" \"y\"\n", wantErr: "unexpected"},
+		{name: "duplicate key", input: "[server]\naddr = \"x\"\naddr = \"y\"\n", wantErr: "server.addr is set twice"},
+		{name: "duplicate table", input: "[server]\n[server]\n", wantErr: "defined twice"},
+		{name: "key outside table", input: "addr = \"x\"\n", wantErr: "not inside a table"},
+		{name: "leading zeros", input: "[media]\nimage_quality = 090\n", wantErr: "leading zeros"},
+		{name: "missing value", input: "[server]\naddr =\n", wantErr: "missing value"},
+		{name: "unterminated header", input: "[server\n", wantErr: "unterminated table header"},
+	})
+}
+
+func TestParseJSON(t *testing.T) {
+	runParseCases(t, parseJSON, []parseCase{
+		{
+			name:  "sections, scalars and lists",
+			input: `{"server": {"addr": ":9090"}, "media": {"image_quality": 90}, "cors": {"allow_credentials": true, "allowed_methods": ["GET", "POST"]}}`,
+			want:  map[string]string{"server.addr": ":9090", "media.image_quality": "90", "cors.allow_credentials": "true", "cors.allowed_methods": "GET,POST"},
+		},
+		{name: "nested object", input: `{"server": {"tls": {"cert": "x"}}}`, wantErr: "nested objects"},
+		{name: "nested list", input: `{"auth": {"admin_principals": [["a"]]}}`, wantErr: "nested lists"},
+		{name: "null", input: `{"server": {"addr": null}}`, wantErr: "null values"},
+		{name: "comma in list item", input: `{"auth": {"admin_principals": ["a,b"]}}`, wantErr: "must not contain a comma"},
+		{name: "section not an object", input: `{"server": ":9090"}`, wantErr: "expected an object"},
+		{name: "trailing data", input: `{"server": {}} {}`, wantErr: "unexpected data"},
+		{name: "invalid", input: `{"server": `, wantErr: "invalid JSON"},
+	})
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    map[string]string
+		wantErr string
+	}{
+		{name: "yaml", path: write("a.yaml", "log:\n  level: debug\n"), want: map[string]string{"log.level": "debug"}},
+		{name: "yml", path: write("a.YML", "log:\n  level: warn\n"), want: map[string]string{"log.level": "warn"}},
+		{name: "toml", path: write("a.toml", "[log]\nlevel = \"error\"\n"), want: map[string]string{"log.level": "error"}},
+		{name: "json", path: write("a.json", `{"log": {"level": "info"}}`), want: map[string]string{"log.level": "info"}},
+		{name: "parse errors name the file", path: write("bad.toml", "[log]\nlevel = debug\n"), wantErr: "bad.toml: line 2: string debug must be quoted"},
+		{name: "unknown extension", path: write("a.ini", ""), wantErr: "unsupported format"},
+		{name: "missing file", path: filepath.Join(dir, "missing.yaml"), wantErr: "read config file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadFile(tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ReadFile() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadFile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
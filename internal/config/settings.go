@@ -0,0 +1,256 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Size units accepted by size settings
+const (
+	KB int64 = 1024
+	MB       = 1024 * KB
+	GB       = 1024 * MB
+)
+
+// setting is one configurable value. Key names it in files ("section.key"),
+// the environment (EnvPrefix + "SECTION_KEY") and flags (-section.key).
+type setting struct {
+	key   string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+// EnvPrefix is prepended to the environment variable of every setting
+const EnvPrefix = "APP_"
+
+var settings = []setting{
+	{"server.addr", "listen address", stringSetting(func(c *Config) *string { return &c.Server.Addr })},
+	{"server.read_timeout", "maximum duration for reading a request", durationSetting(func(c *Config) *time.Duration { return &c.Server.ReadTimeout })},
+	{"server.write_timeout", "maximum duration for writing a response", durationSetting(func(c *Config) *time.Duration { return &c.Server.WriteTimeout })},
+	{"server.idle_timeout", "how long idle keep-alive connections stay open", durationSetting(func(c *Config) *time.Duration { return &c.Server.IdleTimeout })},
+	{"server.shutdown_timeout", "how long graceful shutdown waits for requests", durationSetting(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })},
+	{"server.drain_delay", "how long readiness fails before shutdown stops accepting connections", durationSetting(func(c *Config) *time.Duration { return &c.Server.DrainDelay })},
+	{"log.level", "log level: debug, info, warn or error", stringSetting(func(c *Config) *string { return &c.Log.Level })},
+	{"log.access_sample_rate", "fraction of successful requests written to the access log (0-1)", floatSetting(func(c *Config) *float64 { return &c.Log.AccessSampleRate })},
+	{"log.redact_query_params", "comma-separated query parameters redacted in logged URLs; * redacts all", listSetting(func(c *Config) *[]string { return &c.Log.RedactQueryParams })},
+	{"media.storage_path", "directory media files are stored in", stringSetting(func(c *Config) *string { return &c.Media.StoragePath })},
+	{"media.max_file_size", "largest accepted upload, e.g. 200MB", sizeSetting(func(c *Config) *int64 { return &c.Media.MaxFileSize })},
+	{"media.max_multipart_memory", "multipart form bytes held in memory, e.g. 300MB", sizeSetting(func(c *Config) *int64 { return &c.Media.MaxMultipartMemory })},
+	{"media.image_quality", "JPEG quality of optimized images (1-100)", intSetting(func(c *Config) *int { return &c.Media.ImageQuality })},
+	{"media.allowed_formats", "comma-separated content types accepted for upload; empty allows all supported", listSetting(func(c *Config) *[]string { return &c.Media.AllowedFormats })},
+	{"media.max_image_pixels", "largest accepted image in pixels (width x height)", intSetting(func(c *Config) *int { return &c.Media.MaxImagePixels })},
+	{"media.decode_memory", "estimated bitmap memory of images decoded at once, e.g. 512MB", sizeSetting(func(c *Config) *int64 { return &c.Media.DecodeMemory })},
+	{"media.decode_queue_size", "uploads that may wait for decode memory before 503s are returned", intSetting(func(c *Config) *int { return &c.Media.DecodeQueueSize })},
+	{"media.decode_queue_timeout", "how long an upload waits for decode memory", durationSetting(func(c *Config) *time.Duration { return &c.Media.DecodeQueueTimeout })},
+	{"media.storage_quota", "total size of stored media files, e.g. 10GB; 0 is unlimited", sizeSetting(func(c *Config) *int64 { return &c.Media.StorageQuota })},
+	{"media.max_files", "number of stored media files; 0 is unlimited", intSetting(func(c *Config) *int { return &c.Media.MaxFiles })},
+	{"tracing.exporter", "span exporter: none, stdout or otlp", stringSetting(func(c *Config) *string { return &c.Tracing.Exporter })},
+	{"tracing.otlp_endpoint", "OTLP/HTTP traces URL of the collector", stringSetting(func(c *Config) *string { return &c.Tracing.OTLPEndpoint })},
+	{"tracing.service_name", "service.name reported with exported spans", stringSetting(func(c *Config) *string { return &c.Tracing.ServiceName })},
+	{"tracing.sample_rate", "fraction of new traces recorded (0-1)", floatSetting(func(c *Config) *float64 { return &c.Tracing.SampleRate })},
+	{"health.check_timeout", "time limit of each readiness check", durationSetting(func(c *Config) *time.Duration { return &c.Health.CheckTimeout })},
+	{"health.min_free_disk", "free space required under media.storage_path, e.g. 100MB", sizeSetting(func(c *Config) *int64 { return &c.Health.MinFreeDisk })},
+	{"health.max_queue_lag", "how far outbox and webhook delivery may fall behind before readiness fails", durationSetting(func(c *Config) *time.Duration { return &c.Health.MaxQueueLag })},
+	{"rate_limit.uploads", "upload requests allowed per principal or IP, e.g. 10/1m; off disables", rateSetting(func(c *Config) *Rate { return &c.RateLimit.Uploads })},
+	{"rate_limit.writes", "other state-changing requests allowed per principal or IP, e.g. 60/1m", rateSetting(func(c *Config) *Rate { return &c.RateLimit.Writes })},
+	{"rate_limit.reads", "GET and HEAD requests allowed per principal or IP, e.g. 600/1m", rateSetting(func(c *Config) *Rate { return &c.RateLimit.Reads })},
+	{"rate_limit.trusted_proxies", "comma-separated proxy IPs or CIDRs whose X-Forwarded-For is trusted", prefixListSetting(func(c *Config) *[]netip.Prefix { return &c.RateLimit.TrustedProxies })},
+	{"cors.allowed_origins", "comma-separated browser origins allowed to call the API; * allows any; empty disables CORS", listSetting(func(c *Config) *[]string { return &c.CORS.AllowedOrigins })},
+	{"cors.allowed_methods", "comma-separated methods allowed in cross-origin requests", listSetting(func(c *Config) *[]string { return &c.CORS.AllowedMethods })},
+	{"cors.allowed_headers", "comma-separated request headers allowed in cross-origin requests", listSetting(func(c *Config) *[]string { return &c.CORS.AllowedHeaders })},
+	{"cors.exposed_headers", "comma-separated response headers readable by cross-origin scripts", listSetting(func(c *Config) *[]string { return &c.CORS.ExposedHeaders })},
+	{"cors.allow_credentials", "whether cross-origin requests may send cookies (true or false)", boolSetting(func(c *Config) *bool { return &c.CORS.AllowCredentials })},
+	{"cors.max_age", "how long browsers cache preflight responses", durationSetting(func(c *Config) *time.Duration { return &c.CORS.MaxAge })},
+	{"security.content_security_policy", "Content-Security-Policy header; empty omits it", stringSetting(func(c *Config) *string { return &c.Security.ContentSecurityPolicy })},
+	{"security.frame_options", "X-Frame-Options header: DENY, SAMEORIGIN or empty", stringSetting(func(c *Config) *string { return &c.Security.FrameOptions })},
+	{"security.hsts_max_age", "Strict-Transport-Security max-age on HTTPS requests; 0 omits it", durationSetting(func(c *Config) *time.Duration { return &c.Security.HSTSMaxAge })},
+	{"security.csrf_secret", "secret of at least 32 characters signing CSRF tokens; required with auth.token_secret", stringSetting(func(c *Config) *string { return &c.Security.CSRFSecret })},
+	{"auth.token_secret", "secret of at least 32 characters signing bearer and session tokens; credentials are not verified when empty", stringSetting(func(c *Config) *string { return &c.Auth.TokenSecret })},
+	{"auth.admin_principals", "comma-separated principals allowed to use admin endpoints", listSetting(func(c *Config) *[]string { return &c.Auth.AdminPrincipals })},
+}
+
+// envName returns the environment variable of a setting key
+func envName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func lookupSetting(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+func stringSetting(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func intSetting(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("must be an integer, got %q", value)
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func boolSetting(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("must be true or false, got %q", value)
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func floatSetting(field func(*Config) *float64) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return fmt.Errorf("must be a number, got %q", value)
+		}
+		*field(c) = f
+		return nil
+	}
+}
+
+func durationSetting(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("must be a duration such as 15s or 1m, got %q", value)
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+func sizeSetting(field func(*Config) *int64) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		n, err := ParseSize(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func listSetting(field func(*Config) *[]string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*field(c) = items
+		return nil
+	}
+}
+
+func rateSetting(field func(*Config) *Rate) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		r, err := ParseRate(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = r
+		return nil
+	}
+}
+
+func prefixListSetting(field func(*Config) *[]netip.Prefix) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		var prefixes []netip.Prefix
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				addr, addrErr := netip.ParseAddr(item)
+				if addrErr != nil {
+					return fmt.Errorf("must be IP addresses or CIDR ranges, got %q", item)
+				}
+				prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+			}
+			prefixes = append(prefixes, prefix.Masked())
+		}
+		*field(c) = prefixes
+		return nil
+	}
+}
+
+// ParseRate parses a rate such as "10/1m" or "100/h": a request count, a
+// slash and a period with an optional count. "off" and "0" disable it.
+func ParseRate(value string) (Rate, error) {
+	s := strings.TrimSpace(value)
+	if s == "0" || strings.EqualFold(s, "off") {
+		return Rate{}, nil
+	}
+	count, period, ok := strings.Cut(s, "/")
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if period = strings.TrimSpace(period); period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+	d, durErr := time.ParseDuration(period)
+	if !ok || err != nil || durErr != nil || n < 0 || d <= 0 {
+		return Rate{}, fmt.Errorf("must be a rate such as 10/1m or 100/h, or off, got %q", value)
+	}
+	if n == 0 {
+		return Rate{}, nil
+	}
+	return Rate{Requests: n, Period: d}, nil
+}
+
+// ParseSize parses a byte count with an optional KB, MB or GB suffix
+// (powers of 1024), e.g. "200MB"
+func ParseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	unit := int64(1)
+	for _, u := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", GB}, {"MB", MB}, {"KB", KB}, {"B", 1}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("must be a size such as 1048576, 512KB or 200MB, got %q", value)
+	}
+	return n * unit, nil
+}
+
+// ParseLogLevel converts a log.level value to a slog level
+func ParseLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", level)
+	}
+}
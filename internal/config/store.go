@@ -0,0 +1,75 @@
+package config
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// Store holds the active configuration. Readers take a snapshot with
+// Current and use it for the whole operation, so a reload never leaves a
+// request with a mix of old and new settings.
+type Store struct {
+	current atomic.Pointer[Config]
+	load    func() (*Config, error)
+
+	// mu serializes reloads and guards listeners
+	mu        sync.Mutex
+	listeners []func(*Config)
+}
+
+// NewStore creates a store holding cfg. load builds a validated replacement
+// configuration on every reload.
+func NewStore(cfg *Config, load func() (*Config, error)) *Store {
+	s := &Store{load: load}
+	s.current.Store(cfg)
+	return s
+}
+
+// Current returns the active configuration. It must not be modified.
+func (s *Store) Current() *Config {
+	return s.current.Load()
+}
+
+// OnReload registers fn to be called with every newly applied configuration
+func (s *Store) OnReload(fn func(*Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// Reload loads and validates a new configuration and swaps it in. An
+// invalid configuration is rejected and the current one stays active.
+// Settings that only take effect at startup keep their current values, and
+// the result is validated again with them; the keys of those that changed
+// are returned so callers can report them.
+func (s *Store) Reload() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, err := s.load()
+	if err != nil {
+		slog.Error("Configuration reload rejected", "error", err)
+		return nil, err
+	}
+
+	// The new values of startup-only settings are discarded, so validate
+	// the configuration that will actually be active: a file that drops
+	// auth.token_secret must still provide what the running secret needs
+	restartRequired := next.restartRequired(s.current.Load())
+	if err := next.Validate(); err != nil {
+		slog.Error("Configuration reload rejected", "error", err)
+		return nil, err
+	}
+	s.current.Store(next)
+	for _, fn := range s.listeners {
+		fn(next)
+	}
+
+	if len(restartRequired) > 0 {
+		slog.Warn("Configuration reloaded; some changes require a restart", "restart_required", restartRequired)
+	} else {
+		slog.Info("Configuration reloaded")
+	}
+	return restartRequired, nil
+}
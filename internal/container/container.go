@@ -1,43 +1,146 @@
 package container
 
 import (
+	"context"
+	"time"
+
+	"example.com/myapp/internal/audit"
+	"example.com/myapp/internal/collections"
+	"example.com/myapp/internal/config"
+	"example.com/myapp/internal/events"
+	"example.com/myapp/internal/health"
 	"example.com/myapp/internal/media"
+	"example.com/myapp/internal/outbox"
+	"example.com/myapp/internal/ratelimit"
 	"example.com/myapp/internal/users"
+	"example.com/myapp/internal/webhooks"
 )
 
 type Container struct {
+	// Settings holds the active configuration and applies reloads
+	Settings *config.Store
+
 	// Repositories
-	UserRepository  users.Repository
-	MediaRepository media.Repository
+	UserRepository       users.Repository
+	MediaRepository      media.Repository
+	CollectionRepository collections.Repository
+	AuditRepository      audit.Repository
+	WebhookRepository    webhooks.Repository
+	OutboxStore          outbox.Store
 
 	// Services
-	UserService   *users.Service
-	MediaService  *media.Service
+	UserService       *users.Service
+	MediaService      *media.Service
+	CollectionService *collections.Service
+	AuditService      *audit.Service
+	WebhookService    *webhooks.Service
+
+	// Events
+	EventBus *events.Bus
+	// OutboxRelay publishes staged events to the bus once Run is started
+	OutboxRelay *outbox.Relay
+	// WebhookDispatcher sends webhook deliveries once Run is started
+	WebhookDispatcher *webhooks.Dispatcher
 
 	// Handlers
-	UserHandler   *users.Handler
-	MediaHandler  *media.Handler
+	UserHandler       *users.Handler
+	MediaHandler      *media.Handler
+	CollectionHandler *collections.Handler
+	AuditHandler      *audit.Handler
+	WebhookHandler    *webhooks.Handler
+	OutboxHandler     *outbox.Handler
+	ConfigHandler     *config.Handler
+
+	// Health serves the liveness and readiness probes
+	Health *health.Checker
+	// RateLimitStore holds the rate limiter's token buckets
+	RateLimitStore ratelimit.Store
+
+	// TokenSecret verifies bearer and session tokens, taken from the
+	// auth.token_secret setting
+	TokenSecret []byte
+	// AdminIDs are the principals allowed to use admin endpoints, taken
+	// from the auth.admin_principals setting
+	AdminIDs []string
 }
 
-func NewContainer() *Container {
-	// Initialize repositories
-	userRepo := users.NewInMemoryRepository()
-	mediaRepo := media.NewInMemoryRepository()
+func NewContainer(settings *config.Store) *Container {
+	// Settings that only take effect at startup
+	cfg := settings.Current()
+
+	// Initialize repositories. User and media writes stage their events in
+	// the outbox as part of the same write; domain repositories report
+	// operation latencies.
+	outboxStore := outbox.NewInMemoryStore()
+	userRepo := users.NewInstrumentedRepository(users.NewInMemoryRepository(outboxStore))
+	mediaRepo := media.NewInstrumentedRepository(media.NewInMemoryRepository(outboxStore))
+	collectionRepo := collections.NewInstrumentedRepository(collections.NewInMemoryRepository())
+	auditRepo := audit.NewInMemoryRepository()
+	webhookRepo := webhooks.NewInMemoryRepository()
+
+	// The relay publishes outbox entries to the bus; webhooks receive
+	// every published event
+	eventBus := events.NewBus()
+	outboxRelay := outbox.NewRelay(outboxStore, eventBus, outboxStore.Notify())
+	webhookDispatcher := webhooks.NewDispatcher(webhookRepo)
+	eventBus.Subscribe(events.AllTypes, webhookDispatcher.HandleEvent)
 
 	// Initialize services with repositories
-	userService := users.NewService(userRepo)
-	mediaService := media.NewService(mediaRepo)
+	auditService := audit.NewService(auditRepo)
+	userService := users.NewService(userRepo, auditService)
+	mediaService := media.NewService(mediaRepo, media.DetectTranscoder(), auditService, settings)
+	collectionService := collections.NewService(collectionRepo, mediaRepo)
+	webhookService := webhooks.NewService(webhookRepo, webhookDispatcher)
 
 	// Initialize handlers with services and repositories
 	userHandler := users.NewHandler(userService, userRepo)
-	mediaHandler := media.NewHandler(mediaService)
+	mediaHandler := media.NewHandler(mediaService, cfg.Media.MaxMultipartMemory)
+	collectionHandler := collections.NewHandler(collectionService)
+	auditHandler := audit.NewHandler(auditService)
+	webhookHandler := webhooks.NewHandler(webhookService)
+	outboxHandler := outbox.NewHandler(outboxRelay)
+	configHandler := config.NewHandler(settings)
+
+	// Readiness checks read their thresholds per probe so reloads apply
+	checker := health.NewChecker(func() time.Duration { return settings.Current().Health.CheckTimeout })
+	checker.Register("users_repository", userRepo.Ping)
+	checker.Register("media_repository", mediaRepo.Ping)
+	checker.Register("collections_repository", collectionRepo.Ping)
+	checker.Register("storage", health.StorageWritable(cfg.Media.StoragePath))
+	checker.Register("disk_space", health.DiskSpace(cfg.Media.StoragePath, func() int64 { return settings.Current().Health.MinFreeDisk }))
+	checker.Register("outbox", func(ctx context.Context) error {
+		return outboxRelay.Healthy(ctx, settings.Current().Health.MaxQueueLag)
+	})
+	checker.Register("webhook_dispatcher", func(ctx context.Context) error {
+		return webhookDispatcher.Healthy(settings.Current().Health.MaxQueueLag)
+	})
 
 	return &Container{
-		UserRepository:  userRepo,
-		MediaRepository: mediaRepo,
-		UserService:     userService,
-		MediaService:    mediaService,
-		UserHandler:     userHandler,
-		MediaHandler:    mediaHandler,
+		Settings:             settings,
+		UserRepository:       userRepo,
+		MediaRepository:      mediaRepo,
+		CollectionRepository: collectionRepo,
+		AuditRepository:      auditRepo,
+		WebhookRepository:    webhookRepo,
+		OutboxStore:          outboxStore,
+		UserService:          userService,
+		MediaService:         mediaService,
+		CollectionService:    collectionService,
+		AuditService:         auditService,
+		WebhookService:       webhookService,
+		EventBus:             eventBus,
+		OutboxRelay:          outboxRelay,
+		WebhookDispatcher:    webhookDispatcher,
+		UserHandler:          userHandler,
+		MediaHandler:         mediaHandler,
+		CollectionHandler:    collectionHandler,
+		AuditHandler:         auditHandler,
+		WebhookHandler:       webhookHandler,
+		OutboxHandler:        outboxHandler,
+		ConfigHandler:        configHandler,
+		Health:               checker,
+		RateLimitStore:       ratelimit.NewMemoryStore(),
+		TokenSecret:          []byte(cfg.Auth.TokenSecret),
+		AdminIDs:             cfg.Auth.AdminPrincipals,
 	}
-}
\ No newline at end of file
+}
@@ -10,6 +10,13 @@ type AppError struct {
 	Code    string
 	Message string
 	Err     error
+	// Fields lists per-field validation failures, if any
+	Fields []FieldError
+}
+
+// Unwrap returns the wrapped cause
+func (e *AppError) Unwrap() error {
+	return e.Err
 }
 
 func (e *AppError) Error() string {
@@ -21,12 +28,21 @@ func (e *AppError) Error() string {
 
 // Error codes
 const (
-	ErrCodeNotFound      = "NOT_FOUND"
-	ErrCodeBadRequest    = "BAD_REQUEST"
-	ErrCodeInternal      = "INTERNAL_ERROR"
-	ErrCodeInvalidID     = "INVALID_ID"
-	ErrCodeFileTooLarge  = "FILE_TOO_LARGE"
-	ErrCodeUnsupported   = "UNSUPPORTED_TYPE"
+	ErrCodeNotFound         = "NOT_FOUND"
+	ErrCodeBadRequest       = "BAD_REQUEST"
+	ErrCodeInternal         = "INTERNAL_ERROR"
+	ErrCodeInvalidID        = "INVALID_ID"
+	ErrCodeFileTooLarge     = "FILE_TOO_LARGE"
+	ErrCodeUnsupported      = "UNSUPPORTED_TYPE"
+	ErrCodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+	ErrCodeUnauthorized     = "UNAUTHORIZED"
+	ErrCodeForbidden        = "FORBIDDEN"
+	ErrCodeConflict         = "CONFLICT"
+	ErrCodePrecondition     = "PRECONDITION_FAILED"
+	ErrCodeRateLimited      = "RATE_LIMITED"
+	ErrCodeQuotaExceeded    = "QUOTA_EXCEEDED"
+	ErrCodeUnavailable      = "UNAVAILABLE"
+	ErrCodeValidation       = "VALIDATION_FAILED"
 )
 
 // Constructors
@@ -38,6 +54,11 @@ func BadRequest(message string) *AppError {
 	return &AppError{Code: ErrCodeBadRequest, Message: message}
 }
 
+// Validation reports request fields that failed validation
+func Validation(fields ...FieldError) *AppError {
+	return &AppError{Code: ErrCodeValidation, Message: "one or more fields are invalid", Fields: fields}
+}
+
 func Internal(message string, err error) *AppError {
 	return &AppError{Code: ErrCodeInternal, Message: message, Err: err}
 }
@@ -54,9 +75,55 @@ func UnsupportedType(message string) *AppError {
 	return &AppError{Code: ErrCodeUnsupported, Message: message}
 }
 
+func MethodNotAllowed(message string) *AppError {
+	return &AppError{Code: ErrCodeMethodNotAllowed, Message: message}
+}
+
+func Unauthorized(message string) *AppError {
+	return &AppError{Code: ErrCodeUnauthorized, Message: message}
+}
+
+func Forbidden(message string) *AppError {
+	return &AppError{Code: ErrCodeForbidden, Message: message}
+}
+
+func Conflict(message string) *AppError {
+	return &AppError{Code: ErrCodeConflict, Message: message}
+}
+
+func PreconditionFailed(message string) *AppError {
+	return &AppError{Code: ErrCodePrecondition, Message: message}
+}
+
+func RateLimited(message string) *AppError {
+	return &AppError{Code: ErrCodeRateLimited, Message: message}
+}
+
+func QuotaExceeded(message string) *AppError {
+	return &AppError{Code: ErrCodeQuotaExceeded, Message: message}
+}
+
+func Unavailable(message string, err error) *AppError {
+	return &AppError{Code: ErrCodeUnavailable, Message: message, Err: err}
+}
+
 // IsAppError checks if an error is an AppError
 func IsAppError(err error) bool {
-	return errors.As(err, &AppError{})
+	return GetAppError(err) != nil
+}
+
+// HasCode reports whether err is an AppError with one of the given codes
+func HasCode(err error, codes ...string) bool {
+	ae := GetAppError(err)
+	if ae == nil {
+		return false
+	}
+	for _, code := range codes {
+		if ae.Code == code {
+			return true
+		}
+	}
+	return false
 }
 
 // GetAppError extracts AppError from wrapped errors
@@ -0,0 +1,97 @@
+package errors
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"example.com/myapp/internal/requestid"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// problemTypeBase prefixes the type URI of every problem; the code is
+// appended in kebab case, e.g. /problems/not-found
+const problemTypeBase = "/problems/"
+
+// Problem is an RFC 7807 problem details body, extended with the application
+// error code, the request ID and field-level validation errors
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Code      string       `json:"code"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Retryable bool         `json:"retryable,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why a single request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// NewProblem builds the problem body for err, resolving status and title
+// through the code registry. Only the message of a client error (4xx) is
+// exposed; server errors, wrapped causes and non-AppErrors are reduced to
+// the registered public message.
+func NewProblem(r *http.Request, err error) *Problem {
+	code := ErrCodeInternal
+	ae := GetAppError(err)
+	if ae != nil {
+		code = ae.Code
+	}
+	info := Lookup(code)
+
+	detail := info.PublicMessage
+	var fields []FieldError
+	if ae != nil && info.Status < http.StatusInternalServerError {
+		detail = ae.Message
+		fields = ae.Fields
+	}
+
+	return &Problem{
+		Type:      problemTypeBase + strings.ReplaceAll(strings.ToLower(code), "_", "-"),
+		Title:     info.PublicMessage,
+		Status:    info.Status,
+		Code:      code,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		RequestID: requestid.FromContext(r.Context()),
+		Retryable: info.Retryable,
+		Errors:    fields,
+	}
+}
+
+// WriteError renders err as application/problem+json. The failure is logged
+// with its full cause at the code's registered level; the cause is never
+// included in the response.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	problem := NewProblem(r, err)
+
+	slog.Log(r.Context(), Lookup(problem.Code).LogLevel, "Request failed",
+		"status", problem.Status,
+		"code", problem.Code,
+		"path", r.URL.Path,
+		"error", err,
+	)
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// NotFoundHandler renders unmatched routes as problems
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, NotFound("no route matches "+r.URL.Path))
+}
+
+// MethodNotAllowedHandler renders unsupported methods as problems
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, MethodNotAllowed(r.Method+" is not allowed on "+r.URL.Path))
+}
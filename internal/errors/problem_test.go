@@ -0,0 +1,124 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"example.com/myapp/internal/requestid"
+)
+
+func TestNewProblem(t *testing.T) {
+	Register("TEST_TEAPOT", CodeInfo{Status: http.StatusTeapot, PublicMessage: "I'm a teapot"})
+	cause := errors.New("pq: connection refused to 10.0.0.5")
+	fields := []FieldError{{Field: "name", Message: "is required"}}
+
+	tests := []struct {
+		name string
+		err  error
+		want Problem
+	}{
+		{
+			name: "client error exposes its message",
+			err:  NotFound("media 42 not found"),
+			want: Problem{Type: "/problems/not-found", Title: "The resource was not found", Status: 404, Code: ErrCodeNotFound, Detail: "media 42 not found"},
+		},
+		{
+			name: "validation keeps the field errors",
+			err:  Validation(fields...),
+			want: Problem{Type: "/problems/validation-failed", Title: "The request failed validation", Status: 400, Code: ErrCodeValidation, Detail: "one or more fields are invalid", Errors: fields},
+		},
+		{
+			name: "wrapped client error",
+			err:  fmt.Errorf("handler: %w", Conflict("already exists")),
+			want: Problem{Type: "/problems/conflict", Title: "The request conflicts with the current state", Status: 409, Code: ErrCodeConflict, Detail: "already exists"},
+		},
+		{
+			name: "internal error hides its message and cause",
+			err:  Internal("failed to query users table", cause),
+			want: Problem{Type: "/problems/internal-error", Title: "An internal error occurred", Status: 500, Code: ErrCodeInternal, Detail: "An internal error occurred"},
+		},
+		{
+			name: "unavailable uses the public message",
+			err:  Unavailable("decoder pool exhausted", cause),
+			want: Problem{Type: "/problems/unavailable", Title: "The service is temporarily unavailable", Status: 503, Code: ErrCodeUnavailable, Detail: "The service is temporarily unavailable", Retryable: true},
+		},
+		{
+			name: "plain error is internal",
+			err:  cause,
+			want: Problem{Type: "/problems/internal-error", Title: "An internal error occurred", Status: 500, Code: ErrCodeInternal, Detail: "An internal error occurred"},
+		},
+		{
+			name: "unregistered code is treated as internal",
+			err:  &AppError{Code: "NO_SUCH_CODE", Message: "secret detail"},
+			want: Problem{Type: "/problems/no-such-code", Title: "An internal error occurred", Status: 500, Code: "NO_SUCH_CODE", Detail: "An internal error occurred"},
+		},
+		{
+			name: "registered code",
+			err:  &AppError{Code: "TEST_TEAPOT", Message: "short and stout"},
+			want: Problem{Type: "/problems/test-teapot", Title: "I'm a teapot", Status: 418, Code: "TEST_TEAPOT", Detail: "short and stout"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/media/42?x=1", nil)
+			r = r.WithContext(requestid.NewContext(r.Context(), "req-1"))
+
+			got := NewProblem(r, tt.err)
+			want := tt.want
+			want.Instance, want.RequestID = "/media/42", "req-1"
+			if !reflect.DeepEqual(*got, want) {
+				t.Errorf("NewProblem() = %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteError(w, httptest.NewRequest(http.MethodDelete, "/users/7", nil), Internal("boom", errors.New("disk on fire")))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ProblemContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, ProblemContentType)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	for _, leaked := range []string{"boom", "disk on fire"} {
+		for k, v := range body {
+			if s, ok := v.(string); ok && s == leaked {
+				t.Errorf("body field %q leaks %q", k, leaked)
+			}
+		}
+	}
+	if body["detail"] != "An internal error occurred" || body["instance"] != "/users/7" {
+		t.Errorf("body = %v", body)
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: nil, want: http.StatusOK},
+		{err: BadRequest("x"), want: http.StatusBadRequest},
+		{err: fmt.Errorf("wrapped: %w", RateLimited("slow down")), want: http.StatusTooManyRequests},
+		{err: QuotaExceeded("x"), want: http.StatusForbidden},
+		{err: errors.New("plain"), want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := StatusCode(tt.err); got != tt.want {
+			t.Errorf("StatusCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
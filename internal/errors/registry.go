@@ -0,0 +1,75 @@
+package errors
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// CodeInfo declares how an error code is surfaced over HTTP
+type CodeInfo struct {
+	// Status is the HTTP status code returned for the code
+	Status int
+	// LogLevel is the level failures with this code are logged at
+	LogLevel slog.Level
+	// Retryable tells clients the same request may succeed later
+	Retryable bool
+	// PublicMessage is the short, stable summary shown as the problem title
+	PublicMessage string
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]CodeInfo{
+		ErrCodeBadRequest:       {Status: http.StatusBadRequest, LogLevel: slog.LevelInfo, PublicMessage: "The request is invalid"},
+		ErrCodeValidation:       {Status: http.StatusBadRequest, LogLevel: slog.LevelInfo, PublicMessage: "The request failed validation"},
+		ErrCodeInvalidID:        {Status: http.StatusBadRequest, LogLevel: slog.LevelInfo, PublicMessage: "The ID is invalid"},
+		ErrCodeUnauthorized:     {Status: http.StatusUnauthorized, LogLevel: slog.LevelWarn, PublicMessage: "Authentication is required"},
+		ErrCodeForbidden:        {Status: http.StatusForbidden, LogLevel: slog.LevelWarn, PublicMessage: "Access to the resource is forbidden"},
+		ErrCodeNotFound:         {Status: http.StatusNotFound, LogLevel: slog.LevelInfo, PublicMessage: "The resource was not found"},
+		ErrCodeMethodNotAllowed: {Status: http.StatusMethodNotAllowed, LogLevel: slog.LevelInfo, PublicMessage: "The method is not allowed"},
+		ErrCodeConflict:         {Status: http.StatusConflict, LogLevel: slog.LevelInfo, PublicMessage: "The request conflicts with the current state"},
+		ErrCodePrecondition:     {Status: http.StatusPreconditionFailed, LogLevel: slog.LevelInfo, PublicMessage: "The resource has been modified"},
+		ErrCodeFileTooLarge:     {Status: http.StatusRequestEntityTooLarge, LogLevel: slog.LevelInfo, PublicMessage: "The file is too large"},
+		ErrCodeUnsupported:      {Status: http.StatusUnsupportedMediaType, LogLevel: slog.LevelInfo, PublicMessage: "The file type is not supported"},
+		ErrCodeQuotaExceeded:    {Status: http.StatusForbidden, LogLevel: slog.LevelWarn, PublicMessage: "The quota has been exceeded"},
+		ErrCodeRateLimited:      {Status: http.StatusTooManyRequests, LogLevel: slog.LevelWarn, Retryable: true, PublicMessage: "Too many requests"},
+		ErrCodeInternal:         {Status: http.StatusInternalServerError, LogLevel: slog.LevelError, PublicMessage: "An internal error occurred"},
+		ErrCodeUnavailable:      {Status: http.StatusServiceUnavailable, LogLevel: slog.LevelWarn, Retryable: true, PublicMessage: "The service is temporarily unavailable"},
+	}
+)
+
+// Register declares or overrides how a code is surfaced over HTTP
+func Register(code string, info CodeInfo) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[code] = info
+}
+
+// Lookup returns the registered info for a code. Unknown codes are treated
+// as internal errors.
+func Lookup(code string) CodeInfo {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if info, ok := registry[code]; ok {
+		return info
+	}
+	return registry[ErrCodeInternal]
+}
+
+// InfoFor resolves the code info for any error; errors that are not
+// AppErrors are internal
+func InfoFor(err error) CodeInfo {
+	if ae := GetAppError(err); ae != nil {
+		return Lookup(ae.Code)
+	}
+	return Lookup(ErrCodeInternal)
+}
+
+// StatusCode returns the HTTP status for err
+func StatusCode(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return InfoFor(err).Status
+}
@@ -0,0 +1,48 @@
+package errors
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		code      string
+		status    int
+		level     slog.Level
+		retryable bool
+	}{
+		{code: ErrCodeBadRequest, status: http.StatusBadRequest, level: slog.LevelInfo},
+		{code: ErrCodeUnauthorized, status: http.StatusUnauthorized, level: slog.LevelWarn},
+		{code: ErrCodePrecondition, status: http.StatusPreconditionFailed, level: slog.LevelInfo},
+		{code: ErrCodeRateLimited, status: http.StatusTooManyRequests, level: slog.LevelWarn, retryable: true},
+		{code: ErrCodeUnavailable, status: http.StatusServiceUnavailable, level: slog.LevelWarn, retryable: true},
+		{code: ErrCodeInternal, status: http.StatusInternalServerError, level: slog.LevelError},
+		{code: "NOT_REGISTERED", status: http.StatusInternalServerError, level: slog.LevelError},
+	}
+	for _, tt := range tests {
+		info := Lookup(tt.code)
+		if info.Status != tt.status || info.LogLevel != tt.level || info.Retryable != tt.retryable {
+			t.Errorf("Lookup(%s) = %+v, want status %d, level %v, retryable %v", tt.code, info, tt.status, tt.level, tt.retryable)
+		}
+		if info.PublicMessage == "" {
+			t.Errorf("Lookup(%s) has no public message", tt.code)
+		}
+	}
+}
+
+func TestRegisterOverrides(t *testing.T) {
+	const code = "TEST_REGISTER"
+	Register(code, CodeInfo{Status: http.StatusPaymentRequired, PublicMessage: "Payment required"})
+	Register(code, CodeInfo{Status: http.StatusGone, PublicMessage: "Gone"})
+
+	err := fmt.Errorf("wrapped: %w", &AppError{Code: code, Message: "x"})
+	if got := InfoFor(err); got.Status != http.StatusGone || got.PublicMessage != "Gone" {
+		t.Errorf("InfoFor() = %+v, want the last registration", got)
+	}
+	if got := StatusCode(err); got != http.StatusGone {
+		t.Errorf("StatusCode() = %d, want %d", got, http.StatusGone)
+	}
+}
@@ -0,0 +1,68 @@
+// Package etag maps resource versions to HTTP entity tags and evaluates
+// If-Match preconditions for optimistic concurrency control.
+package etag
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	appErr "example.com/myapp/internal/errors"
+)
+
+// Format returns the strong entity tag for a resource version
+func Format(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// Set writes the ETag header for a resource version
+func Set(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", Format(version))
+}
+
+// Condition is a parsed If-Match header. The zero value has no
+// precondition and matches every version.
+type Condition struct {
+	present bool
+	tags    []string
+}
+
+// IfMatch parses the If-Match header of r
+func IfMatch(r *http.Request) Condition {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return Condition{}
+	}
+
+	c := Condition{present: true}
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			c.tags = append(c.tags, tag)
+		}
+	}
+	return c
+}
+
+// Matches reports whether version satisfies the condition. If-Match uses
+// strong comparison, so weak tags never match.
+func (c Condition) Matches(version int) bool {
+	if !c.present {
+		return true
+	}
+	current := Format(version)
+	for _, tag := range c.tags {
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns a PreconditionFailed error when version does not satisfy
+// the condition
+func (c Condition) Check(version int) error {
+	if !c.Matches(version) {
+		return appErr.PreconditionFailed("resource has been modified; fetch the current version and retry")
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appErr "example.com/myapp/internal/errors"
+)
+
+func condition(header string) Condition {
+	r := httptest.NewRequest(http.MethodPut, "/", nil)
+	if header != "" {
+		r.Header.Set("If-Match", header)
+	}
+	return IfMatch(r)
+}
+
+func TestConditionMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		version int
+		want    bool
+	}{
+		{name: "no header", header: "", version: 3, want: true},
+		{name: "blank header", header: "  ", version: 3, want: true},
+		{name: "current tag", header: `"3"`, version: 3, want: true},
+		{name: "stale tag", header: `"2"`, version: 3, want: false},
+		{name: "unquoted tag", header: `3`, version: 3, want: false},
+		{name: "weak tag never matches", header: `W/"3"`, version: 3, want: false},
+		{name: "wildcard", header: `*`, version: 9, want: true},
+		{name: "list", header: `"1", "3" ,"5"`, version: 3, want: true},
+		{name: "list without a match", header: `"1","2"`, version: 3, want: false},
+		{name: "empty list entries", header: `, ,`, version: 1, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := condition(tt.header)
+			if got := c.Matches(tt.version); got != tt.want {
+				t.Errorf("Matches(%d) with If-Match %q = %v, want %v", tt.version, tt.header, got, tt.want)
+			}
+			err := c.Check(tt.version)
+			if tt.want && err != nil {
+				t.Errorf("Check() = %v, want nil", err)
+			}
+			if !tt.want && !appErr.HasCode(err, appErr.ErrCodePrecondition) {
+				t.Errorf("Check() = %v, want PRECONDITION_FAILED", err)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	w := httptest.NewRecorder()
+	Set(w, 12)
+	if got := w.Header().Get("ETag"); got != `"12"` {
+		t.Errorf("ETag = %s, want \"12\"", got)
+	}
+	if !condition(Format(12)).Matches(12) {
+		t.Error("a formatted tag does not match its own version")
+	}
+}
@@ -0,0 +1,71 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// AllTypes subscribes a handler to every event type
+const AllTypes = "*"
+
+// Handler reacts to a published event. Events can be delivered more than
+// once, so handlers must be idempotent, keyed on Event.ID. Returning an
+// error asks the publisher to deliver the event again later.
+type Handler func(ctx context.Context, event Event) error
+
+// Publisher delivers events to subscribers
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Bus dispatches events to the handlers subscribed to their type
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewBus creates an event bus without subscribers
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe registers h for eventType, or for every event when eventType
+// is AllTypes
+func (b *Bus) Subscribe(eventType string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], h)
+}
+
+// Publish calls the matching handlers synchronously in subscription order
+// and returns their joined errors. Handlers should hand slow work off to
+// their own goroutines; a panicking handler is reported as an error and
+// does not affect the others.
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.handlers[event.Type])+len(b.handlers[AllTypes]))
+	handlers = append(handlers, b.handlers[event.Type]...)
+	handlers = append(handlers, b.handlers[AllTypes]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, h := range handlers {
+		if err := b.call(ctx, h, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *Bus) call(ctx context.Context, h Handler, event Event) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			slog.ErrorContext(ctx, "Event handler panicked", "type", event.Type, "event_id", event.ID, "panic", rec)
+			err = fmt.Errorf("event handler panicked: %v", rec)
+		}
+	}()
+	return h(ctx, event)
+}
@@ -0,0 +1,100 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBusPublish(t *testing.T) {
+	tests := []struct {
+		name      string
+		event     string
+		wantCalls []string
+		wantErrs  []string
+	}{
+		{name: "type and wildcard subscribers in order", event: UserCreated, wantCalls: []string{"user.created", "user.created again", "all"}},
+		{name: "only wildcard subscribers", event: MediaDeleted, wantCalls: []string{"all"}},
+		{name: "failing handler does not stop the others", event: UserDeleted, wantCalls: []string{"fails", "all"}, wantErrs: []string{"delivery failed"}},
+		{name: "panicking handler is reported", event: MediaUploaded, wantCalls: []string{"panics", "all"}, wantErrs: []string{"event handler panicked: boom"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			record := func(name string, err error) Handler {
+				return func(ctx context.Context, e Event) error {
+					calls = append(calls, name)
+					return err
+				}
+			}
+			bus := NewBus()
+			bus.Subscribe(UserCreated, record("user.created", nil))
+			bus.Subscribe(UserCreated, record("user.created again", nil))
+			bus.Subscribe(UserDeleted, record("fails", errors.New("delivery failed")))
+			bus.Subscribe(MediaUploaded, func(ctx context.Context, e Event) error {
+				calls = append(calls, "panics")
+				panic("boom")
+			})
+			bus.Subscribe(AllTypes, record("all", nil))
+
+			event, err := New(tt.event, "42", map[string]string{"name": "x"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = bus.Publish(context.Background(), event)
+
+			if strings.Join(calls, ",") != strings.Join(tt.wantCalls, ",") {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+			for _, want := range tt.wantErrs {
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("Publish() error = %v, want %q", err, want)
+				}
+			}
+			if len(tt.wantErrs) == 0 && err != nil {
+				t.Errorf("Publish() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     interface{}
+		wantData string
+		wantErr  bool
+	}{
+		{name: "object", data: map[string]int{"id": 7}, wantData: `{"id":7}`},
+		{name: "nil", data: nil, wantData: `null`},
+		{name: "unencodable", data: func() {}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := New(UserUpdated, "7", tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if e.ID == "" || e.Type != UserUpdated || e.Subject != "7" || e.OccurredAt.IsZero() || e.OccurredAt.Location().String() != "UTC" {
+				t.Errorf("event = %+v", e)
+			}
+			if string(e.Data) != tt.wantData {
+				t.Errorf("data = %s, want %s", e.Data, tt.wantData)
+			}
+			other, _ := New(UserUpdated, "7", tt.data)
+			if other.ID == e.ID {
+				t.Error("events share an ID")
+			}
+			if _, err := json.Marshal(e); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Package events publishes domain events to in-process subscribers.
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types published by the services
+const (
+	UserCreated   = "user.created"
+	UserUpdated   = "user.updated"
+	UserDeleted   = "user.deleted"
+	MediaUploaded = "media.uploaded"
+	MediaDeleted  = "media.deleted"
+)
+
+// Types lists every event type
+var Types = []string{UserCreated, UserUpdated, UserDeleted, MediaUploaded, MediaDeleted}
+
+// Event is an immutable notification that something happened to a domain
+// object. Data holds the JSON form of the object after the change, or
+// before it for deletions. ID is unique per event and serves as the
+// idempotency key for consumers.
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Subject    string          `json:"subject"` // ID of the user or media item
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// New creates an event with a fresh ID, encoding data as its payload
+func New(eventType, subject string, data interface{}) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		Subject:    subject,
+		OccurredAt: time.Now().UTC(),
+		Data:       payload,
+	}, nil
+}
@@ -0,0 +1,48 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// StorageWritable checks that a file can be created, written and removed
+// in dir
+func StorageWritable(dir string) Check {
+	return func(ctx context.Context) error {
+		f, err := os.CreateTemp(dir, ".readyz-*")
+		if err != nil {
+			return fmt.Errorf("storage is not writable: %w", err)
+		}
+		defer os.Remove(f.Name())
+
+		_, err = f.Write([]byte("ok"))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("storage is not writable: %w", err)
+		}
+		return nil
+	}
+}
+
+// DiskSpace checks that the file system holding dir has at least minFree
+// bytes available. Platforms without support for reading free space
+// always pass.
+func DiskSpace(dir string, minFree func() int64) Check {
+	return func(ctx context.Context) error {
+		free, err := freeDiskSpace(dir)
+		if errors.Is(err, errors.ErrUnsupported) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read free disk space: %w", err)
+		}
+		if want := minFree(); free < uint64(want) {
+			return fmt.Errorf("%d bytes free, %d required", free, want)
+		}
+		return nil
+	}
+}
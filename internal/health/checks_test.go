@@ -0,0 +1,68 @@
+package health
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestStorageWritable(t *testing.T) {
+	tests := []struct {
+		name    string
+		dir     func(t *testing.T) string
+		wantErr bool
+	}{
+		{name: "writable", dir: func(t *testing.T) string { return t.TempDir() }},
+		{name: "missing", dir: func(t *testing.T) string { return filepath.Join(t.TempDir(), "missing") }, wantErr: true},
+		{name: "file instead of directory", dir: func(t *testing.T) string {
+			path := filepath.Join(t.TempDir(), "file")
+			if err := os.WriteFile(path, nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			return path
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tt.dir(t)
+			err := StorageWritable(dir)(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StorageWritable() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				entries, _ := os.ReadDir(dir)
+				if len(entries) != 0 {
+					t.Errorf("check left %d files behind", len(entries))
+				}
+			}
+		})
+	}
+}
+
+func TestDiskSpace(t *testing.T) {
+	supported := runtime.GOOS == "linux" || runtime.GOOS == "darwin" || runtime.GOOS == "freebsd"
+
+	tests := []struct {
+		name    string
+		dir     string
+		minFree int64
+		wantErr bool
+	}{
+		{name: "no minimum", dir: t.TempDir()},
+		{name: "more than any disk", dir: t.TempDir(), minFree: math.MaxInt64, wantErr: supported},
+		{name: "missing directory", dir: filepath.Join(t.TempDir(), "missing"), wantErr: supported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DiskSpace(tt.dir, func() int64 { return tt.minFree })(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DiskSpace() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Package health serves the liveness and readiness probes. Liveness only
+// shows that the process can serve HTTP; readiness runs every registered
+// check and fails while the server is draining for shutdown.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Check statuses
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check reports why a dependency cannot serve requests, or nil when it can
+type Check func(ctx context.Context) error
+
+// CheckResult is the outcome of one readiness check
+type CheckResult struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// Response is the body of both probes
+type Response struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the readiness checks. timeout returns the time limit of
+// each check so it can follow configuration reloads.
+type Checker struct {
+	timeout  func() time.Duration
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+// NewChecker creates a checker without checks
+func NewChecker(timeout func() time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Register adds a named readiness check. Register all checks before
+// serving requests.
+func (c *Checker) Register(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Drain makes readiness fail from now on and waits delay, so load
+// balancers notice and stop sending new requests before the server shuts
+// down
+func (c *Checker) Drain(delay time.Duration) {
+	if !c.draining.Swap(true) {
+		slog.Info("Readiness set to failing for shutdown", "drain_delay", delay)
+	}
+	time.Sleep(delay)
+}
+
+// RegisterRoutes registers the probes. They need no authentication and are
+// not access logged, since orchestrators call them every few seconds.
+func (c *Checker) RegisterRoutes(r chi.Router) {
+	r.Get("/healthz", c.Liveness)
+	r.Get("/readyz", c.Readiness)
+}
+
+// Liveness reports that the process serves HTTP - GET /healthz
+func (c *Checker) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, Response{Status: StatusOK})
+}
+
+// Readiness runs every check concurrently and fails with 503 when any of
+// them fails or the server is draining - GET /readyz
+func (c *Checker) Readiness(w http.ResponseWriter, r *http.Request) {
+	results := c.run(r.Context())
+
+	resp := Response{Status: StatusOK, Checks: results}
+	status := http.StatusOK
+	for name, result := range results {
+		if result.Status != StatusOK {
+			resp.Status = StatusFail
+			status = http.StatusServiceUnavailable
+			if name != "shutdown" {
+				slog.WarnContext(r.Context(), "Readiness check failed", "check", name, "error", result.Error)
+			}
+		}
+	}
+	writeResponse(w, status, resp)
+}
+
+// run executes the checks, each bounded by the configured timeout. A check
+// that does not return in time is reported as failed; its goroutine is left
+// to finish on its own.
+func (c *Checker) run(ctx context.Context) map[string]CheckResult {
+	timeout := c.timeout()
+	results := make(map[string]CheckResult, len(c.checks)+1)
+
+	shutdown := CheckResult{Status: StatusOK}
+	if c.draining.Load() {
+		shutdown = CheckResult{Status: StatusFail, Error: "server is shutting down"}
+	}
+	results["shutdown"] = shutdown
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			done := make(chan error, 1)
+			go func() { done <- nc.check(checkCtx) }()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-checkCtx.Done():
+				err = checkCtx.Err()
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				err = errors.New("timed out after " + timeout.String())
+			}
+
+			result := CheckResult{
+				Status:     StatusOK,
+				DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[nc.name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,166 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func probe(t *testing.T, c *Checker, path string) (int, Response) {
+	t.Helper()
+	r := chi.NewRouter()
+	c.RegisterRoutes(r)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	if got := w.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", got)
+	}
+	var resp Response
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, resp
+}
+
+func TestReadiness(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	failing := func(ctx context.Context) error { return errors.New("connection refused") }
+	hanging := func(ctx context.Context) error { <-ctx.Done(); time.Sleep(time.Second); return nil }
+
+	tests := []struct {
+		name       string
+		checks     map[string]Check
+		drain      bool
+		wantStatus int
+		wantChecks map[string]CheckResult
+	}{
+		{
+			name:       "no checks",
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]CheckResult{"shutdown": {Status: StatusOK}},
+		},
+		{
+			name:       "all pass",
+			checks:     map[string]Check{"db": ok, "storage": ok},
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]CheckResult{"shutdown": {Status: StatusOK}, "db": {Status: StatusOK}, "storage": {Status: StatusOK}},
+		},
+		{
+			name:       "one fails",
+			checks:     map[string]Check{"db": failing, "storage": ok},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]CheckResult{"shutdown": {Status: StatusOK}, "db": {Status: StatusFail, Error: "connection refused"}, "storage": {Status: StatusOK}},
+		},
+		{
+			name:       "check times out",
+			checks:     map[string]Check{"db": hanging},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]CheckResult{"shutdown": {Status: StatusOK}, "db": {Status: StatusFail, Error: "timed out after 20ms"}},
+		},
+		{
+			name:       "draining with healthy checks",
+			checks:     map[string]Check{"db": ok},
+			drain:      true,
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]CheckResult{"shutdown": {Status: StatusFail, Error: "server is shutting down"}, "db": {Status: StatusOK}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(func() time.Duration { return 20 * time.Millisecond })
+			for name, check := range tt.checks {
+				c.Register(name, check)
+			}
+			if tt.drain {
+				c.Drain(0)
+			}
+
+			start := time.Now()
+			status, resp := probe(t, c, "/readyz")
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("probe took %v; hanging checks must not hold it", elapsed)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			wantBody := StatusOK
+			if tt.wantStatus != http.StatusOK {
+				wantBody = StatusFail
+			}
+			if resp.Status != wantBody {
+				t.Errorf("body status = %q, want %q", resp.Status, wantBody)
+			}
+			if len(resp.Checks) != len(tt.wantChecks) {
+				t.Errorf("checks = %v, want %v", resp.Checks, tt.wantChecks)
+			}
+			for name, want := range tt.wantChecks {
+				got := resp.Checks[name]
+				got.DurationMs = 0
+				if got != want {
+					t.Errorf("check %s = %+v, want %+v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDrain(t *testing.T) {
+	c := NewChecker(func() time.Duration { return time.Second })
+	c.Register("db", func(ctx context.Context) error { return nil })
+
+	steps := []struct {
+		name          string
+		drain         bool
+		wantReadiness int
+	}{
+		{name: "serving", wantReadiness: http.StatusOK},
+		{name: "draining", drain: true, wantReadiness: http.StatusServiceUnavailable},
+		{name: "drained twice", drain: true, wantReadiness: http.StatusServiceUnavailable},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if step.drain {
+				c.Drain(0)
+			}
+			if status, _ := probe(t, c, "/readyz"); status != step.wantReadiness {
+				t.Errorf("readiness = %d, want %d", status, step.wantReadiness)
+			}
+			// Liveness keeps passing so the orchestrator does not restart
+			// the process while in-flight requests finish
+			status, resp := probe(t, c, "/healthz")
+			if status != http.StatusOK || resp.Status != StatusOK || resp.Checks != nil {
+				t.Errorf("liveness = %d %+v, want 200 ok without checks", status, resp)
+			}
+		})
+	}
+}
+
+func TestDrainWaitsForDelay(t *testing.T) {
+	c := NewChecker(func() time.Duration { return time.Second })
+	const delay = 100 * time.Millisecond
+
+	start := time.Now()
+	drained := make(chan time.Duration)
+	go func() {
+		c.Drain(delay)
+		drained <- time.Since(start)
+	}()
+
+	// Readiness fails while the server waits out the delay
+	time.Sleep(delay / 4)
+	if status, _ := probe(t, c, "/readyz"); status != http.StatusServiceUnavailable {
+		t.Errorf("readiness during drain = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+	if elapsed := <-drained; elapsed < delay {
+		t.Errorf("Drain returned after %v, want at least %v", elapsed, delay)
+	}
+}
@@ -0,0 +1,54 @@
+// Package logging provides the slog handler used by the application.
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/go-chi/chi/v5"
+
+	"example.com/myapp/internal/auth"
+	"example.com/myapp/internal/requestid"
+	"example.com/myapp/internal/tracing"
+)
+
+// ContextHandler adds the request ID, user ID, route pattern and trace
+// found in the context to every record, so any log call made with a request context
+// (slog.InfoContext and friends) can be correlated with the request
+type ContextHandler struct {
+	slog.Handler
+}
+
+// NewContextHandler wraps handler
+func NewContextHandler(handler slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: handler}
+}
+
+// Handle adds the context attributes and passes the record on
+func (h *ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := requestid.FromContext(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if p := auth.FromContext(ctx); !p.IsAnonymous() {
+		record.AddAttrs(slog.String("user_id", p.ID))
+	}
+	if sc := tracing.SpanContextFromContext(ctx); sc.IsValid() {
+		record.AddAttrs(slog.String("trace_id", sc.TraceID.String()), slog.String("span_id", sc.SpanID.String()))
+	}
+	if rctx := chi.RouteContext(ctx); rctx != nil {
+		if route := rctx.RoutePattern(); route != "" {
+			record.AddAttrs(slog.String("route", route))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs keeps the context attributes on derived loggers
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup keeps the context attributes on derived loggers
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"example.com/myapp/internal/auth"
+	"example.com/myapp/internal/requestid"
+	"example.com/myapp/internal/tracing"
+)
+
+func TestContextHandler(t *testing.T) {
+	sc, ok := tracing.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok {
+		t.Fatal("invalid traceparent")
+	}
+
+	tests := []struct {
+		name string
+		ctx  func() context.Context
+		want map[string]string
+	}{
+		{
+			name: "no request context",
+			ctx:  context.Background,
+			want: map[string]string{},
+		},
+		{
+			name: "request ID and principal",
+			ctx: func() context.Context {
+				ctx := requestid.NewContext(context.Background(), "req-1")
+				return auth.WithPrincipal(ctx, auth.Principal{ID: "alice"})
+			},
+			want: map[string]string{"request_id": "req-1", "user_id": "alice"},
+		},
+		{
+			name: "anonymous principal is omitted",
+			ctx: func() context.Context {
+				return auth.WithPrincipal(context.Background(), auth.Principal{ID: auth.Anonymous})
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "remote trace",
+			ctx: func() context.Context {
+				return tracing.ContextWithRemoteSpanContext(context.Background(), sc)
+			},
+			want: map[string]string{"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736", "span_id": "00f067aa0ba902b7"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewContextHandler(slog.NewJSONHandler(&buf, nil)))
+			logger.InfoContext(tt.ctx(), "hello")
+
+			got := decodeRecord(t, &buf)
+			for _, key := range []string{"request_id", "user_id", "trace_id", "span_id", "route"} {
+				if got[key] != tt.want[key] {
+					t.Errorf("%s = %q, want %q", key, got[key], tt.want[key])
+				}
+			}
+		})
+	}
+}
+
+func TestContextHandlerRoute(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewContextHandler(slog.NewJSONHandler(&buf, nil))).With("component", "test").WithGroup("g")
+
+	r := chi.NewRouter()
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoContext(r.Context(), "loaded")
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	got := decodeRecord(t, &buf)
+	if got["route"] != "/users/{id}" || got["component"] != "test" {
+		t.Errorf("record = %v, want route /users/{id} and component test", got)
+	}
+}
+
+// decodeRecord returns the string attributes of the logged record. Groups
+// are flattened so attributes added under WithGroup are found too.
+func decodeRecord(t *testing.T, buf *bytes.Buffer) map[string]string {
+	t.Helper()
+	var raw map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		t.Fatalf("decode %q: %v", buf, err)
+	}
+	got := make(map[string]string)
+	var flatten func(map[string]interface{})
+	flatten = func(m map[string]interface{}) {
+		for k, v := range m {
+			switch v := v.(type) {
+			case string:
+				got[k] = v
+			case map[string]interface{}:
+				flatten(v)
+			}
+		}
+	}
+	flatten(raw)
+	return got
+}
@@ -0,0 +1,710 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf16"
+)
+
+// WaveformPeaks is the number of peak values generated per audio file
+const WaveformPeaks = 100
+
+// AudioInfo is the metadata extracted from an audio file
+type AudioInfo struct {
+	Codec           string
+	DurationSeconds float64
+	SampleRate      int
+	Channels        int
+	BitrateKbps     int
+	// Tags uses normalised keys: title, artist, album, date, genre, track
+	Tags map[string]string
+	// Waveform holds peak amplitudes in [0, 1]; only set when samples could
+	// be read without a decoder (uncompressed WAV)
+	Waveform []float32
+}
+
+// probeAudio parses the headers and tags of an MP3, Ogg, WAV or FLAC file
+func probeAudio(data []byte, format string) (*AudioInfo, error) {
+	var info *AudioInfo
+	var err error
+	switch format {
+	case "mp3":
+		info, err = probeMP3(data)
+	case "ogg":
+		info, err = probeOgg(data)
+	case "wav":
+		info, err = probeWAV(data)
+	case "flac":
+		info, err = probeFLAC(data)
+	default:
+		return nil, fmt.Errorf("%w: %s", errInvalidContainer, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.DurationSeconds > 0 && info.BitrateKbps == 0 {
+		info.BitrateKbps = int(float64(len(data)) * 8 / info.DurationSeconds / 1000)
+	}
+	return info, nil
+}
+
+// Tag normalisation
+
+var id3TagKeys = map[string]string{
+	"TIT2": "title", "TT2": "title",
+	"TPE1": "artist", "TP1": "artist",
+	"TALB": "album", "TAL": "album",
+	"TYER": "date", "TYE": "date", "TDRC": "date",
+	"TCON": "genre", "TCO": "genre",
+	"TRCK": "track", "TRK": "track",
+}
+
+var vorbisTagKeys = map[string]string{
+	"TITLE":       "title",
+	"ARTIST":      "artist",
+	"ALBUM":       "album",
+	"DATE":        "date",
+	"GENRE":       "genre",
+	"TRACKNUMBER": "track",
+}
+
+var riffTagKeys = map[string]string{
+	"INAM": "title",
+	"IART": "artist",
+	"IPRD": "album",
+	"ICRD": "date",
+	"IGNR": "genre",
+	"ITRK": "track",
+}
+
+func setTag(tags map[string]string, key, value string) {
+	value = strings.TrimSpace(strings.TrimRight(value, "\x00"))
+	if key == "" || value == "" {
+		return
+	}
+	if _, exists := tags[key]; !exists {
+		tags[key] = value
+	}
+}
+
+// parseVorbisComment parses the comment block shared by Vorbis, Opus and
+// FLAC: a vendor string followed by KEY=value pairs, all little-endian
+// length-prefixed
+func parseVorbisComment(p []byte, tags map[string]string) {
+	if len(p) < 4 {
+		return
+	}
+	vendorLen := int(binary.LittleEndian.Uint32(p))
+	pos := 4 + vendorLen
+	if pos+4 > len(p) {
+		return
+	}
+	count := int(binary.LittleEndian.Uint32(p[pos:]))
+	pos += 4
+	for i := 0; i < count && pos+4 <= len(p); i++ {
+		n := int(binary.LittleEndian.Uint32(p[pos:]))
+		pos += 4
+		if n < 0 || pos+n > len(p) {
+			return
+		}
+		if key, value, ok := strings.Cut(string(p[pos:pos+n]), "="); ok {
+			setTag(tags, vorbisTagKeys[strings.ToUpper(key)], value)
+		}
+		pos += n
+	}
+}
+
+// Waveform
+
+// computePeaks splits frames into buckets and returns the largest absolute
+// sample of each, with sample returning values normalised to [-1, 1]
+func computePeaks(frames int, sample func(frame int) float64, buckets int) []float32 {
+	if frames <= 0 {
+		return nil
+	}
+	buckets = min(buckets, frames)
+	peaks := make([]float32, buckets)
+	for i := 0; i < frames; i++ {
+		b := i * buckets / frames
+		if v := float32(math.Abs(sample(i))); v > peaks[b] {
+			peaks[b] = min(v, 1)
+		}
+	}
+	return peaks
+}
+
+// peaksFromPCM16 computes peaks from mono signed 16-bit samples
+func peaksFromPCM16(samples []int16, buckets int) []float32 {
+	return computePeaks(len(samples), func(i int) float64 {
+		return float64(samples[i]) / 32768
+	}, buckets)
+}
+
+// WAV (RIFF)
+
+func probeWAV(data []byte) (*AudioInfo, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("%w: missing RIFF/WAVE header", errInvalidContainer)
+	}
+
+	info := &AudioInfo{Codec: "pcm", Tags: make(map[string]string)}
+	var audioFormat, blockAlign, bitsPerSample int
+	var byteRate int
+	var pcm []byte
+	haveFmt := false
+
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		start := pos + 8
+		if size > len(data)-start {
+			return nil, fmt.Errorf("%w: chunk %q runs past the end of the file", errInvalidContainer, id)
+		}
+		chunk := data[start : start+size]
+
+		switch id {
+		case "fmt ":
+			if len(chunk) < 16 {
+				return nil, fmt.Errorf("%w: short fmt chunk", errInvalidContainer)
+			}
+			audioFormat = int(binary.LittleEndian.Uint16(chunk[0:]))
+			info.Channels = int(binary.LittleEndian.Uint16(chunk[2:]))
+			info.SampleRate = int(binary.LittleEndian.Uint32(chunk[4:]))
+			byteRate = int(binary.LittleEndian.Uint32(chunk[8:]))
+			blockAlign = int(binary.LittleEndian.Uint16(chunk[12:]))
+			bitsPerSample = int(binary.LittleEndian.Uint16(chunk[14:]))
+			// WAVE_FORMAT_EXTENSIBLE stores the real format in the sub-format GUID
+			if audioFormat == 0xFFFE && len(chunk) >= 26 {
+				audioFormat = int(binary.LittleEndian.Uint16(chunk[24:]))
+			}
+			haveFmt = true
+		case "data":
+			pcm = chunk
+		case "LIST":
+			if len(chunk) >= 4 && string(chunk[:4]) == "INFO" {
+				parseRIFFInfo(chunk[4:], info.Tags)
+			}
+		}
+
+		// Chunks are padded to an even size
+		pos = start + size + size%2
+	}
+
+	if !haveFmt || pcm == nil {
+		return nil, fmt.Errorf("%w: missing fmt or data chunk", errInvalidContainer)
+	}
+	if byteRate > 0 {
+		info.DurationSeconds = float64(len(pcm)) / float64(byteRate)
+		info.BitrateKbps = byteRate * 8 / 1000
+	}
+	if audioFormat == 3 {
+		info.Codec = "pcm_float"
+	}
+
+	info.Waveform = wavPeaks(pcm, audioFormat, info.Channels, blockAlign, bitsPerSample)
+	return info, nil
+}
+
+func parseRIFFInfo(p []byte, tags map[string]string) {
+	for pos := 0; pos+8 <= len(p); {
+		id := string(p[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(p[pos+4:]))
+		start := pos + 8
+		end := min(start+size, len(p))
+		setTag(tags, riffTagKeys[id], string(p[start:end]))
+		pos = start + size + size%2
+	}
+}
+
+// wavPeaks decodes integer or float PCM and returns waveform peaks, taking
+// the loudest channel of each frame
+func wavPeaks(pcm []byte, audioFormat, channels, blockAlign, bits int) []float32 {
+	if channels <= 0 || blockAlign <= 0 || (audioFormat != 1 && audioFormat != 3) {
+		return nil
+	}
+	bytesPerSample := bits / 8
+	if bytesPerSample == 0 || bytesPerSample*channels > blockAlign {
+		return nil
+	}
+
+	decode := func(b []byte) float64 {
+		switch {
+		case audioFormat == 3 && bytesPerSample == 4:
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		case audioFormat == 3 && bytesPerSample == 8:
+			return math.Float64frombits(binary.LittleEndian.Uint64(b))
+		case bytesPerSample == 1: // 8-bit PCM is unsigned
+			return (float64(b[0]) - 128) / 128
+		case bytesPerSample == 2:
+			return float64(int16(binary.LittleEndian.Uint16(b))) / 32768
+		case bytesPerSample == 3:
+			v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+			return float64(v) / 8388608
+		case bytesPerSample == 4:
+			return float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648
+		default:
+			return 0
+		}
+	}
+
+	frames := len(pcm) / blockAlign
+	return computePeaks(frames, func(i int) float64 {
+		frame := pcm[i*blockAlign:]
+		peak := 0.0
+		for c := 0; c < channels; c++ {
+			if v := math.Abs(decode(frame[c*bytesPerSample:])); v > peak {
+				peak = v
+			}
+		}
+		return peak
+	}, WaveformPeaks)
+}
+
+// FLAC
+
+func probeFLAC(data []byte) (*AudioInfo, error) {
+	if len(data) < 4 || string(data[:4]) != "fLaC" {
+		return nil, fmt.Errorf("%w: missing fLaC marker", errInvalidContainer)
+	}
+
+	info := &AudioInfo{Codec: "flac", Tags: make(map[string]string)}
+	haveStreamInfo, last := false, false
+
+	for pos := 4; !last; {
+		if pos+4 > len(data) {
+			return nil, fmt.Errorf("%w: truncated metadata block", errInvalidContainer)
+		}
+		header := data[pos]
+		blockType := header & 0x7F
+		length := int(data[pos+1])<<16 | int(data[pos+2])<<8 | int(data[pos+3])
+		start := pos + 4
+		if start+length > len(data) {
+			return nil, fmt.Errorf("%w: truncated metadata block", errInvalidContainer)
+		}
+		block := data[start : start+length]
+
+		switch blockType {
+		case 0: // STREAMINFO
+			if len(block) < 18 {
+				return nil, fmt.Errorf("%w: short STREAMINFO", errInvalidContainer)
+			}
+			v := binary.BigEndian.Uint64(block[10:18])
+			info.SampleRate = int(v >> 44)
+			info.Channels = int((v>>41)&0x7) + 1
+			totalSamples := v & 0xFFFFFFFFF
+			if info.SampleRate > 0 {
+				info.DurationSeconds = float64(totalSamples) / float64(info.SampleRate)
+			}
+			haveStreamInfo = true
+		case 4: // VORBIS_COMMENT
+			parseVorbisComment(block, info.Tags)
+		}
+
+		pos = start + length
+		last = header&0x80 != 0
+	}
+
+	if !haveStreamInfo {
+		return nil, fmt.Errorf("%w: missing STREAMINFO", errInvalidContainer)
+	}
+	return info, nil
+}
+
+// Ogg (Vorbis and Opus)
+
+// maxOggHeaderPacket bounds the size of the identification and comment
+// packets we reassemble
+const maxOggHeaderPacket = 1024 * 1024
+
+type oggPage struct {
+	serial  uint32
+	granule int64
+	body    []byte
+	lacing  []byte
+	next    int
+}
+
+func readOggPage(data []byte, pos int) (oggPage, bool) {
+	if pos+27 > len(data) || string(data[pos:pos+4]) != "OggS" {
+		return oggPage{}, false
+	}
+	nseg := int(data[pos+26])
+	bodyStart := pos + 27 + nseg
+	if bodyStart > len(data) {
+		return oggPage{}, false
+	}
+	lacing := data[pos+27 : bodyStart]
+	bodyLen := 0
+	for _, l := range lacing {
+		bodyLen += int(l)
+	}
+	if bodyStart+bodyLen > len(data) {
+		return oggPage{}, false
+	}
+	return oggPage{
+		serial:  binary.LittleEndian.Uint32(data[pos+14:]),
+		granule: int64(binary.LittleEndian.Uint64(data[pos+6:])),
+		body:    data[bodyStart : bodyStart+bodyLen],
+		lacing:  lacing,
+		next:    bodyStart + bodyLen,
+	}, true
+}
+
+// oggHeaderPackets reassembles the first n packets of the first logical stream
+func oggHeaderPackets(data []byte, n int) ([][]byte, uint32, error) {
+	first, ok := readOggPage(data, 0)
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: missing OggS page", errInvalidContainer)
+	}
+	serial := first.serial
+
+	var packets [][]byte
+	var current []byte
+	for pos := 0; len(packets) < n; {
+		page, ok := readOggPage(data, pos)
+		if !ok {
+			break
+		}
+		pos = page.next
+		if page.serial != serial {
+			continue
+		}
+
+		offset := 0
+		for _, l := range page.lacing {
+			current = append(current, page.body[offset:offset+int(l)]...)
+			offset += int(l)
+			if len(current) > maxOggHeaderPacket {
+				return nil, 0, fmt.Errorf("%w: header packet too large", errInvalidContainer)
+			}
+			// A lacing value below 255 terminates the packet
+			if l < 255 {
+				packets = append(packets, current)
+				current = nil
+				if len(packets) == n {
+					break
+				}
+			}
+		}
+	}
+
+	if len(packets) < n {
+		return nil, 0, fmt.Errorf("%w: truncated Ogg header packets", errInvalidContainer)
+	}
+	return packets, serial, nil
+}
+
+// lastOggGranule returns the granule position of the last page of a stream
+func lastOggGranule(data []byte, serial uint32) int64 {
+	end := len(data)
+	for end > 0 {
+		i := bytes.LastIndex(data[:end], []byte("OggS"))
+		if i < 0 {
+			break
+		}
+		if page, ok := readOggPage(data, i); ok && page.serial == serial && page.granule >= 0 {
+			return page.granule
+		}
+		end = i
+	}
+	return 0
+}
+
+func probeOgg(data []byte) (*AudioInfo, error) {
+	packets, serial, err := oggHeaderPackets(data, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &AudioInfo{Tags: make(map[string]string)}
+	id := packets[0]
+	granule := lastOggGranule(data, serial)
+
+	switch {
+	case len(id) >= 16 && id[0] == 1 && string(id[1:7]) == "vorbis":
+		info.Codec = "vorbis"
+		info.Channels = int(id[11])
+		info.SampleRate = int(binary.LittleEndian.Uint32(id[12:]))
+		if len(id) >= 24 {
+			if nominal := int32(binary.LittleEndian.Uint32(id[20:])); nominal > 0 {
+				info.BitrateKbps = int(nominal) / 1000
+			}
+		}
+		if info.SampleRate > 0 {
+			info.DurationSeconds = float64(granule) / float64(info.SampleRate)
+		}
+		if len(packets) > 1 && len(packets[1]) > 7 && string(packets[1][:7]) == "\x03vorbis" {
+			parseVorbisComment(packets[1][7:], info.Tags)
+		}
+	case len(id) >= 19 && string(id[:8]) == "OpusHead":
+		info.Codec = "opus"
+		info.Channels = int(id[9])
+		preSkip := int64(binary.LittleEndian.Uint16(id[10:]))
+		info.SampleRate = int(binary.LittleEndian.Uint32(id[12:]))
+		// Opus granule positions always count 48 kHz samples
+		if granule > preSkip {
+			info.DurationSeconds = float64(granule-preSkip) / 48000
+		}
+		if len(packets) > 1 && len(packets[1]) > 8 && string(packets[1][:8]) == "OpusTags" {
+			parseVorbisComment(packets[1][8:], info.Tags)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported Ogg codec", errInvalidContainer)
+	}
+	return info, nil
+}
+
+// MP3 (MPEG audio with ID3 tags)
+
+type mp3Frame struct {
+	length      int
+	samples     int
+	sampleRate  int
+	bitrateKbps int
+	channels    int
+	layer       int
+}
+
+var (
+	mp3Bitrates = map[[2]int][]int{
+		{1, 1}: {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+		{1, 2}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{1, 3}: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		{2, 1}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		{2, 2}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{2, 3}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	}
+	mp3SampleRates = map[int][]int{
+		1:  {44100, 48000, 32000},
+		2:  {22050, 24000, 16000},
+		25: {11025, 12000, 8000},
+	}
+)
+
+// parseMP3FrameHeader decodes a 4-byte MPEG audio frame header
+func parseMP3FrameHeader(h []byte) (mp3Frame, bool) {
+	if len(h) < 4 || h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+
+	var version int
+	switch (h[1] >> 3) & 0x3 {
+	case 0:
+		version = 25
+	case 2:
+		version = 2
+	case 3:
+		version = 1
+	default:
+		return mp3Frame{}, false
+	}
+	layer := 4 - int((h[1]>>1)&0x3)
+	if layer == 4 {
+		return mp3Frame{}, false
+	}
+
+	bitrateIdx := int(h[2] >> 4)
+	rateIdx := int((h[2] >> 2) & 0x3)
+	if bitrateIdx == 0 || bitrateIdx == 15 || rateIdx == 3 {
+		return mp3Frame{}, false
+	}
+	padding := int((h[2] >> 1) & 0x1)
+
+	tableVersion := min(version, 2)
+	bitrate := mp3Bitrates[[2]int{tableVersion, layer}][bitrateIdx]
+	sampleRate := mp3SampleRates[version][rateIdx]
+
+	f := mp3Frame{
+		sampleRate:  sampleRate,
+		bitrateKbps: bitrate,
+		channels:    2,
+		layer:       layer,
+	}
+	if h[3]>>6 == 3 {
+		f.channels = 1
+	}
+
+	switch {
+	case layer == 1:
+		f.samples = 384
+		f.length = (12*bitrate*1000/sampleRate + padding) * 4
+	case layer == 3 && version != 1:
+		f.samples = 576
+		f.length = 72*bitrate*1000/sampleRate + padding
+	default:
+		f.samples = 1152
+		f.length = 144*bitrate*1000/sampleRate + padding
+	}
+	return f, f.length > 4
+}
+
+func probeMP3(data []byte) (*AudioInfo, error) {
+	info := &AudioInfo{Codec: "mp3", Tags: make(map[string]string)}
+
+	start := 0
+	if len(data) >= 10 && string(data[:3]) == "ID3" {
+		size := syncsafe(data[6:10])
+		start = 10 + size
+		if data[5]&0x10 != 0 { // footer present
+			start += 10
+		}
+		parseID3v2(data[:min(start, len(data))], info.Tags)
+	}
+
+	end := len(data)
+	if end-start >= 128 && string(data[end-128:end-125]) == "TAG" {
+		parseID3v1(data[end-128:], info.Tags)
+		end -= 128
+	}
+
+	// Walk the frames, resynchronising over junk between them. After junk a
+	// header only counts when another one follows it, so stray sync bits in
+	// junk or cover art are not taken for frames
+	var totalSamples, frames, bitrateSum int
+	synced := false
+	for pos := start; pos+4 <= end; {
+		f, ok := parseMP3FrameHeader(data[pos:end])
+		ok = ok && pos+f.length <= end
+		if ok && !synced {
+			ok = mp3FrameFollows(data[:end], pos+f.length, f)
+		}
+		if !ok {
+			pos++
+			synced = false
+			continue
+		}
+		if frames == 0 {
+			info.SampleRate = f.sampleRate
+			info.Channels = f.channels
+			if f.layer != 3 {
+				info.Codec = fmt.Sprintf("mp%d", f.layer)
+			}
+		}
+		totalSamples += f.samples
+		bitrateSum += f.bitrateKbps
+		frames++
+		synced = true
+		pos += f.length
+	}
+
+	if frames == 0 {
+		return nil, fmt.Errorf("%w: no MPEG audio frames", errInvalidContainer)
+	}
+	info.DurationSeconds = float64(totalSamples) / float64(info.SampleRate)
+	info.BitrateKbps = bitrateSum / frames
+	return info, nil
+}
+
+// mp3FrameFollows reports whether pos is the end of the data or the start
+// of a frame with the same layer and sample rate as f
+func mp3FrameFollows(data []byte, pos int, f mp3Frame) bool {
+	if pos == len(data) {
+		return true
+	}
+	next, ok := parseMP3FrameHeader(data[pos:])
+	return ok && next.layer == f.layer && next.sampleRate == f.sampleRate
+}
+
+// syncsafe decodes an ID3v2 syncsafe integer: 7 bits per byte, so the
+// value never contains a false frame sync
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
+
+// parseID3v2 reads text frames from an ID3v2.2, 2.3 or 2.4 tag
+func parseID3v2(tag []byte, tags map[string]string) {
+	major := tag[3]
+	pos := 10
+	if tag[5]&0x40 != 0 && len(tag) >= 14 { // extended header
+		if major == 4 {
+			pos += syncsafe(tag[10:14])
+		} else {
+			pos += 4 + int(binary.BigEndian.Uint32(tag[10:14]))
+		}
+	}
+
+	idLen, headerLen := 4, 10
+	if major == 2 {
+		idLen, headerLen = 3, 6
+	}
+
+	for pos+headerLen <= len(tag) {
+		id := string(tag[pos : pos+idLen])
+		if id[0] == 0 { // padding
+			return
+		}
+
+		var size int
+		switch major {
+		case 2:
+			size = int(tag[pos+3])<<16 | int(tag[pos+4])<<8 | int(tag[pos+5])
+		case 4:
+			size = syncsafe(tag[pos+4 : pos+8])
+		default:
+			size = int(binary.BigEndian.Uint32(tag[pos+4 : pos+8]))
+		}
+
+		start := pos + headerLen
+		if size <= 0 || start+size > len(tag) {
+			return
+		}
+		if key, ok := id3TagKeys[id]; ok {
+			setTag(tags, key, decodeID3Text(tag[start:start+size]))
+		}
+		pos = start + size
+	}
+}
+
+// decodeID3Text decodes an ID3 text frame body; the first byte selects the
+// encoding
+func decodeID3Text(p []byte) string {
+	if len(p) == 0 {
+		return ""
+	}
+	enc, text := p[0], p[1:]
+	switch enc {
+	case 1, 2: // UTF-16 with BOM, UTF-16BE
+		bigEndian := enc == 2
+		if len(text) >= 2 {
+			if text[0] == 0xFF && text[1] == 0xFE {
+				bigEndian, text = false, text[2:]
+			} else if text[0] == 0xFE && text[1] == 0xFF {
+				bigEndian, text = true, text[2:]
+			}
+		}
+		units := make([]uint16, 0, len(text)/2)
+		for i := 0; i+1 < len(text); i += 2 {
+			if bigEndian {
+				units = append(units, binary.BigEndian.Uint16(text[i:]))
+			} else {
+				units = append(units, binary.LittleEndian.Uint16(text[i:]))
+			}
+		}
+		return string(utf16.Decode(units))
+	case 3: // UTF-8
+		return string(text)
+	default: // ISO-8859-1
+		return latin1(text)
+	}
+}
+
+func parseID3v1(tag []byte, tags map[string]string) {
+	setTag(tags, "title", latin1(tag[3:33]))
+	setTag(tags, "artist", latin1(tag[33:63]))
+	setTag(tags, "album", latin1(tag[63:93]))
+	setTag(tags, "date", latin1(tag[93:97]))
+}
+
+func latin1(b []byte) string {
+	runes := make([]rune, 0, len(b))
+	for _, c := range b {
+		if c == 0 {
+			break
+		}
+		runes = append(runes, rune(c))
+	}
+	return string(runes)
+}
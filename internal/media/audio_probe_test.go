@@ -0,0 +1,433 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"maps"
+	"math"
+	"testing"
+)
+
+// WAV
+
+func riffChunk(id string, payload []byte) []byte {
+	out := append([]byte(id), binary.LittleEndian.AppendUint32(nil, uint32(len(payload)))...)
+	out = append(out, payload...)
+	if len(payload)%2 == 1 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+func wavFile(chunks ...[]byte) []byte {
+	body := append([]byte("WAVE"), bytes.Join(chunks, nil)...)
+	return append(append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...), body...)
+}
+
+func wavFmt(format, channels, rate, bits int) []byte {
+	blockAlign := channels * bits / 8
+	p := binary.LittleEndian.AppendUint16(nil, uint16(format))
+	p = binary.LittleEndian.AppendUint16(p, uint16(channels))
+	p = binary.LittleEndian.AppendUint32(p, uint32(rate))
+	p = binary.LittleEndian.AppendUint32(p, uint32(rate*blockAlign))
+	p = binary.LittleEndian.AppendUint16(p, uint16(blockAlign))
+	p = binary.LittleEndian.AppendUint16(p, uint16(bits))
+	return p
+}
+
+// wavFmtExtensible wraps the real format in a WAVE_FORMAT_EXTENSIBLE chunk
+func wavFmtExtensible(format, channels, rate, bits int) []byte {
+	p := wavFmt(0xFFFE, channels, rate, bits)
+	p = binary.LittleEndian.AppendUint16(p, 22)
+	p = binary.LittleEndian.AppendUint16(p, uint16(bits))
+	p = binary.LittleEndian.AppendUint32(p, 0)
+	p = binary.LittleEndian.AppendUint16(p, uint16(format))
+	return append(p, make([]byte, 14)...)
+}
+
+func riffInfo(pairs ...string) []byte {
+	p := []byte("INFO")
+	for i := 0; i+1 < len(pairs); i += 2 {
+		p = append(p, riffChunk(pairs[i], []byte(pairs[i+1]+"\x00"))...)
+	}
+	return riffChunk("LIST", p)
+}
+
+// pcm16 returns n mono 16-bit samples of the given value
+func pcm16(n int, value int16) []byte {
+	var p []byte
+	for range n {
+		p = binary.LittleEndian.AppendUint16(p, uint16(value))
+	}
+	return p
+}
+
+func validWAV() []byte {
+	return wavFile(
+		riffChunk("fmt ", wavFmt(1, 1, 8000, 16)),
+		riffInfo("INAM", "Take One", "IART", "The Band"),
+		riffChunk("data", pcm16(8000, 16384)),
+	)
+}
+
+// FLAC
+
+func flacBlock(blockType byte, last bool, payload []byte) []byte {
+	if last {
+		blockType |= 0x80
+	}
+	n := len(payload)
+	return append([]byte{blockType, byte(n >> 16), byte(n >> 8), byte(n)}, payload...)
+}
+
+func flacStreamInfo(rate, channels int, samples uint64) []byte {
+	p := make([]byte, 34)
+	v := uint64(rate)<<44 | uint64(channels-1)<<41 | 15<<36 | samples
+	binary.BigEndian.PutUint64(p[10:], v)
+	return p
+}
+
+func vorbisComment(pairs ...string) []byte {
+	p := binary.LittleEndian.AppendUint32(nil, 4)
+	p = append(p, "test"...)
+	p = binary.LittleEndian.AppendUint32(p, uint32(len(pairs)))
+	for _, kv := range pairs {
+		p = binary.LittleEndian.AppendUint32(p, uint32(len(kv)))
+		p = append(p, kv...)
+	}
+	return p
+}
+
+func validFLAC() []byte {
+	return bytes.Join([][]byte{
+		[]byte("fLaC"),
+		flacBlock(0, false, flacStreamInfo(44100, 2, 441000)),
+		flacBlock(4, true, vorbisComment("TITLE=Take One", "tracknumber=3")),
+	}, nil)
+}
+
+// Ogg
+
+// buildOggPage builds a page holding whole packets; the CRC is not checked
+func buildOggPage(serial uint32, granule int64, packets ...[]byte) []byte {
+	var lacing, body []byte
+	for _, p := range packets {
+		n := len(p)
+		for ; n >= 255; n -= 255 {
+			lacing = append(lacing, 255)
+		}
+		lacing = append(lacing, byte(n))
+		body = append(body, p...)
+	}
+	h := append([]byte("OggS"), 0, 0)
+	h = binary.LittleEndian.AppendUint64(h, uint64(granule))
+	h = binary.LittleEndian.AppendUint32(h, serial)
+	h = append(h, make([]byte, 8)...) // sequence number and CRC
+	h = append(h, byte(len(lacing)))
+	return append(append(h, lacing...), body...)
+}
+
+func vorbisID(channels, rate, nominal int) []byte {
+	p := append([]byte("\x01vorbis"), 0, 0, 0, 0, byte(channels))
+	p = binary.LittleEndian.AppendUint32(p, uint32(rate))
+	p = binary.LittleEndian.AppendUint32(p, 0)
+	p = binary.LittleEndian.AppendUint32(p, uint32(nominal))
+	p = binary.LittleEndian.AppendUint32(p, 0)
+	return append(p, 0xB8, 1)
+}
+
+func opusHead(channels, preSkip, rate int) []byte {
+	p := append([]byte("OpusHead"), 1, byte(channels))
+	p = binary.LittleEndian.AppendUint16(p, uint16(preSkip))
+	p = binary.LittleEndian.AppendUint32(p, uint32(rate))
+	return append(p, 0, 0, 0)
+}
+
+// vorbisHeaders returns the identification and comment pages of a Vorbis
+// stream
+func vorbisHeaders(serial uint32) []byte {
+	comment := append([]byte("\x03vorbis"), vorbisComment("ARTIST=The Band", "Album=Live")...)
+	return append(buildOggPage(serial, 0, vorbisID(2, 44100, 128000)), buildOggPage(serial, 0, append(comment, 1))...)
+}
+
+// MP3
+
+// mp3Header128 is an MPEG-1 Layer III frame header: 128 kbit/s, 44.1 kHz,
+// stereo, so each frame is 417 bytes
+var mp3Header128 = []byte{0xFF, 0xFB, 0x90, 0x00}
+
+// mp3Header64 is an MPEG-2 Layer III frame header: 64 kbit/s, 22.05 kHz,
+// mono, so each frame is 208 bytes
+var mp3Header64 = []byte{0xFF, 0xF3, 0x80, 0xC0}
+
+func mp3Frames(header []byte, n int) []byte {
+	f, ok := parseMP3FrameHeader(header)
+	if !ok {
+		panic("invalid test frame header")
+	}
+	frame := append(append([]byte(nil), header...), make([]byte, f.length-len(header))...)
+	return bytes.Repeat(frame, n)
+}
+
+func syncsafeBytes(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}
+}
+
+// id3Frame builds an ISO-8859-1 text frame for the given ID3v2 version
+func id3Frame(major byte, id, text string) []byte {
+	body := append([]byte{0}, text...)
+	switch major {
+	case 2:
+		n := len(body)
+		return append(append([]byte(id), byte(n>>16), byte(n>>8), byte(n)), body...)
+	case 4:
+		return append(append(append([]byte(id), syncsafeBytes(len(body))...), 0, 0), body...)
+	default:
+		return append(append(binary.BigEndian.AppendUint32([]byte(id), uint32(len(body))), 0, 0), body...)
+	}
+}
+
+// id3v2 builds a tag of the given version, padded to size bytes after the
+// header
+func id3v2(major byte, size int, frames ...[]byte) []byte {
+	body := bytes.Join(frames, nil)
+	body = append(body, make([]byte, size-len(body))...)
+	return append(append([]byte{'I', 'D', '3', major, 0, 0}, syncsafeBytes(size)...), body...)
+}
+
+func id3v1(title, artist string) []byte {
+	tag := make([]byte, 128)
+	copy(tag, "TAG")
+	copy(tag[3:], title)
+	copy(tag[33:], artist)
+	return tag
+}
+
+func checkAudioInfo(t *testing.T, got *AudioInfo, want AudioInfo) {
+	t.Helper()
+	if got.Codec != want.Codec || got.SampleRate != want.SampleRate || got.Channels != want.Channels {
+		t.Errorf("codec, rate, channels = %s, %d, %d, want %s, %d, %d",
+			got.Codec, got.SampleRate, got.Channels, want.Codec, want.SampleRate, want.Channels)
+	}
+	if math.Abs(got.DurationSeconds-want.DurationSeconds) > 1e-6 {
+		t.Errorf("DurationSeconds = %v, want %v", got.DurationSeconds, want.DurationSeconds)
+	}
+	if want.BitrateKbps != 0 && got.BitrateKbps != want.BitrateKbps {
+		t.Errorf("BitrateKbps = %d, want %d", got.BitrateKbps, want.BitrateKbps)
+	}
+	if want.Tags == nil {
+		want.Tags = map[string]string{}
+	}
+	if !maps.Equal(got.Tags, want.Tags) {
+		t.Errorf("Tags = %v, want %v", got.Tags, want.Tags)
+	}
+}
+
+func TestProbeAudio(t *testing.T) {
+	floatPCM := binary.LittleEndian.AppendUint32(nil, math.Float32bits(-0.25))
+	floatPCM = binary.LittleEndian.AppendUint32(floatPCM, math.Float32bits(0.75))
+
+	tests := []struct {
+		name   string
+		format string
+		file   []byte
+		want   AudioInfo
+	}{
+		{
+			name: "wav with INFO tags", format: "wav", file: validWAV(),
+			want: AudioInfo{Codec: "pcm", DurationSeconds: 1, SampleRate: 8000, Channels: 1, BitrateKbps: 128,
+				Tags: map[string]string{"title": "Take One", "artist": "The Band"}},
+		},
+		{
+			name: "extensible float wav", format: "wav",
+			file: wavFile(riffChunk("fmt ", wavFmtExtensible(3, 2, 48000, 32)), riffChunk("data", bytes.Repeat(floatPCM, 4800))),
+			want: AudioInfo{Codec: "pcm_float", DurationSeconds: 0.1, SampleRate: 48000, Channels: 2, BitrateKbps: 3072},
+		},
+		{
+			name: "flac", format: "flac", file: validFLAC(),
+			want: AudioInfo{Codec: "flac", DurationSeconds: 10, SampleRate: 44100, Channels: 2,
+				Tags: map[string]string{"title": "Take One", "track": "3"}},
+		},
+		{
+			name: "flac with padding before STREAMINFO", format: "flac",
+			file: bytes.Join([][]byte{[]byte("fLaC"), flacBlock(1, false, make([]byte, 10)), flacBlock(0, true, flacStreamInfo(48000, 1, 24000))}, nil),
+			want: AudioInfo{Codec: "flac", DurationSeconds: 0.5, SampleRate: 48000, Channels: 1},
+		},
+		{
+			name: "ogg vorbis", format: "ogg",
+			file: append(vorbisHeaders(7), buildOggPage(7, 441000, make([]byte, 300))...),
+			want: AudioInfo{Codec: "vorbis", DurationSeconds: 10, SampleRate: 44100, Channels: 2, BitrateKbps: 128,
+				Tags: map[string]string{"artist": "The Band", "album": "Live"}},
+		},
+		{
+			name: "ogg opus skips other streams", format: "ogg",
+			file: bytes.Join([][]byte{
+				buildOggPage(1, 0, opusHead(1, 312, 44100)),
+				buildOggPage(2, 0, []byte("other stream")),
+				buildOggPage(1, 0, append([]byte("OpusTags"), vorbisComment("TITLE=Opus")...)),
+				buildOggPage(1, 5*48000+312, make([]byte, 100)),
+				buildOggPage(2, 1<<40, make([]byte, 100)),
+			}, nil),
+			want: AudioInfo{Codec: "opus", DurationSeconds: 5, SampleRate: 44100, Channels: 1,
+				Tags: map[string]string{"title": "Opus"}},
+		},
+		{
+			name: "ogg packet spanning lacing values", format: "ogg",
+			file: append(buildOggPage(3, 0, vorbisID(1, 22050, 0)), buildOggPage(3, 22050,
+				append(append([]byte("\x03vorbis"), vorbisComment("TITLE="+string(bytes.Repeat([]byte("a"), 600)))...), 1))...),
+			want: AudioInfo{Codec: "vorbis", DurationSeconds: 1, SampleRate: 22050, Channels: 1,
+				Tags: map[string]string{"title": string(bytes.Repeat([]byte("a"), 600))}},
+		},
+		{
+			name: "mp3 with ID3v2.3 tag", format: "mp3",
+			file: append(id3v2(3, 100, id3Frame(3, "TIT2", "Take One"), id3Frame(3, "TPE1", "The Band")), mp3Frames(mp3Header128, 10)...),
+			want: AudioInfo{Codec: "mp3", DurationSeconds: 10 * 1152.0 / 44100, SampleRate: 44100, Channels: 2, BitrateKbps: 128,
+				Tags: map[string]string{"title": "Take One", "artist": "The Band"}},
+		},
+		{
+			// 257 is 0x0201 as a syncsafe integer; read as plain big-endian
+			// it would be 513 and skip into the first frame
+			name: "mp3 with ID3v2.4 syncsafe sizes", format: "mp3",
+			file: append(id3v2(4, 257, id3Frame(4, "TALB", "Live"), id3Frame(4, "TDRC", "2024")), mp3Frames(mp3Header128, 3)...),
+			want: AudioInfo{Codec: "mp3", DurationSeconds: 3 * 1152.0 / 44100, SampleRate: 44100, Channels: 2, BitrateKbps: 128,
+				Tags: map[string]string{"album": "Live", "date": "2024"}},
+		},
+		{
+			name: "mp3 with ID3v2.2 and ID3v1 tags", format: "mp3",
+			file: bytes.Join([][]byte{id3v2(2, 20, id3Frame(2, "TT2", "Short")), mp3Frames(mp3Header64, 4), id3v1("Ignored title", "V1 Artist")}, nil),
+			want: AudioInfo{Codec: "mp3", DurationSeconds: 4 * 576.0 / 22050, SampleRate: 22050, Channels: 1, BitrateKbps: 64,
+				Tags: map[string]string{"title": "Short", "artist": "V1 Artist"}},
+		},
+		{
+			// Taken for a frame, the sync word in the junk would set the
+			// sample rate and channels and swallow the first real frame
+			name: "mp3 ignores a false frame sync in junk", format: "mp3",
+			file: bytes.Join([][]byte{mp3Header64, make([]byte, 20), mp3Frames(mp3Header128, 3)}, nil),
+			want: AudioInfo{Codec: "mp3", DurationSeconds: 3 * 1152.0 / 44100, SampleRate: 44100, Channels: 2, BitrateKbps: 128},
+		},
+		{
+			name: "mp3 resyncs after junk between frames", format: "mp3",
+			file: bytes.Join([][]byte{mp3Frames(mp3Header128, 2), []byte("junk"), mp3Frames(mp3Header128, 2)}, nil),
+			want: AudioInfo{Codec: "mp3", DurationSeconds: 4 * 1152.0 / 44100, SampleRate: 44100, Channels: 2, BitrateKbps: 128},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := probeAudio(tt.file, tt.format)
+			if err != nil {
+				t.Fatalf("probeAudio() error = %v", err)
+			}
+			checkAudioInfo(t, got, tt.want)
+		})
+	}
+}
+
+func TestProbeAudioWaveform(t *testing.T) {
+	info, err := probeAudio(validWAV(), "wav")
+	if err != nil {
+		t.Fatalf("probeAudio() error = %v", err)
+	}
+	if len(info.Waveform) != WaveformPeaks {
+		t.Fatalf("len(Waveform) = %d, want %d", len(info.Waveform), WaveformPeaks)
+	}
+	for i, peak := range info.Waveform {
+		if peak != 0.5 {
+			t.Fatalf("Waveform[%d] = %v, want 0.5", i, peak)
+		}
+	}
+}
+
+func TestProbeAudioInvalid(t *testing.T) {
+	wavData := riffChunk("data", pcm16(10, 1))
+	flacMarker := []byte("fLaC")
+
+	tests := []struct {
+		name   string
+		format string
+		file   []byte
+	}{
+		{name: "unknown format", format: "aac", file: validWAV()},
+
+		{name: "wav without RIFF header", format: "wav", file: []byte("RIFX\x00\x00\x00\x00WAVE")},
+		{name: "wav without fmt", format: "wav", file: wavFile(wavData)},
+		{name: "wav without data", format: "wav", file: wavFile(riffChunk("fmt ", wavFmt(1, 1, 8000, 16)))},
+		{name: "wav short fmt", format: "wav", file: wavFile(riffChunk("fmt ", make([]byte, 12)), wavData)},
+		{name: "wav data size past end", format: "wav", file: wavFile(riffChunk("fmt ", wavFmt(1, 1, 8000, 16)), []byte("data\xff\xff\xff\x7f\x00\x00"))},
+		{name: "wav chunk size near 4 GiB", format: "wav", file: wavFile(riffChunk("fmt ", wavFmt(1, 1, 8000, 16)), []byte("junk\xff\xff\xff\xff"), wavData)},
+
+		{name: "flac without marker", format: "flac", file: validFLAC()[4:]},
+		{name: "flac without STREAMINFO", format: "flac", file: append(flacMarker, flacBlock(4, true, vorbisComment())...)},
+		{name: "flac short STREAMINFO", format: "flac", file: append(flacMarker, flacBlock(0, true, make([]byte, 17))...)},
+		{name: "flac block past end", format: "flac", file: append(flacMarker, 0x80, 0xFF, 0xFF, 0xFF, 0)},
+		{name: "flac without last block", format: "flac", file: append(flacMarker, flacBlock(0, false, flacStreamInfo(44100, 2, 1))...)},
+
+		{name: "ogg without capture pattern", format: "ogg", file: []byte("OggX" + string(make([]byte, 40)))},
+		{name: "ogg with only one packet", format: "ogg", file: buildOggPage(1, 0, vorbisID(2, 44100, 0))},
+		{name: "ogg unknown codec", format: "ogg", file: buildOggPage(1, 0, []byte("\x80theora"), []byte("comment"))},
+		{name: "ogg page body past end", format: "ogg", file: vorbisHeaders(1)[:60]},
+
+		{name: "mp3 empty", format: "mp3", file: nil},
+		{name: "mp3 without frames", format: "mp3", file: []byte("not an mp3 at all, just text")},
+		{name: "mp3 lone frame sync", format: "mp3", file: append(append([]byte(nil), mp3Header128...), make([]byte, 1000)...)},
+		{name: "mp3 reserved sample rate", format: "mp3", file: bytes.Repeat([]byte{0xFF, 0xFB, 0x9C, 0x00}, 200)},
+		{name: "mp3 ID3 tag larger than file", format: "mp3", file: append(id3v2(3, 100), 0x7F)[:50]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := probeAudio(tt.file, tt.format); !errors.Is(err, errInvalidContainer) {
+				t.Errorf("probeAudio() error = %v, want errInvalidContainer", err)
+			}
+		})
+	}
+}
+
+// TestProbeAudioTruncated cuts files at every length up to the point where
+// a complete file could end, and each prefix must be rejected cleanly
+func TestProbeAudioTruncated(t *testing.T) {
+	tag := id3v2(3, 40, id3Frame(3, "TIT2", "Take One"))
+	tests := []struct {
+		format string
+		file   []byte
+		upTo   int
+	}{
+		{format: "wav", file: validWAV()},
+		{format: "flac", file: validFLAC()},
+		{format: "ogg", file: vorbisHeaders(1)},
+		// One complete frame is a valid MP3, so only shorter prefixes are cut
+		{format: "mp3", file: append(tag, mp3Frames(mp3Header128, 2)...), upTo: len(tag) + 417},
+	}
+	for _, tt := range tests {
+		upTo := tt.upTo
+		if upTo == 0 {
+			upTo = len(tt.file)
+		}
+		for n := 0; n < upTo; n++ {
+			if _, err := probeAudio(tt.file[:n], tt.format); !errors.Is(err, errInvalidContainer) {
+				t.Errorf("%s truncated to %d of %d bytes: error = %v, want errInvalidContainer", tt.format, n, len(tt.file), err)
+			}
+		}
+	}
+}
+
+func TestSyncsafe(t *testing.T) {
+	tests := []struct {
+		b    []byte
+		want int
+	}{
+		{b: []byte{0, 0, 0, 0}, want: 0},
+		{b: []byte{0, 0, 0, 0x7F}, want: 127},
+		{b: []byte{0, 0, 1, 0}, want: 128},
+		{b: []byte{0, 0, 2, 1}, want: 257},
+		{b: []byte{0x7F, 0x7F, 0x7F, 0x7F}, want: 1<<28 - 1},
+		// The top bit of each byte is not part of the value
+		{b: []byte{0x80, 0x80, 0x81, 0x80}, want: 128},
+	}
+	for _, tt := range tests {
+		if got := syncsafe(tt.b); got != tt.want {
+			t.Errorf("syncsafe(% x) = %d, want %d", tt.b, got, tt.want)
+		}
+	}
+}
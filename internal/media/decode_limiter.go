@@ -0,0 +1,189 @@
+package media
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"log/slog"
+	"sync"
+	"time"
+
+	"example.com/myapp/internal/config"
+	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/tracing"
+)
+
+// DecodeRetryAfter is suggested to clients whose upload was shed because
+// image processing is saturated
+const DecodeRetryAfter = 5 * time.Second
+
+// errDecodeQueueFull is returned when no more uploads may wait for memory
+var errDecodeQueueFull = errors.New("image decode queue is full")
+
+// errUndecodable wraps decoder errors for images that are corrupt or in a
+// variant the decoders do not support
+var errUndecodable = errors.New("unsupported or corrupt image")
+
+// decodeLimiter is a weighted semaphore bounding the estimated memory of
+// images being decoded at once. Waiters are served in arrival order so a
+// large image is not starved by a stream of small ones.
+type decodeLimiter struct {
+	mu       sync.Mutex
+	capacity int64
+	used     int64
+	maxQueue int
+	waiters  list.List // of *decodeWaiter
+}
+
+type decodeWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+func newDecodeLimiter(capacity int64, maxQueue int) *decodeLimiter {
+	return &decodeLimiter{capacity: capacity, maxQueue: maxQueue}
+}
+
+// acquire reserves n bytes, waiting until they are free or ctx is done.
+// Requests above the capacity are reduced to it, so a single large image
+// can still be processed on its own. The returned function releases the
+// reservation.
+func (l *decodeLimiter) acquire(ctx context.Context, n int64) (func(), error) {
+	n = min(n, l.capacity)
+
+	l.mu.Lock()
+	if l.waiters.Len() == 0 && l.used+n <= l.capacity {
+		l.used += n
+		l.mu.Unlock()
+		return l.releaseFunc(n), nil
+	}
+	if l.waiters.Len() >= l.maxQueue {
+		l.mu.Unlock()
+		return nil, errDecodeQueueFull
+	}
+
+	w := &decodeWaiter{n: n, ready: make(chan struct{})}
+	elem := l.waiters.PushBack(w)
+	decodeQueueLength.Set(float64(l.waiters.Len()))
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return l.releaseFunc(n), nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		select {
+		case <-w.ready:
+			// Granted while giving up; keep the reservation
+			return l.releaseFunc(n), nil
+		default:
+		}
+		isFront := l.waiters.Front() == elem
+		l.waiters.Remove(elem)
+		decodeQueueLength.Set(float64(l.waiters.Len()))
+		// The next waiter may fit now that this one no longer blocks it
+		if isFront {
+			l.grant()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (l *decodeLimiter) releaseFunc(n int64) func() {
+	decodeMemoryInUse.Add(float64(n))
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			decodeMemoryInUse.Add(-float64(n))
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.used -= n
+			l.grant()
+		})
+	}
+}
+
+// grant hands free memory to waiters in order. Callers hold mu.
+func (l *decodeLimiter) grant() {
+	for {
+		front := l.waiters.Front()
+		if front == nil {
+			break
+		}
+		w := front.Value.(*decodeWaiter)
+		if l.used+w.n > l.capacity {
+			break
+		}
+		l.used += w.n
+		l.waiters.Remove(front)
+		close(w.ready)
+	}
+	decodeQueueLength.Set(float64(l.waiters.Len()))
+}
+
+// reserveDecode reads the image header, rejects images above
+// media.max_image_pixels and reserves memory for the decoded bitmap,
+// waiting up to media.decode_queue_timeout. Call the returned function
+// once the decoded image is no longer used.
+func (s *Service) reserveDecode(ctx context.Context, fileBytes []byte, cfg config.MediaConfig) (func(), error) {
+	header, _, err := image.DecodeConfig(bytes.NewReader(fileBytes))
+	if err != nil {
+		return nil, undecodableImage(ctx, err)
+	}
+
+	pixels := int64(header.Width) * int64(header.Height)
+	if pixels > int64(cfg.MaxImagePixels) {
+		decodeRejections.Inc("too_many_pixels")
+		return nil, appErr.Validation(appErr.FieldError{
+			Field:   "file",
+			Message: fmt.Sprintf("image is %dx%d pixels; the limit is %d pixels", header.Width, header.Height, cfg.MaxImagePixels),
+		})
+	}
+
+	_, span := tracing.Start(ctx, "media.queue", tracing.WithAttributes(
+		tracing.Attr("image.width", header.Width),
+		tracing.Attr("image.height", header.Height),
+	))
+	defer span.End()
+
+	waitCtx, cancel := context.WithTimeout(ctx, cfg.DecodeQueueTimeout)
+	defer cancel()
+
+	release, err := s.decodeLimiter.acquire(waitCtx, pixels*bytesPerPixel(header.ColorModel))
+	span.SetError(err)
+	switch {
+	case err == nil:
+		return release, nil
+	case ctx.Err() != nil:
+		return nil, appErr.Internal("context cancelled", ctx.Err())
+	case errors.Is(err, errDecodeQueueFull):
+		decodeRejections.Inc("queue_full")
+		return nil, appErr.Unavailable("image processing is at capacity; retry later", err)
+	default:
+		decodeRejections.Inc("timeout")
+		return nil, appErr.Unavailable("timed out waiting for image processing capacity; retry later", err)
+	}
+}
+
+// undecodableImage logs why an image could not be decoded and returns a
+// client error that does not expose decoder internals
+func undecodableImage(ctx context.Context, err error) error {
+	slog.InfoContext(ctx, "Rejected undecodable image", "error", err)
+	return appErr.BadRequest(errUndecodable.Error())
+}
+
+// bytesPerPixel estimates the in-memory size of a decoded pixel. 16-bit
+// PNGs decode to 64-bit pixels; everything else fits in 32 bits or less.
+func bytesPerPixel(model color.Model) int64 {
+	switch model {
+	case color.RGBA64Model, color.NRGBA64Model:
+		return 8
+	default:
+		return 4
+	}
+}
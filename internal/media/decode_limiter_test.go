@@ -0,0 +1,184 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDecodeLimiterAcquire(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int64
+		maxQueue int
+		held     []int64
+		timeout  time.Duration
+		request  int64
+		wantErr  error
+		wantUsed int64
+	}{
+		{name: "fits", capacity: 100, maxQueue: 1, held: []int64{40}, request: 60, wantUsed: 100},
+		{name: "oversized request runs alone", capacity: 100, maxQueue: 1, request: 1000, wantUsed: 100},
+		{name: "queue full", capacity: 100, maxQueue: 0, held: []int64{60}, request: 60, wantErr: errDecodeQueueFull, wantUsed: 60},
+		{name: "wait times out", capacity: 100, maxQueue: 1, held: []int64{60}, timeout: 10 * time.Millisecond, request: 60, wantErr: context.DeadlineExceeded, wantUsed: 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newDecodeLimiter(tt.capacity, tt.maxQueue)
+			for _, n := range tt.held {
+				if _, err := l.acquire(context.Background(), n); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			release, err := l.acquire(ctx, tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("acquire() error = %v, want %v", err, tt.wantErr)
+			}
+			if l.used != tt.wantUsed {
+				t.Errorf("used = %d, want %d", l.used, tt.wantUsed)
+			}
+			if l.waiters.Len() != 0 {
+				t.Errorf("%d waiters left in the queue", l.waiters.Len())
+			}
+			if err == nil {
+				release()
+				release()
+				if want := tt.wantUsed - min(tt.request, tt.capacity); l.used != want {
+					t.Errorf("used after double release = %d, want %d", l.used, want)
+				}
+			}
+		})
+	}
+}
+
+// acquireAsync starts acquire in a goroutine and waits until it is queued
+func acquireAsync(t *testing.T, l *decodeLimiter, ctx context.Context, n int64) <-chan error {
+	t.Helper()
+	queued := l.waiters.Len()
+	done := make(chan error, 1)
+	go func() {
+		_, err := l.acquire(ctx, n)
+		done <- err
+	}()
+	for deadline := time.Now().Add(time.Second); ; {
+		l.mu.Lock()
+		waiting := l.waiters.Len()
+		l.mu.Unlock()
+		if waiting > queued {
+			return done
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("acquire did not queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func granted(t *testing.T, done <-chan error) bool {
+	t.Helper()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("acquire() error = %v", err)
+		}
+		return true
+	case <-time.After(50 * time.Millisecond):
+		return false
+	}
+}
+
+func TestDecodeLimiterServesWaitersInOrder(t *testing.T) {
+	l := newDecodeLimiter(100, 4)
+	releaseFirst, _ := l.acquire(context.Background(), 60)
+	releaseSecond, _ := l.acquire(context.Background(), 30)
+
+	large := acquireAsync(t, l, context.Background(), 80)
+	// 10 bytes are free, but the small request must not overtake the large one
+	small := acquireAsync(t, l, context.Background(), 10)
+	if granted(t, small) {
+		t.Fatal("small waiter overtook the large one")
+	}
+
+	releaseFirst()
+	if granted(t, large) {
+		t.Fatal("large waiter granted before enough memory was free")
+	}
+	releaseSecond()
+	if !granted(t, large) || !granted(t, small) {
+		t.Fatal("waiters not granted after release")
+	}
+	if l.used != 90 {
+		t.Errorf("used = %d, want 90", l.used)
+	}
+}
+
+func TestDecodeLimiterCancelledWaiter(t *testing.T) {
+	l := newDecodeLimiter(100, 4)
+	release, _ := l.acquire(context.Background(), 50)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	blocked := acquireAsync(t, l, ctx, 100)
+	next := acquireAsync(t, l, context.Background(), 40)
+
+	// Giving up at the front of the queue lets the next waiter in
+	cancel()
+	if err := <-blocked; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled acquire() error = %v", err)
+	}
+	if !granted(t, next) {
+		t.Fatal("next waiter not granted after the front waiter gave up")
+	}
+	if l.used != 90 || l.waiters.Len() != 0 {
+		t.Errorf("used = %d with %d waiters, want 90 and none", l.used, l.waiters.Len())
+	}
+	release()
+}
+
+func TestDecodeLimiterContention(t *testing.T) {
+	const capacity = 100
+	l := newDecodeLimiter(capacity, 64)
+
+	var inUse, peak atomic.Int64
+	var wg sync.WaitGroup
+	for i := range 64 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := int64(10 + i%5*10)
+			release, err := l.acquire(context.Background(), n)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			now := inUse.Add(n)
+			for {
+				p := peak.Load()
+				if now <= p || peak.CompareAndSwap(p, now) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			inUse.Add(-n)
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p > capacity {
+		t.Errorf("peak reserved memory = %d, want at most %d", p, capacity)
+	}
+	if l.used != 0 || l.waiters.Len() != 0 {
+		t.Errorf("used = %d with %d waiters after all released", l.used, l.waiters.Len())
+	}
+}
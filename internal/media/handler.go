@@ -4,21 +4,29 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 
 	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/etag"
 	"github.com/go-chi/chi/v5"
 )
 
 type Handler struct {
 	service *Service
+	// maxMultipartMemory is how much of an upload form is buffered in
+	// memory before spilling to temporary files
+	maxMultipartMemory int64
 }
 
-func NewHandler(service *Service) *Handler {
-	return &Handler{service: service}
+func NewHandler(service *Service, maxMultipartMemory int64) *Handler {
+	return &Handler{service: service, maxMultipartMemory: maxMultipartMemory}
 }
 
 // RegisterRoutes registers all media-related routes with appropriate middleware
 // Middleware is passed as parameters to avoid circular imports
+// validateIDMw must accept UUIDs, the form media IDs take
 func (h *Handler) RegisterRoutes(r chi.Router, loggingMw, authMw, validateIDMw func(http.Handler) http.Handler) {
 	r.Route("/media", func(r chi.Router) {
 		// Middleware for ALL media routes
@@ -27,12 +35,17 @@ func (h *Handler) RegisterRoutes(r chi.Router, loggingMw, authMw, validateIDMw f
 
 		r.Post("/upload", h.UploadMedia)
 		r.Get("/", h.GetAllMedia)
+		r.Get("/search", h.SearchMedia)
 		r.Route("/{id}", func(r chi.Router) {
 			// Middleware for ID-specific operations
 			r.Use(validateIDMw)
 
 			r.Get("/", h.GetMedia)
 			r.Get("/download", h.DownloadMedia)
+			r.Get("/stream", h.StreamMedia)
+			r.Get("/poster", h.GetPoster)
+			r.Put("/tags", h.SetTags)
+			r.Get("/similar", h.GetSimilarMedia)
 			r.Delete("/", h.DeleteMedia)
 		})
 	})
@@ -40,42 +53,44 @@ func (h *Handler) RegisterRoutes(r chi.Router, loggingMw, authMw, validateIDMw f
 
 // UploadMedia handles file upload - POST /media/upload
 func (h *Handler) UploadMedia(w http.ResponseWriter, r *http.Request) {
-	// Parse multipart form with 300MB max (buffer for multiple files)
-	err := r.ParseMultipartForm(300 * 1024 * 1024)
+	// Parse multipart form, buffering up to the configured size in memory
+	err := r.ParseMultipartForm(h.maxMultipartMemory)
 	if err != nil {
-		slog.Error("Failed to parse form", "error", err)
-		respondMediaError(w, appErr.BadRequest("failed to parse form"), http.StatusBadRequest)
+		slog.ErrorContext(r.Context(), "Failed to parse form", "error", err)
+		appErr.WriteError(w, r, appErr.BadRequest("failed to parse form"))
 		return
 	}
 
 	file, fileHeader, err := r.FormFile("file")
 	if err != nil {
-		slog.Error("Failed to get file from request", "error", err)
-		respondMediaError(w, appErr.BadRequest("failed to get file from request"), http.StatusBadRequest)
+		slog.ErrorContext(r.Context(), "Failed to get file from request", "error", err)
+		appErr.WriteError(w, r, appErr.Validation(appErr.FieldError{Field: "file", Message: "is required"}))
 		return
 	}
 	defer file.Close()
 
 	// Upload and process media
-	media, err := h.service.UploadMedia(r.Context(), fileHeader)
+	media, err := h.service.UploadMedia(r.Context(), fileHeader, parseTags(r.MultipartForm.Value["tags"]))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to upload media", "error", err)
+		// Shed uploads may be retried once image processing catches up
+		if appErr.HasCode(err, appErr.ErrCodeUnavailable) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(DecodeRetryAfter.Seconds())))
+		}
+		appErr.WriteError(w, r, err)
+		return
+	}
 
 	response := &MediaUploadResponse{
-		Success: err == nil,
+		Success: true,
+		Message: "File uploaded and processed successfully",
 		Media:   media,
 	}
 
-	if err != nil {
-		response.Error = err.Error()
-		response.Success = false
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(getMediaStatusCode(err))
-	} else {
-		response.Message = "File uploaded and processed successfully"
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		slog.Info("Media uploaded", "id", media.ID)
-	}
-
+	slog.InfoContext(r.Context(), "Media uploaded", "id", media.ID)
+	etag.Set(w, media.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -83,13 +98,8 @@ func (h *Handler) UploadMedia(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetAllMedia(w http.ResponseWriter, r *http.Request) {
 	mediaList, err := h.service.GetAllMedia(r.Context())
 	if err != nil {
-		slog.Error("Failed to get all media", "error", err)
-		response := &MediaListResponse{
-			Error: "Failed to retrieve media: " + err.Error(),
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		slog.ErrorContext(r.Context(), "Failed to get all media", "error", err)
+		appErr.WriteError(w, r, err)
 		return
 	}
 
@@ -105,38 +115,101 @@ func (h *Handler) GetAllMedia(w http.ResponseWriter, r *http.Request) {
 
 // GetMedia retrieves a specific media file - GET /media/{id}
 func (h *Handler) GetMedia(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
+	id := chi.URLParam(r, "id")
 
 	media, err := h.service.GetMedia(r.Context(), id)
 	if err != nil {
-		slog.Error("Failed to get media", "id", id, "error", err)
-		response := &MediaListResponse{
-			Error: "Media not found",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(response)
+		slog.ErrorContext(r.Context(), "Failed to get media", "id", id, "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	etag.Set(w, media.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(media)
+}
+
+// SearchMedia searches media by text, tag and type - GET /media/search?q=&tag=&type=&page=&page_size=
+func (h *Handler) SearchMedia(w http.ResponseWriter, r *http.Request) {
+	params, err := ParseSearchParams(r.URL.Query())
+	if err != nil {
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	response, err := h.service.SearchMedia(r.Context(), params.Query, params.Page, params.PageSize)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to search media", "error", err)
+		appErr.WriteError(w, r, err)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// SetTags replaces the tags of a media file - PUT /media/{id}/tags
+func (h *Handler) SetTags(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req SetTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to decode request", "error", err)
+		appErr.WriteError(w, r, appErr.BadRequest("invalid request body"))
+		return
+	}
+
+	media, err := h.service.SetTags(r.Context(), id, req.Tags, etag.IfMatch(r))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to set media tags", "id", id, "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	etag.Set(w, media.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(media)
 }
 
+// GetSimilarMedia finds near-duplicate images - GET /media/{id}/similar?max_distance=
+func (h *Handler) GetSimilarMedia(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	maxDistance, err := ParseMaxDistance(r.URL.Query())
+	if err != nil {
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	similar, err := h.service.FindSimilar(r.Context(), id, maxDistance)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to find similar media", "id", id, "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	response := &SimilarMediaResponse{
+		Total:       len(similar),
+		MaxDistance: maxDistance,
+		Similar:     similar,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
 // DeleteMedia deletes a media file - DELETE /media/{id}
 func (h *Handler) DeleteMedia(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
+	id := chi.URLParam(r, "id")
 
-	err := h.service.DeleteMedia(r.Context(), id)
+	err := h.service.DeleteMedia(r.Context(), id, etag.IfMatch(r))
 	if err != nil {
-		slog.Error("Failed to delete media", "id", id, "error", err)
-		response := &MediaListResponse{
-			Error: "Failed to delete media: " + err.Error(),
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(response)
+		slog.ErrorContext(r.Context(), "Failed to delete media", "id", id, "error", err)
+		appErr.WriteError(w, r, err)
 		return
 	}
 
@@ -145,7 +218,7 @@ func (h *Handler) DeleteMedia(w http.ResponseWriter, r *http.Request) {
 		"message": "Media deleted successfully",
 	}
 
-	slog.Info("Media deleted", "id", id)
+	slog.InfoContext(r.Context(), "Media deleted", "id", id)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
@@ -153,12 +226,12 @@ func (h *Handler) DeleteMedia(w http.ResponseWriter, r *http.Request) {
 
 // DownloadMedia serves a media file - GET /media/{id}/download
 func (h *Handler) DownloadMedia(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
+	id := chi.URLParam(r, "id")
 
 	media, err := h.service.GetMedia(r.Context(), id)
 	if err != nil {
-		slog.Error("Failed to get media for download", "id", id, "error", err)
-		http.Error(w, "Media not found", http.StatusNotFound)
+		slog.ErrorContext(r.Context(), "Failed to get media for download", "id", id, "error", err)
+		appErr.WriteError(w, r, err)
 		return
 	}
 
@@ -168,8 +241,64 @@ func (h *Handler) DownloadMedia(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, media.FilePath)
 }
 
+// StreamMedia serves a media file inline with HTTP range support so video
+// players can seek - GET /media/{id}/stream
+func (h *Handler) StreamMedia(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	media, err := h.service.GetMedia(r.Context(), id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get media for streaming", "id", id, "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	serveFileInline(w, r, media.FilePath, media.OriginalName, getMediaContentType(media.Format))
+}
+
+// GetPoster serves the poster frame of a video - GET /media/{id}/poster
+func (h *Handler) GetPoster(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	media, err := h.service.GetMedia(r.Context(), id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get media for poster", "id", id, "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+	if media.PosterPath == "" {
+		appErr.WriteError(w, r, appErr.NotFound("poster not available"))
+		return
+	}
+
+	serveFileInline(w, r, media.PosterPath, "poster.jpg", "image/jpeg")
+}
+
 // Helper functions
 
+// serveFileInline serves a file with http.ServeContent, which handles Range,
+// If-Range and conditional requests
+func serveFileInline(w http.ResponseWriter, r *http.Request, path, name, contentType string) {
+	f, err := os.Open(path)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to open media file", "path", path, "error", err)
+		appErr.WriteError(w, r, appErr.NotFound("media file not available"))
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to stat media file", "path", path, "error", err)
+		appErr.WriteError(w, r, appErr.Internal("media file not available", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "inline; filename="+strconv.Quote(name))
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
 func getMediaContentType(format string) string {
 	switch format {
 	case "jpg", "jpeg":
@@ -182,38 +311,28 @@ func getMediaContentType(format string) string {
 		return "image/gif"
 	case "pdf":
 		return "application/pdf"
+	case "mp4":
+		return "video/mp4"
+	case "webm":
+		return "video/webm"
+	case "mp3":
+		return "audio/mpeg"
+	case "ogg":
+		return "audio/ogg"
+	case "wav":
+		return "audio/wav"
+	case "flac":
+		return "audio/flac"
 	default:
 		return "application/octet-stream"
 	}
 }
 
-func getMediaStatusCode(err error) int {
-	if err == nil {
-		return http.StatusOK
+// parseTags accepts both repeated values and comma-separated lists
+func parseTags(values []string) []string {
+	var tags []string
+	for _, v := range values {
+		tags = append(tags, strings.Split(v, ",")...)
 	}
-
-	ae := appErr.GetAppError(err)
-	if ae == nil {
-		return http.StatusInternalServerError
-	}
-
-	switch ae.Code {
-	case appErr.ErrCodeNotFound:
-		return http.StatusNotFound
-	case appErr.ErrCodeBadRequest, appErr.ErrCodeInvalidID, appErr.ErrCodeUnsupported:
-		return http.StatusBadRequest
-	case appErr.ErrCodeFileTooLarge:
-		return http.StatusRequestEntityTooLarge
-	default:
-		return http.StatusInternalServerError
-	}
-}
-
-func respondMediaError(w http.ResponseWriter, err error, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	response := &MediaListResponse{
-		Error: err.Error(),
-	}
-	json.NewEncoder(w).Encode(response)
+	return tags
 }
@@ -0,0 +1,201 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"testing"
+	"time"
+
+	"example.com/myapp/internal/audit"
+	"example.com/myapp/internal/config"
+	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/outbox"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := range width {
+		img.Set(x, 0, color.NRGBA{R: uint8(x), A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// uploadRequest builds a multipart upload of data in the file field;
+// without a content type no file is attached
+func uploadRequest(t *testing.T, contentType string, data []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if contentType != "" {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", `form-data; name="file"; filename="upload"`)
+		h.Set("Content-Type", contentType)
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(data)
+	}
+	mw.WriteField("tags", "holiday")
+	mw.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/media/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestUploadMedia(t *testing.T) {
+	photo := encodePNG(t, 40, 30)
+
+	tests := []struct {
+		name        string
+		configure   func(*config.MediaConfig)
+		saturate    bool
+		contentType string
+		data        []byte
+		wantStatus  int
+		wantCode    string
+		wantDetail  string
+		wantField   string
+	}{
+		{name: "image", contentType: "image/png", data: photo, wantStatus: http.StatusCreated},
+		{name: "no file", wantStatus: http.StatusBadRequest, wantCode: appErr.ErrCodeValidation, wantField: "is required"},
+		{
+			name:        "file too large",
+			configure:   func(c *config.MediaConfig) { c.MaxFileSize = 10 },
+			contentType: "image/png", data: photo,
+			wantStatus: http.StatusRequestEntityTooLarge, wantCode: appErr.ErrCodeFileTooLarge,
+		},
+		{
+			name:        "unsupported type",
+			contentType: "text/plain", data: []byte("hello"),
+			wantStatus: http.StatusUnsupportedMediaType, wantCode: appErr.ErrCodeUnsupported,
+		},
+		{
+			name:        "mislabelled audio outside allowed formats",
+			configure:   func(c *config.MediaConfig) { c.AllowedFormats = []string{"audio/mpeg"} },
+			contentType: "audio/mpeg", data: validWAV(),
+			wantStatus: http.StatusUnsupportedMediaType, wantCode: appErr.ErrCodeUnsupported,
+		},
+		{
+			name:        "storage quota exceeded",
+			configure:   func(c *config.MediaConfig) { c.StorageQuota = 10 },
+			contentType: "image/png", data: photo,
+			wantStatus: http.StatusForbidden, wantCode: appErr.ErrCodeQuotaExceeded,
+		},
+		{
+			name:        "too many pixels",
+			configure:   func(c *config.MediaConfig) { c.MaxImagePixels = 1000 },
+			contentType: "image/png", data: photo,
+			wantStatus: http.StatusBadRequest, wantCode: appErr.ErrCodeValidation,
+			wantField: "image is 40x30 pixels; the limit is 1000 pixels",
+		},
+		{
+			name:        "unreadable header",
+			contentType: "image/png", data: []byte("not an image at all"),
+			wantStatus: http.StatusBadRequest, wantCode: appErr.ErrCodeBadRequest, wantDetail: "unsupported or corrupt image",
+		},
+		{
+			name:        "truncated image data",
+			contentType: "image/png", data: photo[:len(photo)-20],
+			wantStatus: http.StatusBadRequest, wantCode: appErr.ErrCodeBadRequest, wantDetail: "unsupported or corrupt image",
+		},
+		{
+			name:        "decode queue full",
+			configure:   func(c *config.MediaConfig) { c.DecodeQueueSize = 0 },
+			saturate:    true,
+			contentType: "image/png", data: photo,
+			wantStatus: http.StatusServiceUnavailable, wantCode: appErr.ErrCodeUnavailable,
+		},
+		{
+			name:        "decode queue timeout",
+			configure:   func(c *config.MediaConfig) { c.DecodeQueueTimeout = 10 * time.Millisecond },
+			saturate:    true,
+			contentType: "image/png", data: photo,
+			wantStatus: http.StatusServiceUnavailable, wantCode: appErr.ErrCodeUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Media.StoragePath = t.TempDir()
+			cfg.Media.DecodeMemory = 1 << 20
+			if tt.configure != nil {
+				tt.configure(&cfg.Media)
+			}
+			repo := NewInMemoryRepository(outbox.NewInMemoryStore())
+			service := NewService(repo, nil, audit.NewService(audit.NewInMemoryRepository()), config.NewStore(cfg, nil))
+			if tt.saturate {
+				release, _ := service.decodeLimiter.acquire(context.Background(), cfg.Media.DecodeMemory)
+				defer release()
+			}
+
+			w := httptest.NewRecorder()
+			NewHandler(service, cfg.Media.MaxMultipartMemory).UploadMedia(w, uploadRequest(t, tt.contentType, tt.data))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			stored, _ := os.ReadDir(cfg.Media.StoragePath)
+
+			if tt.wantStatus == http.StatusCreated {
+				var resp MediaUploadResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				m := resp.Media
+				if m.Type != "image" || m.Width != 40 || m.Height != 30 || m.PerceptualHash == "" || len(m.Tags) != 1 {
+					t.Errorf("media = %+v", m)
+				}
+				if len(stored) != 1 {
+					t.Errorf("%d files stored, want 1", len(stored))
+				}
+				if all, _ := repo.GetAll(context.Background()); len(all) != 1 {
+					t.Errorf("%d media saved, want 1", len(all))
+				}
+				if service.decodeLimiter.used != 0 {
+					t.Errorf("decode memory still reserved: %d", service.decodeLimiter.used)
+				}
+				return
+			}
+
+			var problem appErr.Problem
+			if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Code != tt.wantCode {
+				t.Errorf("code = %s, want %s", problem.Code, tt.wantCode)
+			}
+			if tt.wantDetail != "" && problem.Detail != tt.wantDetail {
+				t.Errorf("detail = %q, want %q", problem.Detail, tt.wantDetail)
+			}
+			if tt.wantField != "" && (len(problem.Errors) != 1 || problem.Errors[0].Field != "file" || problem.Errors[0].Message != tt.wantField) {
+				t.Errorf("errors = %+v, want file: %s", problem.Errors, tt.wantField)
+			}
+			if tt.wantStatus == http.StatusServiceUnavailable && w.Header().Get("Retry-After") != "5" {
+				t.Errorf("Retry-After = %q, want 5", w.Header().Get("Retry-After"))
+			}
+			if len(stored) != 0 {
+				t.Errorf("rejected upload stored %d files", len(stored))
+			}
+			if !tt.saturate && service.decodeLimiter.used != 0 {
+				t.Errorf("decode memory still reserved: %d", service.decodeLimiter.used)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package media
+
+import (
+	"context"
+
+	"example.com/myapp/internal/metrics"
+)
+
+// instrumentedRepository records the latency of every call to the wrapped
+// repository and traces it as a child span of the caller
+type instrumentedRepository struct {
+	next Repository
+}
+
+// NewInstrumentedRepository wraps repo with operation metrics and spans
+func NewInstrumentedRepository(repo Repository) Repository {
+	return &instrumentedRepository{next: repo}
+}
+
+func (r *instrumentedRepository) Save(ctx context.Context, media *Media, eventTypes ...string) error {
+	return metrics.ObserveRepositoryCall(ctx, "media", "save", func(ctx context.Context) error {
+		return r.next.Save(ctx, media, eventTypes...)
+	})
+}
+
+func (r *instrumentedRepository) GetByID(ctx context.Context, id string) (*Media, error) {
+	return metrics.ObserveRepository(ctx, "media", "get_by_id", func(ctx context.Context) (*Media, error) {
+		return r.next.GetByID(ctx, id)
+	})
+}
+
+func (r *instrumentedRepository) GetAll(ctx context.Context) ([]*Media, error) {
+	return metrics.ObserveRepository(ctx, "media", "get_all", r.next.GetAll)
+}
+
+func (r *instrumentedRepository) Delete(ctx context.Context, id string, version int, eventTypes ...string) error {
+	return metrics.ObserveRepositoryCall(ctx, "media", "delete", func(ctx context.Context) error {
+		return r.next.Delete(ctx, id, version, eventTypes...)
+	})
+}
+
+func (r *instrumentedRepository) Ping(ctx context.Context) error {
+	return metrics.ObserveRepositoryCall(ctx, "media", "ping", r.next.Ping)
+}
@@ -5,23 +5,29 @@ import (
 	"sync"
 
 	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/outbox"
 )
 
 // InMemoryRepository is an in-memory implementation of Repository
 type InMemoryRepository struct {
-	mu    sync.RWMutex
-	media map[string]*Media
+	mu     sync.RWMutex
+	media  map[string]*Media
+	outbox outbox.Store
 }
 
-// NewInMemoryRepository creates a new in-memory media repository
-func NewInMemoryRepository() *InMemoryRepository {
+// NewInMemoryRepository creates a new in-memory media repository that
+// stages events in outboxStore
+func NewInMemoryRepository(outboxStore outbox.Store) *InMemoryRepository {
 	return &InMemoryRepository{
-		media: make(map[string]*Media),
+		media:  make(map[string]*Media),
+		outbox: outboxStore,
 	}
 }
 
-// Save stores a media file
-func (r *InMemoryRepository) Save(ctx context.Context, media *Media) error {
+// Save stores a media file. New media start at version 1; replacing
+// existing media requires media.Version to match the stored version and
+// increments it.
+func (r *InMemoryRepository) Save(ctx context.Context, media *Media, eventTypes ...string) error {
 	if err := ctx.Err(); err != nil {
 		return appErr.Internal("context cancelled", err)
 	}
@@ -33,7 +39,21 @@ func (r *InMemoryRepository) Save(ctx context.Context, media *Media) error {
 		return appErr.BadRequest("media ID is required")
 	}
 
-	r.media[media.ID] = media
+	saved := cloneMedia(media)
+	if existing, exists := r.media[media.ID]; exists {
+		if existing.Version != media.Version {
+			return appErr.PreconditionFailed("media was modified concurrently")
+		}
+		saved.Version++
+	} else {
+		saved.Version = 1
+	}
+	if err := outbox.Stage(ctx, r.outbox, saved.ID, saved, eventTypes...); err != nil {
+		return appErr.Internal("failed to stage media events", err)
+	}
+
+	media.Version = saved.Version
+	r.media[media.ID] = saved
 	return nil
 }
 
@@ -50,7 +70,7 @@ func (r *InMemoryRepository) GetByID(ctx context.Context, id string) (*Media, er
 	if !exists {
 		return nil, appErr.NotFound("media not found")
 	}
-	return media, nil
+	return cloneMedia(media), nil
 }
 
 // GetAll retrieves all media files
@@ -64,13 +84,13 @@ func (r *InMemoryRepository) GetAll(ctx context.Context) ([]*Media, error) {
 
 	mediaList := make([]*Media, 0, len(r.media))
 	for _, m := range r.media {
-		mediaList = append(mediaList, m)
+		mediaList = append(mediaList, cloneMedia(m))
 	}
 	return mediaList, nil
 }
 
-// Delete removes a media file
-func (r *InMemoryRepository) Delete(ctx context.Context, id string) error {
+// Delete removes a media file if it is still at version
+func (r *InMemoryRepository) Delete(ctx context.Context, id string, version int, eventTypes ...string) error {
 	if err := ctx.Err(); err != nil {
 		return appErr.Internal("context cancelled", err)
 	}
@@ -78,9 +98,43 @@ func (r *InMemoryRepository) Delete(ctx context.Context, id string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.media[id]; !exists {
+	media, exists := r.media[id]
+	if !exists {
 		return appErr.NotFound("media not found")
 	}
+	if media.Version != version {
+		return appErr.PreconditionFailed("media was modified concurrently")
+	}
+	if err := outbox.Stage(ctx, r.outbox, id, media, eventTypes...); err != nil {
+		return appErr.Internal("failed to stage media events", err)
+	}
 	delete(r.media, id)
 	return nil
 }
+
+// Ping checks that the repository lock can be taken, so a deadlocked
+// repository fails readiness
+func (r *InMemoryRepository) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.RLock()
+	r.mu.RUnlock()
+	return nil
+}
+
+// cloneMedia returns a deep copy so callers never share the stored media
+func cloneMedia(media *Media) *Media {
+	c := *media
+	c.Tags = append([]string(nil), media.Tags...)
+	c.DominantColors = append([]string(nil), media.DominantColors...)
+	c.Waveform = append([]float32(nil), media.Waveform...)
+	if media.AudioTags != nil {
+		c.AudioTags = make(map[string]string, len(media.AudioTags))
+		for k, v := range media.AudioTags {
+			c.AudioTags[k] = v
+		}
+	}
+	return &c
+}
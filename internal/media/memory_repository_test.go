@@ -0,0 +1,57 @@
+package media
+
+import (
+	"context"
+	"testing"
+
+	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/outbox"
+)
+
+func TestInMemoryRepositoryVersions(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRepository(outbox.NewInMemoryStore())
+	m := &Media{ID: "m1", Tags: []string{"a"}}
+	if err := repo.Save(ctx, m); err != nil || m.Version != 1 {
+		t.Fatalf("Save() new media: version %d, error %v; want version 1", m.Version, err)
+	}
+
+	first, _ := repo.GetByID(ctx, "m1")
+	second, _ := repo.GetByID(ctx, "m1")
+
+	// Copies are returned, so callers cannot change the stored media
+	first.Tags[0] = "changed"
+	if stored, _ := repo.GetByID(ctx, "m1"); stored.Tags[0] != "a" {
+		t.Fatalf("stored tags changed through a returned copy: %v", stored.Tags)
+	}
+
+	tests := []struct {
+		name        string
+		run         func() error
+		wantCode    string
+		wantVersion int
+	}{
+		{name: "first writer wins", run: func() error { return repo.Save(ctx, first) }, wantVersion: 2},
+		{name: "second writer is stale", run: func() error { return repo.Save(ctx, second) }, wantCode: appErr.ErrCodePrecondition, wantVersion: 2},
+		{name: "stale delete", run: func() error { return repo.Delete(ctx, "m1", 1) }, wantCode: appErr.ErrCodePrecondition, wantVersion: 2},
+		{name: "current delete", run: func() error { return repo.Delete(ctx, "m1", 2) }},
+		{name: "delete of missing media", run: func() error { return repo.Delete(ctx, "m1", 2) }, wantCode: appErr.ErrCodeNotFound},
+	}
+	// The steps build on each other, so they run in order on one repository
+	for _, tt := range tests {
+		err := tt.run()
+		if tt.wantCode == "" && err != nil || tt.wantCode != "" && !appErr.HasCode(err, tt.wantCode) {
+			t.Fatalf("%s: error = %v, want code %q", tt.name, err, tt.wantCode)
+		}
+		stored, err := repo.GetByID(ctx, "m1")
+		if tt.wantVersion == 0 {
+			if !appErr.HasCode(err, appErr.ErrCodeNotFound) {
+				t.Errorf("%s: media still stored: %+v", tt.name, stored)
+			}
+			continue
+		}
+		if err != nil || stored.Version != tt.wantVersion {
+			t.Errorf("%s: stored version %v, error %v; want version %d", tt.name, stored, err, tt.wantVersion)
+		}
+	}
+}
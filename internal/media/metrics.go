@@ -0,0 +1,35 @@
+package media
+
+import "example.com/myapp/internal/metrics"
+
+var (
+	uploadsTotal = metrics.NewCounterVec(
+		"media_uploads_total",
+		"Successfully stored uploads by media type.",
+		"type",
+	)
+	uploadBytes = metrics.NewCounterVec(
+		"media_upload_bytes_total",
+		"Bytes received in successful uploads by media type.",
+		"type",
+	)
+	imageProcessingDuration = metrics.NewHistogramVec(
+		"media_image_processing_duration_seconds",
+		"Time to decode and re-encode an uploaded image by source format.",
+		nil,
+		"format",
+	)
+	decodeMemoryInUse = metrics.NewGaugeVec(
+		"media_image_decode_memory_bytes",
+		"Estimated bitmap memory reserved by images being decoded.",
+	)
+	decodeQueueLength = metrics.NewGaugeVec(
+		"media_image_decode_queue_length",
+		"Uploads waiting for image decode memory.",
+	)
+	decodeRejections = metrics.NewCounterVec(
+		"media_image_decode_rejections_total",
+		"Images rejected before decoding by reason: too_many_pixels, queue_full or timeout.",
+		"reason",
+	)
+)
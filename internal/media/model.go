@@ -7,13 +7,66 @@ type Media struct {
 	ID           string    `json:"id"`
 	OriginalName string    `json:"original_name"`
 	StoredName   string    `json:"stored_name"`
-	Type         string    `json:"type"` // image, pdf
-	Format       string    `json:"format"` // jpg, png, webp, pdf
+	Type         string    `json:"type"`   // image, pdf, video, audio
+	Format       string    `json:"format"` // jpg, png, webp, pdf, mp4, webm, mp3, ogg, wav, flac
 	SizeBytes    int64     `json:"size_bytes"`
 	FilePath     string    `json:"file_path"`
 	UploadedAt   time.Time `json:"uploaded_at"`
-	Width        int       `json:"width,omitempty"` // For images
+	Width        int       `json:"width,omitempty"`  // For images
 	Height       int       `json:"height,omitempty"` // For images
+	Tags         []string  `json:"tags,omitempty"`
+	Version      int       `json:"version"` // Increments on every update; exposed as the ETag
+
+	// PerceptualHash is a 64-bit dHash in hex, used to find near-duplicate images
+	PerceptualHash string `json:"perceptual_hash,omitempty"`
+
+	// Placeholder data so clients can render an image before it loads.
+	// Colors are "#rrggbb"; dominant colors are most common first.
+	AverageColor   string   `json:"average_color,omitempty"`
+	DominantColors []string `json:"dominant_colors,omitempty"`
+	BlurHash       string   `json:"blurhash,omitempty"`
+
+	// Video metadata parsed from the container headers. Width and Height
+	// above hold the video resolution.
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	VideoCodec      string  `json:"video_codec,omitempty"`
+	AudioCodec      string  `json:"audio_codec,omitempty"`
+	// PosterPath is where the poster frame is stored on disk; clients
+	// fetch it from PosterURL
+	PosterPath string `json:"-"`
+	PosterURL  string `json:"poster_url,omitempty"`
+
+	// Audio metadata. DurationSeconds and AudioCodec above are shared with
+	// video. AudioTags uses normalised keys (title, artist, album, date,
+	// genre, track); Waveform holds peak amplitudes in [0, 1].
+	SampleRate  int               `json:"sample_rate,omitempty"`
+	Channels    int               `json:"channels,omitempty"`
+	BitrateKbps int               `json:"bitrate_kbps,omitempty"`
+	AudioTags   map[string]string `json:"audio_tags,omitempty"`
+	Waveform    []float32         `json:"waveform,omitempty"`
+}
+
+// Variant is a rendition of a media file that clients can fetch
+type Variant struct {
+	// Name is original, stream or poster
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	URL         string `json:"url"`
+}
+
+// Variants lists the renditions of m: the stored file, an inline stream
+// with range support for video and audio, and the poster frame of videos
+// that have one
+func (m *Media) Variants() []Variant {
+	base := "/media/" + m.ID
+	variants := []Variant{{Name: "original", ContentType: getMediaContentType(m.Format), URL: base + "/download"}}
+	if m.Type == "video" || m.Type == "audio" {
+		variants = append(variants, Variant{Name: "stream", ContentType: getMediaContentType(m.Format), URL: base + "/stream"})
+	}
+	if m.PosterURL != "" {
+		variants = append(variants, Variant{Name: "poster", ContentType: "image/jpeg", URL: m.PosterURL})
+	}
+	return variants
 }
 
 // MediaUploadResponse is the response after uploading media
@@ -21,12 +74,42 @@ type MediaUploadResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
 	Media   *Media `json:"media,omitempty"`
-	Error   string `json:"error,omitempty"`
 }
 
 // MediaListResponse is the response when listing media
 type MediaListResponse struct {
-	Total  int      `json:"total"`
-	Media  []*Media `json:"media"`
-	Error  string   `json:"error,omitempty"`
+	Total int      `json:"total"`
+	Media []*Media `json:"media"`
+}
+
+// SetTagsRequest replaces the tags of a media file
+type SetTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// SearchResult is a media file matched by a search with its relevance score
+type SearchResult struct {
+	Media *Media  `json:"media"`
+	Score float64 `json:"score"`
+}
+
+// MediaSearchResponse is the response when searching media
+type MediaSearchResponse struct {
+	Total    int             `json:"total"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"page_size"`
+	Results  []*SearchResult `json:"results"`
+}
+
+// SimilarMedia is an image found to be a near-duplicate of another
+type SimilarMedia struct {
+	Media    *Media `json:"media"`
+	Distance int    `json:"distance"`
+}
+
+// SimilarMediaResponse is the response when looking up near-duplicate images
+type SimilarMediaResponse struct {
+	Total       int             `json:"total"`
+	MaxDistance int             `json:"max_distance"`
+	Similar     []*SimilarMedia `json:"similar"`
 }
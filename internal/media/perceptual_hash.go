@@ -0,0 +1,233 @@
+package media
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/bits"
+	"strconv"
+	"sync"
+)
+
+const (
+	// DefaultSimilarityDistance is the Hamming distance under which two images
+	// are considered near-duplicates when the caller does not specify one
+	DefaultSimilarityDistance = 10
+
+	// MaxSimilarityDistance bounds the threshold accepted from callers; above
+	// this most unrelated images start to match
+	MaxSimilarityDistance = 24
+)
+
+// differenceHash computes a 64-bit dHash: the image is reduced to a 9x8
+// grayscale thumbnail and each bit records whether a pixel is brighter than
+// its right-hand neighbour. Re-encoded and resized copies of an image hash to
+// the same or nearby values.
+func differenceHash(img image.Image) uint64 {
+	const w, h = 9, 8
+	var sums [h][w]float64
+	var counts [h][w]int
+
+	b := img.Bounds()
+	if b.Empty() {
+		return 0
+	}
+
+	// Box-filter downscale; large images are sampled on a grid so hashing
+	// stays cheap regardless of resolution
+	stepX := max(1, b.Dx()/(w*16))
+	stepY := max(1, b.Dy()/(h*16))
+	for y := b.Min.Y; y < b.Max.Y; y += stepY {
+		cy := (y - b.Min.Y) * h / b.Dy()
+		for x := b.Min.X; x < b.Max.X; x += stepX {
+			cx := (x - b.Min.X) * w / b.Dx()
+			g := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+			sums[cy][cx] += float64(g.Y)
+			counts[cy][cx]++
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			left := sums[y][x] / float64(max(1, counts[y][x]))
+			right := sums[y][x+1] / float64(max(1, counts[y][x+1]))
+			hash <<= 1
+			if left > right {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// formatHash renders a perceptual hash as 16 hex digits
+func formatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// parseHash parses a hash produced by formatHash
+func parseHash(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 64)
+}
+
+func hammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// HashMatch is a media ID found within a distance of a query hash
+type HashMatch struct {
+	ID       string
+	Distance int
+}
+
+// HashIndex is a BK-tree over perceptual hashes. Lookups only visit subtrees
+// whose distance band can contain a match, so queries with small thresholds
+// touch a fraction of the stored hashes.
+type HashIndex struct {
+	mu      sync.RWMutex
+	root    *bkNode
+	hashes  map[string]uint64 // media ID -> hash
+	size    int               // nodes in the tree
+	emptied int               // nodes whose IDs were all removed
+}
+
+type bkNode struct {
+	hash     uint64
+	ids      []string
+	children map[int]*bkNode
+}
+
+// NewHashIndex creates an empty hash index
+func NewHashIndex() *HashIndex {
+	return &HashIndex{hashes: make(map[string]uint64)}
+}
+
+// Add indexes the hash of a media file, replacing any previous entry
+func (idx *HashIndex) Add(id string, hash uint64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(id)
+	idx.hashes[id] = hash
+	idx.insertLocked(id, hash)
+}
+
+func (idx *HashIndex) insertLocked(id string, hash uint64) {
+	if idx.root == nil {
+		idx.root = &bkNode{hash: hash, ids: []string{id}}
+		idx.size++
+		return
+	}
+
+	node := idx.root
+	for {
+		d := hammingDistance(node.hash, hash)
+		if d == 0 {
+			if len(node.ids) == 0 {
+				idx.emptied--
+			}
+			node.ids = append(node.ids, id)
+			return
+		}
+		child, ok := node.children[d]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[int]*bkNode)
+			}
+			node.children[d] = &bkNode{hash: hash, ids: []string{id}}
+			idx.size++
+			return
+		}
+		node = child
+	}
+}
+
+// Remove drops a media file from the index. BK-trees cannot delete interior
+// nodes, so emptied nodes stay as routing points until they outnumber the
+// live ones and the tree is rebuilt.
+func (idx *HashIndex) Remove(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(id)
+}
+
+func (idx *HashIndex) removeLocked(id string) {
+	hash, ok := idx.hashes[id]
+	if !ok {
+		return
+	}
+	delete(idx.hashes, id)
+
+	node := idx.root
+	for node != nil {
+		d := hammingDistance(node.hash, hash)
+		if d == 0 {
+			for i, v := range node.ids {
+				if v == id {
+					node.ids = append(node.ids[:i], node.ids[i+1:]...)
+					break
+				}
+			}
+			if len(node.ids) == 0 {
+				idx.emptied++
+			}
+			break
+		}
+		node = node.children[d]
+	}
+
+	if idx.emptied > idx.size/2 {
+		idx.rebuildLocked()
+	}
+}
+
+func (idx *HashIndex) rebuildLocked() {
+	idx.root = nil
+	idx.size = 0
+	idx.emptied = 0
+	for id, hash := range idx.hashes {
+		idx.insertLocked(id, hash)
+	}
+}
+
+// Hash returns the indexed hash of a media file
+func (idx *HashIndex) Hash(id string) (uint64, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	hash, ok := idx.hashes[id]
+	return hash, ok
+}
+
+// Search returns every indexed media file within maxDistance of hash
+func (idx *HashIndex) Search(hash uint64, maxDistance int) []HashMatch {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var matches []HashMatch
+	if idx.root == nil {
+		return matches
+	}
+
+	stack := []*bkNode{idx.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		d := hammingDistance(node.hash, hash)
+		if d <= maxDistance {
+			for _, id := range node.ids {
+				matches = append(matches, HashMatch{ID: id, Distance: d})
+			}
+		}
+
+		// By the triangle inequality only children at distance
+		// [d-maxDistance, d+maxDistance] from this node can match
+		for k := max(1, d-maxDistance); k <= d+maxDistance; k++ {
+			if child, ok := node.children[k]; ok {
+				stack = append(stack, child)
+			}
+		}
+	}
+	return matches
+}
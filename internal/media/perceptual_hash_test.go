@@ -0,0 +1,157 @@
+package media
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// gradientImage draws a diagonal gradient with a bright square, so its
+// dHash has both set and cleared bits
+func gradientImage(w, h int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8((x*255/w + y*128/h) / 2)
+			if x > w/3 && x < w/2 && y > h/4 && y < h/2 {
+				v = 255
+			}
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return img
+}
+
+func TestDifferenceHash(t *testing.T) {
+	original := differenceHash(gradientImage(640, 480))
+	if original == 0 || original == ^uint64(0) {
+		t.Fatalf("hash of a gradient = %016x, want mixed bits", original)
+	}
+
+	tests := []struct {
+		name        string
+		img         image.Image
+		maxDistance int
+	}{
+		{name: "same image", img: gradientImage(640, 480), maxDistance: 0},
+		{name: "downscaled copy", img: gradientImage(160, 120), maxDistance: 4},
+		{name: "upscaled copy", img: gradientImage(1920, 1440), maxDistance: 4},
+		{name: "offset bounds", img: gradientImage(640, 480).(*image.Gray).SubImage(image.Rect(0, 0, 640, 480)), maxDistance: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if d := hammingDistance(original, differenceHash(tt.img)); d > tt.maxDistance {
+				t.Errorf("distance = %d, want at most %d", d, tt.maxDistance)
+			}
+		})
+	}
+
+	if got := differenceHash(image.NewGray(image.Rect(0, 0, 0, 0))); got != 0 {
+		t.Errorf("hash of an empty image = %016x, want 0", got)
+	}
+}
+
+func TestFormatParseHash(t *testing.T) {
+	for _, hash := range []uint64{0, 1, 0xdeadbeefcafef00d, ^uint64(0)} {
+		s := formatHash(hash)
+		if len(s) != 16 {
+			t.Errorf("formatHash(%x) = %q, want 16 digits", hash, s)
+		}
+		if got, err := parseHash(s); err != nil || got != hash {
+			t.Errorf("parseHash(%q) = %x, %v, want %x", s, got, err, hash)
+		}
+	}
+}
+
+// bruteForce is the reference result of HashIndex.Search
+func bruteForce(hashes map[string]uint64, query uint64, maxDistance int) []HashMatch {
+	var matches []HashMatch
+	for id, hash := range hashes {
+		if d := hammingDistance(hash, query); d <= maxDistance {
+			matches = append(matches, HashMatch{ID: id, Distance: d})
+		}
+	}
+	return matches
+}
+
+func sortMatches(matches []HashMatch) []HashMatch {
+	sort.Slice(matches, func(i, j int) bool { return matches[i].ID < matches[j].ID })
+	return matches
+}
+
+func TestHashIndexMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	idx := NewHashIndex()
+	hashes := make(map[string]uint64)
+
+	// Clusters of near-duplicates around a few bases plus random hashes
+	bases := []uint64{rng.Uint64(), rng.Uint64(), rng.Uint64()}
+	for i := 0; i < 500; i++ {
+		hash := rng.Uint64()
+		if i%2 == 0 {
+			hash = bases[i%3]
+			for flips := rng.Intn(12); flips > 0; flips-- {
+				hash ^= 1 << rng.Intn(64)
+			}
+		}
+		id := formatHash(uint64(i))
+		hashes[id] = hash
+		idx.Add(id, hash)
+	}
+
+	tests := []struct {
+		name        string
+		query       uint64
+		maxDistance int
+	}{
+		{name: "exact", query: bases[0], maxDistance: 0},
+		{name: "default threshold", query: bases[1], maxDistance: DefaultSimilarityDistance},
+		{name: "max threshold", query: bases[2], maxDistance: MaxSimilarityDistance},
+		{name: "random query", query: rng.Uint64(), maxDistance: DefaultSimilarityDistance},
+		{name: "everything", query: 0, maxDistance: 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sortMatches(idx.Search(tt.query, tt.maxDistance))
+			want := sortMatches(bruteForce(hashes, tt.query, tt.maxDistance))
+			if !slices.Equal(got, want) {
+				t.Errorf("Search() found %d matches, want %d", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestHashIndexAddRemove(t *testing.T) {
+	idx := NewHashIndex()
+	idx.Add("a", 0b0000)
+	idx.Add("b", 0b0001)
+	idx.Add("c", 0b0011)
+	idx.Add("dup", 0b0011)
+
+	if got := sortMatches(idx.Search(0b0011, 0)); !slices.Equal(got, []HashMatch{{ID: "c"}, {ID: "dup"}}) {
+		t.Errorf("identical hashes = %v, want c and dup", got)
+	}
+
+	// Re-adding replaces the previous hash
+	idx.Add("a", 0b1111)
+	if got := idx.Search(0b0000, 0); len(got) != 0 {
+		t.Errorf("old hash of a still matches: %v", got)
+	}
+	if hash, ok := idx.Hash("a"); !ok || hash != 0b1111 {
+		t.Errorf("Hash(a) = %b, %v, want 1111", hash, ok)
+	}
+
+	// Removing most entries rebuilds the tree without losing the rest
+	for _, id := range []string{"a", "b", "c", "missing"} {
+		idx.Remove(id)
+	}
+	if got := idx.Search(0, 64); !slices.Equal(got, []HashMatch{{ID: "dup", Distance: 2}}) {
+		t.Errorf("after removals Search() = %v, want only dup", got)
+	}
+	if idx.emptied > idx.size/2 {
+		t.Errorf("tree not rebuilt: %d of %d nodes emptied", idx.emptied, idx.size)
+	}
+}
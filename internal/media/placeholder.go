@@ -0,0 +1,246 @@
+package media
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// placeholderSampleSize is the longest side of the thumbnail that colors
+	// and blurhashes are computed from
+	placeholderSampleSize = 32
+
+	// maxDominantColors is the number of dominant colors stored per image
+	maxDominantColors = 5
+
+	// minDominantShare drops colors that cover less than this fraction of
+	// the image
+	minDominantShare = 0.05
+)
+
+// imagePlaceholder holds the values clients use to render an image before it loads
+type imagePlaceholder struct {
+	AverageColor   string
+	DominantColors []string
+	BlurHash       string
+}
+
+// computePlaceholder derives the average color, dominant colors and a
+// BlurHash from a decoded image
+func computePlaceholder(img image.Image) imagePlaceholder {
+	thumb := sampleImage(img, placeholderSampleSize)
+	if len(thumb.pix) == 0 {
+		return imagePlaceholder{}
+	}
+
+	xComponents, yComponents := 4, 3
+	if thumb.height > thumb.width {
+		xComponents, yComponents = 3, 4
+	}
+
+	return imagePlaceholder{
+		AverageColor:   averageColor(thumb),
+		DominantColors: dominantColors(thumb, maxDominantColors),
+		BlurHash:       encodeBlurHash(thumb, xComponents, yComponents),
+	}
+}
+
+// rgb is an 8-bit sRGB color
+type rgb struct{ r, g, b uint8 }
+
+func (c rgb) hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.r, c.g, c.b)
+}
+
+// sampledImage is a small row-major grid of averaged pixels
+type sampledImage struct {
+	width, height int
+	pix           []rgb
+}
+
+// sampleImage box-filters img down so its longest side is at most maxSide.
+// Alpha is ignored: transparent pixels contribute their color channels as-is.
+func sampleImage(img image.Image, maxSide int) sampledImage {
+	b := img.Bounds()
+	if b.Empty() {
+		return sampledImage{}
+	}
+
+	w, h := b.Dx(), b.Dy()
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			w, h = maxSide, max(1, h*maxSide/b.Dx())
+		} else {
+			w, h = max(1, w*maxSide/b.Dy()), maxSide
+		}
+	}
+
+	sums := make([][3]uint64, w*h)
+	counts := make([]uint64, w*h)
+
+	// Very large images are sampled on a grid to bound the cost
+	stepX := max(1, b.Dx()/(w*8))
+	stepY := max(1, b.Dy()/(h*8))
+	for y := b.Min.Y; y < b.Max.Y; y += stepY {
+		cy := (y - b.Min.Y) * h / b.Dy()
+		for x := b.Min.X; x < b.Max.X; x += stepX {
+			cx := (x - b.Min.X) * w / b.Dx()
+			r, g, bl, _ := img.At(x, y).RGBA()
+			i := cy*w + cx
+			sums[i][0] += uint64(r >> 8)
+			sums[i][1] += uint64(g >> 8)
+			sums[i][2] += uint64(bl >> 8)
+			counts[i]++
+		}
+	}
+
+	pix := make([]rgb, w*h)
+	for i := range pix {
+		n := max(1, counts[i])
+		pix[i] = rgb{uint8(sums[i][0] / n), uint8(sums[i][1] / n), uint8(sums[i][2] / n)}
+	}
+	return sampledImage{width: w, height: h, pix: pix}
+}
+
+func averageColor(img sampledImage) string {
+	var r, g, b uint64
+	for _, p := range img.pix {
+		r += uint64(p.r)
+		g += uint64(p.g)
+		b += uint64(p.b)
+	}
+	n := uint64(len(img.pix))
+	return rgb{uint8(r / n), uint8(g / n), uint8(b / n)}.hex()
+}
+
+// dominantColors buckets pixels into a 4-bit-per-channel palette and returns
+// the mean color of the most populated buckets, most common first
+func dominantColors(img sampledImage, limit int) []string {
+	type bucket struct {
+		r, g, b, count int
+	}
+	buckets := make(map[int]*bucket)
+	for _, p := range img.pix {
+		key := int(p.r>>4)<<8 | int(p.g>>4)<<4 | int(p.b>>4)
+		bk, ok := buckets[key]
+		if !ok {
+			bk = &bucket{}
+			buckets[key] = bk
+		}
+		bk.r += int(p.r)
+		bk.g += int(p.g)
+		bk.b += int(p.b)
+		bk.count++
+	}
+
+	sorted := make([]*bucket, 0, len(buckets))
+	for _, bk := range buckets {
+		sorted = append(sorted, bk)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].count > sorted[j].count
+	})
+
+	colors := make([]string, 0, limit)
+	for _, bk := range sorted {
+		if len(colors) == limit {
+			break
+		}
+		if len(colors) > 0 && float64(bk.count)/float64(len(img.pix)) < minDominantShare {
+			break
+		}
+		colors = append(colors, rgb{uint8(bk.r / bk.count), uint8(bk.g / bk.count), uint8(bk.b / bk.count)}.hex())
+	}
+	return colors
+}
+
+// encodeBlurHash implements the BlurHash algorithm (https://blurha.sh): the
+// image is approximated by a few DCT components that are quantised into a
+// short base83 string
+func encodeBlurHash(img sampledImage, xComponents, yComponents int) string {
+	factors := make([][3]float64, xComponents*yComponents)
+	w, h := float64(img.width), float64(img.height)
+
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1.0
+			}
+
+			var f [3]float64
+			for y := 0; y < img.height; y++ {
+				for x := 0; x < img.width; x++ {
+					basis := math.Cos(math.Pi*float64(i)*float64(x)/w) * math.Cos(math.Pi*float64(j)*float64(y)/h)
+					p := img.pix[y*img.width+x]
+					f[0] += basis * srgbToLinear(p.r)
+					f[1] += basis * srgbToLinear(p.g)
+					f[2] += basis * srgbToLinear(p.b)
+				}
+			}
+
+			scale := normalisation / (w * h)
+			factors[j*xComponents+i] = [3]float64{f[0] * scale, f[1] * scale, f[2] * scale}
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(encodeBase83((xComponents-1)+(yComponents-1)*9, 1))
+
+	dc, ac := factors[0], factors[1:]
+	maximumValue := 1.0
+	if len(ac) > 0 {
+		actualMax := 0.0
+		for _, f := range ac {
+			actualMax = math.Max(actualMax, math.Max(math.Abs(f[0]), math.Max(math.Abs(f[1]), math.Abs(f[2]))))
+		}
+		quantisedMax := int(math.Max(0, math.Min(82, math.Floor(actualMax*166-0.5))))
+		maximumValue = float64(quantisedMax+1) / 166
+		sb.WriteString(encodeBase83(quantisedMax, 1))
+	} else {
+		sb.WriteString(encodeBase83(0, 1))
+	}
+
+	sb.WriteString(encodeBase83(linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4))
+	for _, f := range ac {
+		quant := func(v float64) int {
+			return int(math.Max(0, math.Min(18, math.Floor(signPow(v/maximumValue, 0.5)*9+9.5))))
+		}
+		sb.WriteString(encodeBase83(quant(f[0])*19*19+quant(f[1])*19+quant(f[2]), 2))
+	}
+	return sb.String()
+}
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+func encodeBase83(value, length int) string {
+	out := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		out[i] = base83Chars[value%83]
+		value /= 83
+	}
+	return string(out)
+}
+
+func srgbToLinear(v uint8) float64 {
+	f := float64(v) / 255
+	if f <= 0.04045 {
+		return f / 12.92
+	}
+	return math.Pow((f+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) int {
+	v = math.Max(0, math.Min(1, v))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(v, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(v), exp), v)
+}
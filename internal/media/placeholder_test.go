@@ -0,0 +1,215 @@
+package media
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func filledImage(w, h int, fill func(x, y int) color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, fill(x, y))
+		}
+	}
+	return img
+}
+
+func solid(c color.Color) func(x, y int) color.Color {
+	return func(x, y int) color.Color { return c }
+}
+
+func decodeBase83(s string) int {
+	value := 0
+	for _, c := range s {
+		value = value*83 + strings.IndexRune(base83Chars, c)
+	}
+	return value
+}
+
+func TestComputePlaceholder(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+
+	tests := []struct {
+		name         string
+		img          image.Image
+		wantAverage  string
+		wantDominant []string
+		wantSize     [2]int // BlurHash x and y components
+	}{
+		{
+			name:         "solid landscape",
+			img:          filledImage(64, 48, solid(red)),
+			wantAverage:  "#ff0000",
+			wantDominant: []string{"#ff0000"},
+			wantSize:     [2]int{4, 3},
+		},
+		{
+			name: "three quarters red portrait",
+			img: filledImage(40, 80, func(x, y int) color.Color {
+				if y < 60 {
+					return red
+				}
+				return blue
+			}),
+			wantAverage:  "#bf003f",
+			wantDominant: []string{"#ff0000", "#0000ff"},
+			wantSize:     [2]int{3, 4},
+		},
+		{
+			name: "noise below the dominant share is dropped",
+			img: filledImage(100, 100, func(x, y int) color.Color {
+				if x == 0 && y == 0 {
+					return blue
+				}
+				return red
+			}),
+			wantAverage:  "#fe0000", // the blue pixel is averaged in
+			wantDominant: []string{"#ff0000"},
+			wantSize:     [2]int{4, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computePlaceholder(tt.img)
+			if got.AverageColor != tt.wantAverage {
+				t.Errorf("AverageColor = %s, want %s", got.AverageColor, tt.wantAverage)
+			}
+			if !slices.Equal(got.DominantColors, tt.wantDominant) {
+				t.Errorf("DominantColors = %v, want %v", got.DominantColors, tt.wantDominant)
+			}
+
+			// Size flag, quantised maximum, DC and two characters per AC component
+			x, y := tt.wantSize[0], tt.wantSize[1]
+			if want := 1 + 1 + 4 + 2*(x*y-1); len(got.BlurHash) != want {
+				t.Fatalf("BlurHash %q has length %d, want %d", got.BlurHash, len(got.BlurHash), want)
+			}
+			if flag := decodeBase83(got.BlurHash[:1]); flag != (x-1)+(y-1)*9 {
+				t.Errorf("size flag = %d, want %d", flag, (x-1)+(y-1)*9)
+			}
+		})
+	}
+}
+
+// decodeBlurHash renders a BlurHash at w x h following the reference
+// decoder, so the encoder is checked against the specification rather
+// than against itself
+func decodeBlurHash(t *testing.T, hash string, w, h int) []rgb {
+	t.Helper()
+	flag := decodeBase83(hash[:1])
+	nx, ny := flag%9+1, flag/9+1
+	if len(hash) != 4+2*nx*ny {
+		t.Fatalf("BlurHash %q has length %d, want %d", hash, len(hash), 4+2*nx*ny)
+	}
+	maximum := float64(decodeBase83(hash[1:2])+1) / 166
+
+	colors := make([][3]float64, nx*ny)
+	dc := decodeBase83(hash[2:6])
+	colors[0] = [3]float64{srgbToLinear(uint8(dc >> 16)), srgbToLinear(uint8(dc >> 8)), srgbToLinear(uint8(dc))}
+	for i := 1; i < nx*ny; i++ {
+		ac := decodeBase83(hash[4+2*i : 6+2*i])
+		unquant := func(q int) float64 { return signPow((float64(q)-9)/9, 2) * maximum }
+		colors[i] = [3]float64{unquant(ac / (19 * 19)), unquant(ac / 19 % 19), unquant(ac % 19)}
+	}
+
+	pix := make([]rgb, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var c [3]float64
+			for j := 0; j < ny; j++ {
+				for i := 0; i < nx; i++ {
+					basis := math.Cos(math.Pi*float64(x*i)/float64(w)) * math.Cos(math.Pi*float64(y*j)/float64(h))
+					for k := range c {
+						c[k] += colors[j*nx+i][k] * basis
+					}
+				}
+			}
+			pix[y*w+x] = rgb{uint8(linearToSRGB(c[0])), uint8(linearToSRGB(c[1])), uint8(linearToSRGB(c[2]))}
+		}
+	}
+	return pix
+}
+
+func TestBlurHashDecodesToSource(t *testing.T) {
+	const size = 32
+	tests := []struct {
+		name string
+		fill func(x, y int) color.Color
+	}{
+		{name: "solid", fill: solid(color.RGBA{R: 12, G: 200, B: 99, A: 255})},
+		{name: "horizontal gradient", fill: func(x, y int) color.Color {
+			v := uint8(x * 255 / (size - 1))
+			return color.RGBA{R: v, G: v, B: v, A: 255}
+		}},
+		{name: "vertical split", fill: func(x, y int) color.Color {
+			if y < size/2 {
+				return color.RGBA{R: 230, G: 40, B: 40, A: 255}
+			}
+			return color.RGBA{R: 30, G: 60, B: 220, A: 255}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := filledImage(size, size, tt.fill)
+			decoded := decodeBlurHash(t, computePlaceholder(img).BlurHash, size, size)
+
+			// Compare block averages in the top and bottom rows; a handful
+			// of components cannot reproduce single pixels or sharp edges
+			const block = size / 4
+			for _, by := range []int{0, 3} {
+				for bx := 0; bx < 4; bx++ {
+					var want, got [3]float64
+					for y := by * block; y < (by+1)*block; y++ {
+						for x := bx * block; x < (bx+1)*block; x++ {
+							r, g, b, _ := img.At(x, y).RGBA()
+							want[0], want[1], want[2] = want[0]+float64(r>>8), want[1]+float64(g>>8), want[2]+float64(b>>8)
+							p := decoded[y*size+x]
+							got[0], got[1], got[2] = got[0]+float64(p.r), got[1]+float64(p.g), got[2]+float64(p.b)
+						}
+					}
+					for k := range want {
+						if diff := math.Abs(want[k]-got[k]) / (block * block); diff > 40 {
+							t.Errorf("block (%d,%d) channel %d differs by %.0f", bx, by, k, diff)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLinearSRGBRoundTrip(t *testing.T) {
+	for v := 0; v <= 255; v++ {
+		if got := linearToSRGB(srgbToLinear(uint8(v))); got != v {
+			t.Errorf("linearToSRGB(srgbToLinear(%d)) = %d", v, got)
+		}
+	}
+}
+
+func TestSampleImage(t *testing.T) {
+	tests := []struct {
+		w, h         int
+		wantW, wantH int
+	}{
+		{w: 10, h: 5, wantW: 10, wantH: 5},
+		{w: 640, h: 480, wantW: 32, wantH: 24},
+		{w: 480, h: 640, wantW: 24, wantH: 32},
+		{w: 1000, h: 10, wantW: 32, wantH: 1},
+	}
+	for _, tt := range tests {
+		got := sampleImage(filledImage(tt.w, tt.h, solid(color.White)), placeholderSampleSize)
+		if got.width != tt.wantW || got.height != tt.wantH {
+			t.Errorf("sampleImage(%dx%d) = %dx%d, want %dx%d", tt.w, tt.h, got.width, got.height, tt.wantW, tt.wantH)
+		}
+	}
+	if got := computePlaceholder(image.NewRGBA(image.Rect(0, 0, 0, 0))); got.BlurHash != "" {
+		t.Errorf("placeholder of an empty image = %+v, want none", got)
+	}
+}
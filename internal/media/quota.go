@@ -0,0 +1,53 @@
+package media
+
+import (
+	"fmt"
+	"sync"
+
+	"example.com/myapp/internal/config"
+	appErr "example.com/myapp/internal/errors"
+)
+
+// storageQuota counts the stored media files and their bytes so uploads
+// can be checked against media.storage_quota and media.max_files. Uploads
+// reserve their size before the file is written, so concurrent uploads
+// cannot exceed the quota together. The limits are passed on every call,
+// so a reload applies to the next upload; lowering a limit below the
+// current usage only stops further uploads.
+type storageQuota struct {
+	mu    sync.Mutex
+	files int
+	bytes int64
+}
+
+// reserve counts a file of n bytes against the limits in cfg. The returned
+// function undoes the reservation for uploads that are not saved.
+func (q *storageQuota) reserve(cfg config.MediaConfig, n int64) (func(), error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if cfg.MaxFiles > 0 && q.files+1 > cfg.MaxFiles {
+		return nil, appErr.QuotaExceeded(fmt.Sprintf("the limit of %d stored files has been reached", cfg.MaxFiles))
+	}
+	if cfg.StorageQuota > 0 && q.bytes+n > cfg.StorageQuota {
+		return nil, appErr.QuotaExceeded(fmt.Sprintf("storing %.2f MB would exceed the storage quota of %g MB (%.2f MB in use)",
+			float64(n)/(1024*1024), float64(cfg.StorageQuota)/(1024*1024), float64(q.bytes)/(1024*1024)))
+	}
+	q.add(n)
+	return func() { q.remove(n) }, nil
+}
+
+// add counts a stored file of n bytes. Callers hold q.mu, or have q to
+// themselves while the service is created.
+func (q *storageQuota) add(n int64) {
+	q.files++
+	q.bytes += n
+}
+
+// remove stops counting a deleted file of n bytes
+func (q *storageQuota) remove(n int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.files--
+	q.bytes -= n
+}
@@ -0,0 +1,46 @@
+package media
+
+import (
+	"testing"
+
+	"example.com/myapp/internal/config"
+	appErr "example.com/myapp/internal/errors"
+)
+
+func TestStorageQuota(t *testing.T) {
+	var q storageQuota
+	limits := config.MediaConfig{StorageQuota: 100, MaxFiles: 3}
+
+	reserve := func(cfg config.MediaConfig, n int64, wantOK bool) func() {
+		t.Helper()
+		release, err := q.reserve(cfg, n)
+		if wantOK != (err == nil) {
+			t.Fatalf("reserve(%d) with %d files, %d bytes: error = %v, want ok %v", n, q.files, q.bytes, err, wantOK)
+		}
+		if err != nil && !appErr.HasCode(err, appErr.ErrCodeQuotaExceeded) {
+			t.Fatalf("reserve(%d) error = %v, want %s", n, err, appErr.ErrCodeQuotaExceeded)
+		}
+		return release
+	}
+
+	reserve(limits, 60, true)
+	release := reserve(limits, 40, true)
+	reserve(limits, 1, false)
+
+	// An upload that is not saved gives its reservation back
+	release()
+	reserve(limits, 40, true)
+
+	// Deleting a file frees its bytes but the file limit still applies
+	q.remove(40)
+	reserve(limits, 20, true)
+	reserve(limits, 20, true)
+	reserve(limits, 1, false)
+
+	// Reloaded limits apply to the next upload; zero is unlimited
+	reserve(config.MediaConfig{StorageQuota: 50}, 1, false)
+	reserve(config.MediaConfig{}, 1000, true)
+	if q.files != 4 || q.bytes != 1100 {
+		t.Errorf("usage = %d files, %d bytes; want 4 files, 1100 bytes", q.files, q.bytes)
+	}
+}
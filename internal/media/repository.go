@@ -2,10 +2,20 @@ package media
 
 import "context"
 
-// Repository defines the interface for media persistence
+// Repository defines the interface for media persistence.
+// Media are returned as copies. Writes are optimistic: Save of existing
+// media and Delete fail with a PreconditionFailed AppError when the given
+// version is no longer the stored one.
+//
+// Write methods take the types of the events describing the change. They
+// are staged in the outbox together with the write, so an event exists if
+// and only if the write happened; the event data is the media after the
+// write, or before it for Delete.
 type Repository interface {
-	Save(ctx context.Context, media *Media) error
+	Save(ctx context.Context, media *Media, eventTypes ...string) error
 	GetByID(ctx context.Context, id string) (*Media, error)
 	GetAll(ctx context.Context) ([]*Media, error)
-	Delete(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string, version int, eventTypes ...string) error
+	// Ping reports whether the underlying store can serve requests
+	Ping(ctx context.Context) error
 }
@@ -0,0 +1,241 @@
+package media
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Field weights used when scoring search matches. Tags are assigned
+// deliberately by users so they count the most.
+const (
+	weightTag      = 5.0
+	weightName     = 3.0
+	weightType     = 1.0
+	weightMetadata = 1.0
+
+	// prefixMatchFactor scales the score of a query term that only matches
+	// the beginning of an indexed term
+	prefixMatchFactor = 0.5
+)
+
+// SearchQuery describes a media search
+type SearchQuery struct {
+	Text string
+	Tags []string
+	Type string
+}
+
+// SearchHit is a single ranked search result
+type SearchHit struct {
+	ID    string
+	Score float64
+}
+
+// SearchIndex is an in-memory inverted index over media metadata
+type SearchIndex struct {
+	mu       sync.RWMutex
+	postings map[string]map[string]float64 // term -> media ID -> weight
+	docs     map[string]*indexedDoc
+}
+
+type indexedDoc struct {
+	terms      []string
+	tags       map[string]bool
+	mediaType  string
+	uploadedAt int64
+}
+
+// NewSearchIndex creates an empty search index
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{
+		postings: make(map[string]map[string]float64),
+		docs:     make(map[string]*indexedDoc),
+	}
+}
+
+// Index adds or replaces the entry for a media record
+func (idx *SearchIndex) Index(m *Media) {
+	weights := make(map[string]float64)
+	for _, t := range tokenize(m.OriginalName) {
+		weights[t] += weightName
+	}
+	for _, tag := range m.Tags {
+		weights[tag] += weightTag
+		for _, t := range tokenize(tag) {
+			if t != tag {
+				weights[t] += weightTag / 2
+			}
+		}
+	}
+	for _, key := range []string{"title", "artist", "album"} {
+		for _, t := range tokenize(m.AudioTags[key]) {
+			weights[t] += weightName
+		}
+	}
+	weights[m.Type] += weightType
+	for _, t := range metadataTerms(m) {
+		weights[t] += weightMetadata
+	}
+
+	doc := &indexedDoc{
+		terms:      make([]string, 0, len(weights)),
+		tags:       make(map[string]bool, len(m.Tags)),
+		mediaType:  m.Type,
+		uploadedAt: m.UploadedAt.UnixNano(),
+	}
+	for _, tag := range m.Tags {
+		doc.tags[tag] = true
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(m.ID)
+	for term, w := range weights {
+		if term == "" {
+			continue
+		}
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[string]float64)
+		}
+		idx.postings[term][m.ID] = w
+		doc.terms = append(doc.terms, term)
+	}
+	idx.docs[m.ID] = doc
+}
+
+// Remove drops a media record from the index
+func (idx *SearchIndex) Remove(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(id)
+}
+
+func (idx *SearchIndex) removeLocked(id string) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range doc.terms {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.docs, id)
+}
+
+// Search returns the IDs of matching media ordered by relevance. Every query
+// term must match; ties and queries without text are ordered newest first.
+func (idx *SearchIndex) Search(q SearchQuery) []SearchHit {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	terms := tokenize(q.Text)
+	scores := make(map[string]float64)
+
+	if len(terms) == 0 {
+		for id := range idx.docs {
+			scores[id] = 0
+		}
+	} else {
+		for i, term := range terms {
+			termScores := idx.scoreTerm(term)
+			if i == 0 {
+				scores = termScores
+				continue
+			}
+			for id := range scores {
+				s, ok := termScores[id]
+				if !ok {
+					delete(scores, id)
+					continue
+				}
+				scores[id] += s
+			}
+		}
+	}
+
+	hits := make([]SearchHit, 0, len(scores))
+	for id, score := range scores {
+		doc := idx.docs[id]
+		if q.Type != "" && doc.mediaType != q.Type {
+			continue
+		}
+		if !hasAllTags(doc, q.Tags) {
+			continue
+		}
+		hits = append(hits, SearchHit{ID: id, Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return idx.docs[hits[i].ID].uploadedAt > idx.docs[hits[j].ID].uploadedAt
+	})
+	return hits
+}
+
+// scoreTerm scores every document containing term. Exact matches use the
+// indexed weight; documents that only contain a term starting with it get
+// their best prefix match at a reduced weight.
+func (idx *SearchIndex) scoreTerm(term string) map[string]float64 {
+	exact := idx.postings[term]
+	scores := make(map[string]float64, len(exact))
+	for id, w := range exact {
+		scores[id] = w
+	}
+
+	for indexed, docs := range idx.postings {
+		if indexed == term || !strings.HasPrefix(indexed, term) {
+			continue
+		}
+		for id, w := range docs {
+			if _, ok := exact[id]; ok {
+				continue
+			}
+			if s := w * prefixMatchFactor; s > scores[id] {
+				scores[id] = s
+			}
+		}
+	}
+	return scores
+}
+
+func hasAllTags(doc *indexedDoc, tags []string) bool {
+	for _, tag := range tags {
+		if !doc.tags[tag] {
+			return false
+		}
+	}
+	return true
+}
+
+// metadataTerms returns searchable terms derived from extracted metadata
+func metadataTerms(m *Media) []string {
+	terms := []string{m.Format, m.VideoCodec, m.AudioCodec}
+	if m.Width > 0 && m.Height > 0 {
+		terms = append(terms, strconv.Itoa(m.Width)+"x"+strconv.Itoa(m.Height))
+		switch {
+		case m.Width > m.Height:
+			terms = append(terms, "landscape")
+		case m.Width < m.Height:
+			terms = append(terms, "portrait")
+		default:
+			terms = append(terms, "square")
+		}
+	}
+	return terms
+}
+
+// tokenize lowercases text and splits it on anything that is not a letter
+// or digit
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,121 @@
+package media
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestSearchIndex() *SearchIndex {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	idx := NewSearchIndex()
+	for i, m := range []*Media{
+		{ID: "beach", OriginalName: "Beach-Sunset.jpg", Type: "image", Format: "webp", Width: 1600, Height: 900, Tags: []string{"summer", "holiday"}},
+		{ID: "tagged", OriginalName: "IMG_0001.jpg", Type: "image", Format: "webp", Width: 900, Height: 1600, Tags: []string{"beach"}},
+		{ID: "clip", OriginalName: "beach clip.mp4", Type: "video", Format: "mp4", VideoCodec: "h264"},
+		{ID: "song", OriginalName: "track01.mp3", Type: "audio", Format: "mp3", AudioTags: map[string]string{"title": "Summer Nights", "artist": "The Band"}},
+	} {
+		m.UploadedAt = base.Add(time.Duration(i) * time.Hour)
+		idx.Index(m)
+	}
+	return idx
+}
+
+func hitIDs(hits []SearchHit) []string {
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	return ids
+}
+
+func TestSearchIndexSearch(t *testing.T) {
+	tests := []struct {
+		name  string
+		query SearchQuery
+		want  []string
+	}{
+		{name: "tag outranks name", query: SearchQuery{Text: "beach"}, want: []string{"tagged", "clip", "beach"}},
+		{name: "case insensitive", query: SearchQuery{Text: "BEACH"}, want: []string{"tagged", "clip", "beach"}},
+		{name: "every term must match", query: SearchQuery{Text: "beach sunset"}, want: []string{"beach"}},
+		{name: "prefix match", query: SearchQuery{Text: "sun"}, want: []string{"beach"}},
+		{name: "audio tags", query: SearchQuery{Text: "nights"}, want: []string{"song"}},
+		{name: "metadata terms", query: SearchQuery{Text: "portrait"}, want: []string{"tagged"}},
+		{name: "codec", query: SearchQuery{Text: "h264"}, want: []string{"clip"}},
+		{name: "type filter", query: SearchQuery{Text: "beach", Type: "video"}, want: []string{"clip"}},
+		{name: "tag filter", query: SearchQuery{Tags: []string{"summer", "holiday"}}, want: []string{"beach"}},
+		{name: "no text lists newest first", query: SearchQuery{Type: "image"}, want: []string{"tagged", "beach"}},
+		{name: "no match", query: SearchQuery{Text: "mountain"}, want: []string{}},
+	}
+
+	idx := newTestSearchIndex()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hitIDs(idx.Search(tt.query)); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%+v) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchIndexReindexAndRemove(t *testing.T) {
+	idx := newTestSearchIndex()
+
+	// Replacing the tags drops the old postings
+	idx.Index(&Media{ID: "tagged", OriginalName: "IMG_0001.jpg", Type: "image", Tags: []string{"mountain"}})
+	if got := hitIDs(idx.Search(SearchQuery{Text: "beach"})); !slices.Equal(got, []string{"clip", "beach"}) {
+		t.Errorf("after reindex, Search(beach) = %v, want [clip beach]", got)
+	}
+	if got := hitIDs(idx.Search(SearchQuery{Text: "mountain"})); !slices.Equal(got, []string{"tagged"}) {
+		t.Errorf("after reindex, Search(mountain) = %v, want [tagged]", got)
+	}
+
+	idx.Remove("tagged")
+	idx.Remove("unknown")
+	if got := hitIDs(idx.Search(SearchQuery{Text: "mountain"})); len(got) != 0 {
+		t.Errorf("after remove, Search(mountain) = %v, want none", got)
+	}
+	if len(idx.postings["mountain"]) != 0 {
+		t.Errorf("postings of a removed document were kept: %v", idx.postings["mountain"])
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "Beach-Sunset_2024.JPG", want: []string{"beach", "sunset", "2024", "jpg"}},
+		{text: "  ", want: []string{}},
+		{text: "Café Zürich", want: []string{"café", "zürich"}},
+	}
+	for _, tt := range tests {
+		if got := tokenize(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("tokenize(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "lowercases and deduplicates", tags: []string{"Summer", "summer ", "beach"}, want: []string{"summer", "beach"}},
+		{name: "skips blanks", tags: []string{"", " ", "a"}, want: []string{"a"}},
+		{name: "too long", tags: []string{string(make([]byte, MaxTagLength+1))}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeTags("tags", tt.tags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(got, tt.want) {
+				t.Errorf("normalizeTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package media
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"image"
+	"image/gif"
 	"image/jpeg"
 	"image/png"
 	"io"
@@ -11,127 +14,287 @@ import (
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
+	"example.com/myapp/internal/audit"
+	"example.com/myapp/internal/config"
 	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/etag"
+	"example.com/myapp/internal/events"
+	"example.com/myapp/internal/tracing"
+	"example.com/myapp/internal/validation"
 	"github.com/google/uuid"
 	"golang.org/x/image/webp"
 )
 
 const (
-	// MaxFileSize is 200 MB in bytes
-	MaxFileSize = 200 * 1024 * 1024
+	// MaxTags is the maximum number of tags on a single media file
+	MaxTags = 32
 
-	// MediaStoragePath is the directory where media files are stored
-	MediaStoragePath = "./uploads"
+	// MaxTagLength is the maximum length of a single tag in bytes
+	MaxTagLength = 64
 
-	// ImageQuality is the JPEG quality for optimized images (0-100)
-	ImageQuality = 85
+	// DefaultSearchPageSize and MaxSearchPageSize bound search pagination
+	DefaultSearchPageSize = 20
+	MaxSearchPageSize     = 100
+
+	// PosterExtractionTimeout bounds how long a transcoder may take to
+	// produce a poster frame
+	PosterExtractionTimeout = 30 * time.Second
+
+	// WaveformSampleRate is the rate compressed audio is decoded at for
+	// waveform generation; peaks do not need full fidelity
+	WaveformSampleRate = 8000
 )
 
 // SupportedImageFormats are the image formats we accept and convert to
 var SupportedImageFormats = []string{"image/jpeg", "image/png", "image/webp", "image/gif"}
-var SupportedFormats = append(SupportedImageFormats, "application/pdf")
+
+// SupportedVideoFormats are the video containers we accept and store as-is
+var SupportedVideoFormats = []string{"video/mp4", "video/webm"}
+
+// SupportedAudioFormats are the audio formats we accept and store as-is,
+// including common aliases sent by browsers
+var SupportedAudioFormats = []string{
+	"audio/mpeg", "audio/mp3",
+	"audio/ogg", "audio/opus",
+	"audio/wav", "audio/x-wav", "audio/vnd.wave",
+	"audio/flac", "audio/x-flac",
+}
+
+var SupportedFormats = append(append(append([]string{"application/pdf"}, SupportedImageFormats...), SupportedVideoFormats...), SupportedAudioFormats...)
 
 type Service struct {
-	repo Repository
+	repo       Repository
+	index      *SearchIndex
+	hashIndex  *HashIndex
+	transcoder Transcoder
+	audit      audit.Recorder
+	settings   *config.Store
+	// decodeLimiter bounds the memory of images decoded concurrently
+	decodeLimiter *decodeLimiter
+	// quota counts stored files against the storage quota
+	quota storageQuota
 }
 
-func NewService(repo Repository) *Service {
+// NewService creates the media service. transcoder may be nil, in which case
+// video poster frames are not extracted. Limits, quotas, allowed formats and
+// image quality are read from settings on every upload so reloads apply at
+// once.
+func NewService(repo Repository, transcoder Transcoder, auditRecorder audit.Recorder, settings *config.Store) *Service {
 	// Create uploads directory if it doesn't exist
-	os.MkdirAll(MediaStoragePath, 0755)
+	os.MkdirAll(settings.Current().Media.StoragePath, 0755)
 
-	return &Service{
-		repo: repo,
+	// Build the search indexes from any media already in the repository
+	index := NewSearchIndex()
+	existing, err := repo.GetAll(context.Background())
+	if err != nil {
+		slog.Error("Failed to load media for search index", "error", err)
+	}
+	s := &Service{
+		repo:       repo,
+		index:      index,
+		hashIndex:  NewHashIndex(),
+		transcoder: transcoder,
+		audit:      auditRecorder,
+		settings:   settings,
+		decodeLimiter: newDecodeLimiter(
+			settings.Current().Media.DecodeMemory,
+			settings.Current().Media.DecodeQueueSize,
+		),
+	}
+	for _, m := range existing {
+		s.index.Index(m)
+		s.indexHash(m)
+		s.quota.add(m.SizeBytes)
 	}
+	return s
 }
 
 // UploadMedia uploads and processes a media file
-func (s *Service) UploadMedia(ctx context.Context, file *multipart.FileHeader) (*Media, error) {
+func (s *Service) UploadMedia(ctx context.Context, file *multipart.FileHeader, tags []string) (*Media, error) {
+	ctx, span := tracing.Start(ctx, "media.UploadMedia", tracing.WithAttributes(tracing.Attr("media.upload_bytes", file.Size)))
+	defer span.End()
+
+	media, err := s.uploadMedia(ctx, file, tags)
+	span.SetError(err)
+	return media, err
+}
+
+// uploadMedia validates, processes, stores and saves an upload. Each stage
+// (read, decode, encode, store, save) is traced as a child span.
+func (s *Service) uploadMedia(ctx context.Context, file *multipart.FileHeader, tags []string) (*Media, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, appErr.Internal("context cancelled", err)
 	}
 
+	tags, err := normalizeTags("tags", tags)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use one configuration snapshot for the whole upload
+	cfg := s.settings.Current().Media
+
 	// Validate file size
-	if file.Size > MaxFileSize {
-		return nil, appErr.FileTooLarge(fmt.Sprintf("file size exceeds maximum limit of 200 MB (file size: %.2f MB)", float64(file.Size)/(1024*1024)))
+	if file.Size > cfg.MaxFileSize {
+		return nil, appErr.FileTooLarge(fmt.Sprintf("file size exceeds maximum limit of %g MB (file size: %.2f MB)", float64(cfg.MaxFileSize)/(1024*1024), float64(file.Size)/(1024*1024)))
 	}
 
 	// Open the file
 	src, err := file.Open()
 	if err != nil {
-		slog.Error("Failed to open file", "error", err)
+		slog.ErrorContext(ctx, "Failed to open file", "error", err)
 		return nil, appErr.Internal("failed to open file", err)
 	}
 	defer src.Close()
 
 	// Detect content type
 	contentType := file.Header.Get("Content-Type")
-	if contentType == "" {
-		// Fallback: try to determine from filename
-		contentType = getContentTypeFromName(file.Filename)
+	if contentType == "" || contentType == "application/octet-stream" {
+		// Fallback: sniff the leading bytes, then try the filename
+		contentType = sniffFile(src)
+		if contentType == "" {
+			contentType = getContentTypeFromName(file.Filename)
+		}
+	} else if isAudioType(contentType) {
+		// Browsers label audio inconsistently, so trust the file signature.
+		// This happens before the allow list is checked so the sniffed type
+		// is the one media.allowed_formats must accept.
+		if sniffed := sniffFile(src); isAudioType(sniffed) {
+			contentType = sniffed
+		}
 	}
 
 	// Validate content type
 	if !isValidContentType(contentType) {
-		return nil, appErr.UnsupportedType("unsupported file type: " + contentType + ". Supported types: JPEG, PNG, WebP, GIF, PDF")
+		return nil, appErr.UnsupportedType("unsupported file type: " + contentType + ". Supported types: JPEG, PNG, WebP, GIF, PDF, MP4, WebM, MP3, OGG, WAV, FLAC")
+	}
+	if !isAllowedContentType(cfg.AllowedFormats, contentType) {
+		return nil, appErr.UnsupportedType("file type " + contentType + " is not accepted. Accepted types: " + strings.Join(cfg.AllowedFormats, ", "))
 	}
 
 	// Determine media type and format
 	var mediaType, format string
 	var fileBytes []byte
 	var imgDims image.Rectangle
+	var perceptualHash string
+	var placeholder imagePlaceholder
+	var videoInfo *VideoInfo
+	var audioInfo *AudioInfo
 
 	if isImageType(contentType) {
 		mediaType = "image"
 		// Read file content
-		fileBytes, err = io.ReadAll(src)
+		fileBytes, err = readUpload(ctx, src)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read file: %w", err)
 		}
 
+		// Reserve memory for the decoded bitmap; it is held until the
+		// image has been analysed
+		release, err := s.reserveDecode(ctx, fileBytes, cfg)
+		if err != nil {
+			return nil, err
+		}
+
 		// Optimize image
-		optimizedBytes, dims, err := s.optimizeImage(fileBytes, contentType)
+		processingStart := time.Now()
+		optimizedBytes, img, err := s.optimizeImage(ctx, fileBytes, contentType, cfg.ImageQuality)
+		imageProcessingDuration.Observe(time.Since(processingStart).Seconds(), imageFormat(contentType))
 		if err != nil {
-			slog.Error("Failed to optimize image", "error", err)
+			release()
+			if errors.Is(err, errUndecodable) {
+				return nil, undecodableImage(ctx, err)
+			}
+			slog.ErrorContext(ctx, "Failed to optimize image", "error", err)
 			return nil, appErr.Internal("failed to optimize image", err)
 		}
 
 		fileBytes = optimizedBytes
-		imgDims = dims
+		imgDims = img.Bounds()
+		perceptualHash = formatHash(differenceHash(img))
+		placeholder = computePlaceholder(img)
+		release()
 		format = "webp" // Store as WebP for better compression
 	} else if isPDFType(contentType) {
 		mediaType = "pdf"
 		format = "pdf"
-		fileBytes, err = io.ReadAll(src)
+		fileBytes, err = readUpload(ctx, src)
 		if err != nil {
-			slog.Error("Failed to read file", "error", err)
+			slog.ErrorContext(ctx, "Failed to read file", "error", err)
 			return nil, appErr.Internal("failed to read file", err)
 		}
+	} else if isVideoType(contentType) {
+		mediaType = "video"
+		format = videoFormat(contentType)
+		fileBytes, err = readUpload(ctx, src)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to read file", "error", err)
+			return nil, appErr.Internal("failed to read file", err)
+		}
+
+		// Videos are stored as uploaded; only the container headers are parsed
+		videoInfo, err = probeVideo(bytes.NewReader(fileBytes), int64(len(fileBytes)), format)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to probe video", "error", err)
+			return nil, appErr.BadRequest("invalid " + format + " video: " + err.Error())
+		}
+	} else if isAudioType(contentType) {
+		mediaType = "audio"
+		fileBytes, err = readUpload(ctx, src)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to read file", "error", err)
+			return nil, appErr.Internal("failed to read file", err)
+		}
+		format = audioFormat(contentType)
+
+		audioInfo, err = probeAudio(fileBytes, format)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to probe audio", "error", err)
+			return nil, appErr.BadRequest("invalid " + format + " audio: " + err.Error())
+		}
 	}
 
 	// Generate unique filename
 	storedName := fmt.Sprintf("%s_%d.%s", uuid.New().String(), time.Now().UnixNano(), format)
-	filePath := filepath.Join(MediaStoragePath, storedName)
+	filePath := filepath.Join(cfg.StoragePath, storedName)
+
+	// Count the file against the storage quota until it is saved
+	releaseQuota, err := s.quota.reserve(cfg, int64(len(fileBytes)))
+	if err != nil {
+		return nil, err
+	}
 
 	// Save file to disk
+	_, storeSpan := tracing.Start(ctx, "media.store", tracing.WithAttributes(tracing.Attr("media.bytes", len(fileBytes))))
 	err = os.WriteFile(filePath, fileBytes, 0644)
+	storeSpan.SetError(err)
+	storeSpan.End()
 	if err != nil {
-		slog.Error("Failed to save file", "error", err)
+		releaseQuota()
+		slog.ErrorContext(ctx, "Failed to save file", "error", err)
 		return nil, appErr.Internal("failed to save file", err)
 	}
 
 	// Create media record
 	media := &Media{
-		ID:           uuid.New().String(),
-		OriginalName: file.Filename,
-		StoredName:   storedName,
-		Type:         mediaType,
-		Format:       format,
-		SizeBytes:    int64(len(fileBytes)),
-		FilePath:     filePath,
-		UploadedAt:   time.Now(),
+		ID:             uuid.New().String(),
+		OriginalName:   file.Filename,
+		StoredName:     storedName,
+		Type:           mediaType,
+		Format:         format,
+		SizeBytes:      int64(len(fileBytes)),
+		FilePath:       filePath,
+		UploadedAt:     time.Now(),
+		Tags:           tags,
+		PerceptualHash: perceptualHash,
+		AverageColor:   placeholder.AverageColor,
+		DominantColors: placeholder.DominantColors,
+		BlurHash:       placeholder.BlurHash,
 	}
 
 	// Add dimensions if it's an image
@@ -140,23 +303,59 @@ func (s *Service) UploadMedia(ctx context.Context, file *multipart.FileHeader) (
 		media.Height = imgDims.Max.Y
 	}
 
+	if videoInfo != nil {
+		media.Width = videoInfo.Width
+		media.Height = videoInfo.Height
+		media.DurationSeconds = videoInfo.DurationSeconds
+		media.VideoCodec = videoInfo.VideoCodec
+		media.AudioCodec = videoInfo.AudioCodec
+		s.attachPoster(ctx, media)
+	}
+
+	if audioInfo != nil {
+		media.DurationSeconds = audioInfo.DurationSeconds
+		media.AudioCodec = audioInfo.Codec
+		media.SampleRate = audioInfo.SampleRate
+		media.Channels = audioInfo.Channels
+		media.BitrateKbps = audioInfo.BitrateKbps
+		if len(audioInfo.Tags) > 0 {
+			media.AudioTags = audioInfo.Tags
+		}
+		media.Waveform = audioInfo.Waveform
+		if media.Waveform == nil {
+			s.attachWaveform(ctx, media)
+		}
+	}
+
 	// Store in repository
-	err = s.repo.Save(ctx, media)
+	saveCtx, saveSpan := tracing.Start(ctx, "media.save")
+	err = s.repo.Save(saveCtx, media, events.MediaUploaded)
+	saveSpan.SetError(err)
+	saveSpan.End()
 	if err != nil {
-		slog.Error("Failed to save media to repository", "error", err)
+		releaseQuota()
+		slog.ErrorContext(ctx, "Failed to save media to repository", "error", err)
 		return nil, appErr.Internal("failed to save media to repository", err)
 	}
+	s.index.Index(media)
+	s.indexHash(media)
+	s.audit.Record(ctx, audit.ActionMediaCreate, audit.TargetMedia, media.ID, nil, media)
+	uploadsTotal.Inc(mediaType)
+	uploadBytes.Add(float64(file.Size), mediaType)
 
 	return media, nil
 }
 
-// optimizeImage converts any image format to WebP with compression
-func (s *Service) optimizeImage(fileBytes []byte, contentType string) ([]byte, image.Rectangle, error) {
+// optimizeImage converts any image format to WebP with compression. The
+// decoded image is returned so callers can analyse it without decoding twice.
+func (s *Service) optimizeImage(ctx context.Context, fileBytes []byte, contentType string, quality int) ([]byte, image.Image, error) {
 	// Decode image from various formats
 	var img image.Image
-	var dims image.Rectangle
 	var err error
 
+	_, decodeSpan := tracing.Start(ctx, "media.decode", tracing.WithAttributes(tracing.Attr("image.format", imageFormat(contentType))))
+	defer decodeSpan.End()
+
 	switch {
 	case strings.Contains(contentType, "jpeg"):
 		img, err = jpeg.Decode(strings.NewReader(string(fileBytes)))
@@ -165,28 +364,89 @@ func (s *Service) optimizeImage(fileBytes []byte, contentType string) ([]byte, i
 	case strings.Contains(contentType, "webp"):
 		img, err = webp.Decode(strings.NewReader(string(fileBytes)))
 	case strings.Contains(contentType, "gif"):
-		img, err = png.Decode(strings.NewReader(string(fileBytes)))
+		img, err = gif.Decode(strings.NewReader(string(fileBytes)))
 	default:
-		return nil, image.Rectangle{}, fmt.Errorf("unsupported image format")
+		return nil, nil, fmt.Errorf("unsupported image format")
 	}
 
+	decodeSpan.SetError(err)
+	decodeSpan.End()
 	if err != nil {
-		return nil, image.Rectangle{}, fmt.Errorf("failed to decode image: %w", err)
+		return nil, nil, fmt.Errorf("%w: %w", errUndecodable, err)
 	}
 
-	dims = img.Bounds()
-
 	// For simplicity, we'll convert all images to JPEG with quality compression
 	// WebP encoding would require additional library
 	// Converting to JPEG maintains good quality while reducing size
 
+	_, encodeSpan := tracing.Start(ctx, "media.encode", tracing.WithAttributes(tracing.Attr("image.quality", quality)))
 	output := &strings.Builder{}
-	err = jpeg.Encode(output, img, &jpeg.Options{Quality: ImageQuality})
+	err = jpeg.Encode(output, img, &jpeg.Options{Quality: quality})
+	encodeSpan.SetError(err)
+	encodeSpan.End()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return []byte(output.String()), img, nil
+}
+
+// attachPoster extracts a poster frame for a stored video and derives the
+// placeholder fields from it. Failures are logged and the upload proceeds
+// without a poster.
+func (s *Service) attachPoster(ctx context.Context, media *Media) {
+	if s.transcoder == nil {
+		return
+	}
+
+	// Take the frame one second in, or halfway through very short clips
+	at := time.Second
+	if half := time.Duration(media.DurationSeconds * float64(time.Second) / 2); half < at {
+		at = half
+	}
+
+	posterCtx, cancel := context.WithTimeout(ctx, PosterExtractionTimeout)
+	defer cancel()
+
+	posterBytes, err := s.transcoder.ExtractPoster(posterCtx, media.FilePath, at)
 	if err != nil {
-		return nil, dims, fmt.Errorf("failed to encode image: %w", err)
+		slog.WarnContext(ctx, "Failed to extract video poster", "id", media.ID, "error", err)
+		return
+	}
+
+	posterPath := strings.TrimSuffix(media.FilePath, filepath.Ext(media.FilePath)) + "_poster.jpg"
+	if err := os.WriteFile(posterPath, posterBytes, 0644); err != nil {
+		slog.WarnContext(ctx, "Failed to save video poster", "id", media.ID, "error", err)
+		return
+	}
+	media.PosterPath = posterPath
+	media.PosterURL = "/media/" + media.ID + "/poster"
+
+	if img, err := jpeg.Decode(bytes.NewReader(posterBytes)); err == nil {
+		placeholder := computePlaceholder(img)
+		media.AverageColor = placeholder.AverageColor
+		media.DominantColors = placeholder.DominantColors
+		media.BlurHash = placeholder.BlurHash
+	}
+}
+
+// attachWaveform decodes compressed audio through the transcoder to build
+// waveform peaks. It is skipped when no decoder is available.
+func (s *Service) attachWaveform(ctx context.Context, media *Media) {
+	decoder, ok := s.transcoder.(AudioDecoder)
+	if !ok {
+		return
 	}
 
-	return []byte(output.String()), dims, nil
+	decodeCtx, cancel := context.WithTimeout(ctx, PosterExtractionTimeout)
+	defer cancel()
+
+	samples, err := decoder.DecodeMono(decodeCtx, media.FilePath, WaveformSampleRate)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to decode audio for waveform", "id", media.ID, "error", err)
+		return
+	}
+	media.Waveform = peaksFromPCM16(samples, WaveformPeaks)
 }
 
 // getImageDimensions returns image dimensions
@@ -207,7 +467,7 @@ func (s *Service) GetMedia(ctx context.Context, id string) (*Media, error) {
 
 	media, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		slog.Error("Failed to get media", "id", id, "error", err)
+		slog.ErrorContext(ctx, "Failed to get media", "id", id, "error", err)
 		return nil, err
 	}
 	return media, nil
@@ -221,33 +481,190 @@ func (s *Service) GetAllMedia(ctx context.Context) ([]*Media, error) {
 
 	media, err := s.repo.GetAll(ctx)
 	if err != nil {
-		slog.Error("Failed to get all media", "error", err)
+		slog.ErrorContext(ctx, "Failed to get all media", "error", err)
 		return nil, appErr.Internal("failed to retrieve media", err)
 	}
 	return media, nil
 }
 
-// DeleteMedia deletes a media file
-func (s *Service) DeleteMedia(ctx context.Context, id string) error {
+// DeleteMedia deletes a media file, provided its current version satisfies
+// cond
+func (s *Service) DeleteMedia(ctx context.Context, id string, cond etag.Condition) error {
 	if err := ctx.Err(); err != nil {
 		return appErr.Internal("context cancelled", err)
 	}
 
 	media, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		slog.Error("Failed to get media for deletion", "id", id, "error", err)
+		slog.ErrorContext(ctx, "Failed to get media for deletion", "id", id, "error", err)
+		return err
+	}
+	if err := cond.Check(media.Version); err != nil {
 		return err
 	}
 
+	// Remove from repository first so a concurrent update keeps its files
+	if err := s.repo.Delete(ctx, id, media.Version, events.MediaDeleted); err != nil {
+		return err
+	}
+	s.index.Remove(id)
+	s.hashIndex.Remove(id)
+	s.quota.remove(media.SizeBytes)
+	s.audit.Record(ctx, audit.ActionMediaDelete, audit.TargetMedia, id, media, nil)
+
 	// Delete file from disk
 	err = os.Remove(media.FilePath)
 	if err != nil && !os.IsNotExist(err) {
-		slog.Error("Failed to delete file", "error", err)
+		slog.ErrorContext(ctx, "Failed to delete file", "error", err)
 		return appErr.Internal("failed to delete file", err)
 	}
+	if media.PosterPath != "" {
+		if err := os.Remove(media.PosterPath); err != nil && !os.IsNotExist(err) {
+			slog.WarnContext(ctx, "Failed to delete poster", "id", id, "error", err)
+		}
+	}
+	return nil
+}
+
+// FindSimilar returns images whose perceptual hash is within maxDistance of
+// the given image, closest first. The image itself is not included.
+func (s *Service) FindSimilar(ctx context.Context, id string, maxDistance int) ([]*SimilarMedia, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	if err := validation.New().Range("max_distance", maxDistance, 0, MaxSimilarityDistance).Err(); err != nil {
+		return nil, err
+	}
 
-	// Remove from repository
-	return s.repo.Delete(ctx, id)
+	media, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get media for similarity search", "id", id, "error", err)
+		return nil, err
+	}
+
+	hash, ok := s.hashIndex.Hash(media.ID)
+	if !ok {
+		return nil, appErr.BadRequest("media has no perceptual hash; only images can be compared")
+	}
+
+	matches := s.hashIndex.Search(hash, maxDistance)
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Distance < matches[j].Distance
+	})
+
+	similar := make([]*SimilarMedia, 0, len(matches))
+	for _, match := range matches {
+		if match.ID == media.ID {
+			continue
+		}
+		m, err := s.repo.GetByID(ctx, match.ID)
+		if err != nil {
+			slog.WarnContext(ctx, "Similar media no longer in repository", "id", match.ID, "error", err)
+			continue
+		}
+		similar = append(similar, &SimilarMedia{Media: m, Distance: match.Distance})
+	}
+	return similar, nil
+}
+
+// indexHash adds a media record's perceptual hash to the similarity index
+func (s *Service) indexHash(m *Media) {
+	if m.PerceptualHash == "" {
+		return
+	}
+	hash, err := parseHash(m.PerceptualHash)
+	if err != nil {
+		slog.Warn("Ignoring invalid perceptual hash", "id", m.ID, "hash", m.PerceptualHash)
+		return
+	}
+	s.hashIndex.Add(m.ID, hash)
+}
+
+// SetTags replaces the tags of a media file, provided its current version
+// satisfies cond
+func (s *Service) SetTags(ctx context.Context, id string, tags []string, cond etag.Condition) (*Media, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	tags, err := normalizeTags("tags", tags)
+	if err != nil {
+		return nil, err
+	}
+
+	media, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get media for tagging", "id", id, "error", err)
+		return nil, err
+	}
+	if err := cond.Check(media.Version); err != nil {
+		return nil, err
+	}
+
+	updated := *media
+	updated.Tags = tags
+	if err := s.repo.Save(ctx, &updated); err != nil {
+		slog.ErrorContext(ctx, "Failed to save media tags", "id", id, "error", err)
+		if appErr.HasCode(err, appErr.ErrCodePrecondition) {
+			return nil, err
+		}
+		return nil, appErr.Internal("failed to save media tags", err)
+	}
+	s.index.Index(&updated)
+	s.audit.Record(ctx, audit.ActionMediaUpdate, audit.TargetMedia, id, media, &updated)
+
+	return &updated, nil
+}
+
+// SearchMedia searches media by free text, tags and type, returning one page
+// of results ordered by relevance
+func (s *Service) SearchMedia(ctx context.Context, query SearchQuery, page, pageSize int) (*MediaSearchResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultSearchPageSize
+	}
+	if pageSize > MaxSearchPageSize {
+		pageSize = MaxSearchPageSize
+	}
+
+	tags, err := normalizeTags("tag", query.Tags)
+	if err != nil {
+		return nil, err
+	}
+	query.Tags = tags
+	query.Type = strings.ToLower(strings.TrimSpace(query.Type))
+
+	hits := s.index.Search(query)
+	response := &MediaSearchResponse{
+		Total:    len(hits),
+		Page:     page,
+		PageSize: pageSize,
+		Results:  []*SearchResult{},
+	}
+
+	start := (page - 1) * pageSize
+	if start >= len(hits) {
+		return response, nil
+	}
+	end := min(start+pageSize, len(hits))
+
+	for _, hit := range hits[start:end] {
+		media, err := s.repo.GetByID(ctx, hit.ID)
+		if err != nil {
+			// The record was deleted between indexing and lookup
+			slog.WarnContext(ctx, "Search hit no longer in repository", "id", hit.ID, "error", err)
+			continue
+		}
+		response.Results = append(response.Results, &SearchResult{Media: media, Score: hit.Score})
+	}
+	return response, nil
 }
 
 // Helper functions
@@ -261,6 +678,38 @@ func isValidContentType(contentType string) bool {
 	return false
 }
 
+// isAllowedContentType reports whether the configured allow list accepts a
+// content type; an empty list accepts every supported type
+func isAllowedContentType(allowed []string, contentType string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, ct := range allowed {
+		if strings.Contains(contentType, ct) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateConfig is a config.Check rejecting allowed formats the service
+// cannot process
+func ValidateConfig(cfg *config.Config) error {
+	var problems []config.Problem
+	for i, ct := range cfg.Media.AllowedFormats {
+		if !slices.Contains(SupportedFormats, ct) {
+			problems = append(problems, config.Problem{
+				Key:     fmt.Sprintf("media.allowed_formats[%d]", i),
+				Message: fmt.Sprintf("%q is not a supported format", ct),
+			})
+		}
+	}
+	if len(problems) > 0 {
+		return &config.Error{Problems: problems}
+	}
+	return nil
+}
+
 func isImageType(contentType string) bool {
 	for _, ct := range SupportedImageFormats {
 		if strings.Contains(contentType, ct) {
@@ -270,6 +719,79 @@ func isImageType(contentType string) bool {
 	return false
 }
 
+// readUpload reads an uploaded file in a media.read span
+func readUpload(ctx context.Context, src io.Reader) ([]byte, error) {
+	_, span := tracing.Start(ctx, "media.read")
+	defer span.End()
+
+	data, err := io.ReadAll(src)
+	span.SetAttributes(tracing.Attr("media.bytes", len(data)))
+	span.SetError(err)
+	return data, err
+}
+
+// imageFormat names the source format of an image content type, e.g. "png"
+func imageFormat(contentType string) string {
+	for _, ct := range SupportedImageFormats {
+		if strings.Contains(contentType, ct) {
+			return strings.TrimPrefix(ct, "image/")
+		}
+	}
+	return "unknown"
+}
+
+func isVideoType(contentType string) bool {
+	for _, ct := range SupportedVideoFormats {
+		if strings.Contains(contentType, ct) {
+			return true
+		}
+	}
+	return false
+}
+
+// videoFormat maps a video content type to its stored format
+func videoFormat(contentType string) string {
+	if strings.Contains(contentType, "webm") {
+		return "webm"
+	}
+	return "mp4"
+}
+
+func isAudioType(contentType string) bool {
+	for _, ct := range SupportedAudioFormats {
+		if strings.Contains(contentType, ct) {
+			return true
+		}
+	}
+	return false
+}
+
+// audioFormat maps an audio content type to its stored format
+func audioFormat(contentType string) string {
+	switch {
+	case strings.Contains(contentType, "ogg"), strings.Contains(contentType, "opus"):
+		return "ogg"
+	case strings.Contains(contentType, "wav"):
+		return "wav"
+	case strings.Contains(contentType, "flac"):
+		return "flac"
+	default:
+		return "mp3"
+	}
+}
+
+// sniffFile detects the content type from the start of an uploaded file and
+// rewinds it
+func sniffFile(src multipart.File) string {
+	head := make([]byte, sniffLen)
+	n, _ := io.ReadFull(src, head)
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		slog.Error("Failed to rewind upload after sniffing", "error", err)
+		return ""
+	}
+	return sniffContentType(head[:n])
+}
+
 func isPDFType(contentType string) bool {
 	return strings.Contains(contentType, "application/pdf")
 }
@@ -287,6 +809,18 @@ func getContentTypeFromName(filename string) string {
 		return "image/gif"
 	case ".pdf":
 		return "application/pdf"
+	case ".mp4", ".m4v":
+		return "video/mp4"
+	case ".webm":
+		return "video/webm"
+	case ".mp3":
+		return "audio/mpeg"
+	case ".ogg", ".oga", ".opus":
+		return "audio/ogg"
+	case ".wav":
+		return "audio/wav"
+	case ".flac":
+		return "audio/flac"
 	default:
 		return ""
 	}
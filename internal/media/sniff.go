@@ -0,0 +1,44 @@
+package media
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// sniffLen is how many leading bytes are inspected to detect a file's type
+const sniffLen = 512
+
+// sniffContentType detects the content type from a file's leading bytes. It
+// recognises the audio and video containers we accept before falling back
+// to http.DetectContentType, and returns "" if nothing matched.
+func sniffContentType(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("ID3")) || isMP3FrameSync(head):
+		return "audio/mpeg"
+	case bytes.HasPrefix(head, []byte("OggS")):
+		return "audio/ogg"
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return "audio/flac"
+	case len(head) >= 12 && bytes.Equal(head[:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WAVE")):
+		return "audio/wav"
+	case len(head) >= 8 && bytes.Equal(head[4:8], []byte("ftyp")):
+		return "video/mp4"
+	case bytes.HasPrefix(head, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return "video/webm"
+	}
+
+	if ct := http.DetectContentType(head); ct != "application/octet-stream" {
+		return ct
+	}
+	return ""
+}
+
+// isMP3FrameSync reports whether head starts with a plausible MPEG audio
+// frame header
+func isMP3FrameSync(head []byte) bool {
+	if len(head) < 4 || head[0] != 0xFF || head[1]&0xE0 != 0xE0 {
+		return false
+	}
+	_, ok := parseMP3FrameHeader(head)
+	return ok
+}
@@ -0,0 +1,101 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// Transcoder extracts derived assets from video files. Implementations may
+// shell out to external tools, so the service treats a nil Transcoder as
+// "feature unavailable" rather than an error.
+type Transcoder interface {
+	// ExtractPoster returns a JPEG frame of the video at the given offset
+	ExtractPoster(ctx context.Context, videoPath string, at time.Duration) ([]byte, error)
+}
+
+// FFmpegTranscoder runs the ffmpeg binary in a subprocess
+type FFmpegTranscoder struct {
+	path string
+}
+
+// NewFFmpegTranscoder locates ffmpeg on PATH
+func NewFFmpegTranscoder() (*FFmpegTranscoder, error) {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+	return &FFmpegTranscoder{path: path}, nil
+}
+
+// DetectTranscoder returns an ffmpeg-backed Transcoder, or nil when ffmpeg is
+// not installed so poster extraction is skipped
+func DetectTranscoder() Transcoder {
+	t, err := NewFFmpegTranscoder()
+	if err != nil {
+		slog.Warn("Video poster extraction disabled", "error", err)
+		return nil
+	}
+	return t
+}
+
+// ExtractPoster grabs a single frame with ffmpeg and encodes it as JPEG
+func (t *FFmpegTranscoder) ExtractPoster(ctx context.Context, videoPath string, at time.Duration) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, t.path,
+		"-hide_banner", "-loglevel", "error",
+		"-ss", strconv.FormatFloat(at.Seconds(), 'f', 3, 64),
+		"-i", videoPath,
+		"-frames:v", "1",
+		"-f", "image2", "-c:v", "mjpeg",
+		"pipe:1",
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("ffmpeg produced no frame")
+	}
+	return stdout.Bytes(), nil
+}
+
+// AudioDecoder is implemented by transcoders that can decode compressed audio
+// to PCM, which is needed to draw waveforms for MP3, Ogg and FLAC files
+type AudioDecoder interface {
+	// DecodeMono returns the audio downmixed to mono signed 16-bit samples
+	DecodeMono(ctx context.Context, audioPath string, sampleRate int) ([]int16, error)
+}
+
+// DecodeMono decodes any audio ffmpeg understands to mono 16-bit PCM
+func (t *FFmpegTranscoder) DecodeMono(ctx context.Context, audioPath string, sampleRate int) ([]int16, error) {
+	cmd := exec.CommandContext(ctx, t.path,
+		"-hide_banner", "-loglevel", "error",
+		"-i", audioPath,
+		"-ac", "1",
+		"-ar", strconv.Itoa(sampleRate),
+		"-f", "s16le",
+		"pipe:1",
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	raw := stdout.Bytes()
+	samples := make([]int16, len(raw)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(raw[i*2:]))
+	}
+	return samples, nil
+}
@@ -0,0 +1,88 @@
+package media
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"example.com/myapp/internal/validation"
+)
+
+// MaxSearchQueryLength bounds the free-text search query
+const MaxSearchQueryLength = 200
+
+// MediaTypes are the values Media.Type can take
+var MediaTypes = []string{"image", "pdf", "video", "audio"}
+
+// SearchParams are the validated query parameters of a media search
+type SearchParams struct {
+	Query    SearchQuery
+	Page     int
+	PageSize int
+}
+
+// ParseSearchParams validates the query parameters of GET /media/search
+func ParseSearchParams(values url.Values) (*SearchParams, error) {
+	v := validation.New()
+
+	page := v.Int("page", values.Get("page"), 1)
+	v.Check(page >= 1, "page", "must be at least 1")
+	pageSize := v.Int("page_size", values.Get("page_size"), DefaultSearchPageSize)
+	v.Range("page_size", pageSize, 1, MaxSearchPageSize)
+
+	text := values.Get("q")
+	v.MaxLength("q", text, MaxSearchQueryLength)
+
+	mediaType := strings.ToLower(strings.TrimSpace(values.Get("type")))
+	v.OneOf("type", mediaType, MediaTypes...)
+
+	tags := parseTags(values["tag"])
+	validateTags(v, "tag", tags)
+
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return &SearchParams{
+		Query:    SearchQuery{Text: text, Tags: tags, Type: mediaType},
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// ParseMaxDistance validates the max_distance parameter of GET /media/{id}/similar
+func ParseMaxDistance(values url.Values) (int, error) {
+	v := validation.New()
+	d := v.Int("max_distance", values.Get("max_distance"), DefaultSimilarityDistance)
+	v.Range("max_distance", d, 0, MaxSimilarityDistance)
+	return d, v.Err()
+}
+
+// validateTags checks the number and length of tags
+func validateTags(v *validation.Validator, field string, tags []string) {
+	v.MaxItems(field, len(tags), MaxTags)
+	for i, tag := range tags {
+		v.MaxLength(fmt.Sprintf("%s[%d]", field, i), strings.TrimSpace(tag), MaxTagLength)
+	}
+}
+
+// normalizeTags validates tags, then lowercases and trims them, dropping
+// empty values and duplicates
+func normalizeTags(field string, tags []string) ([]string, error) {
+	v := validation.New()
+	validateTags(v, field, tags)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,575 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// VideoInfo is the metadata extracted from a video container
+type VideoInfo struct {
+	Container       string
+	DurationSeconds float64
+	Width           int
+	Height          int
+	VideoCodec      string
+	AudioCodec      string
+}
+
+var errInvalidContainer = errors.New("invalid or unsupported container")
+
+// probeVideo parses the container headers of an MP4 or WebM file. Only the
+// headers are read, so the sample data is never decoded.
+func probeVideo(r io.ReaderAt, size int64, format string) (*VideoInfo, error) {
+	switch format {
+	case "mp4":
+		return probeMP4(r, size)
+	case "webm":
+		return probeWebM(r, size)
+	default:
+		return nil, fmt.Errorf("%w: %s", errInvalidContainer, format)
+	}
+}
+
+// MP4 (ISO base media file format)
+
+// maxMP4LeafBox bounds how much of a metadata box is read into memory
+const maxMP4LeafBox = 64 * 1024
+
+type mp4Box struct {
+	typ        string
+	start, end int64 // payload range
+}
+
+// readMP4Boxes lists the boxes in [start, end)
+func readMP4Boxes(r io.ReaderAt, start, end int64) ([]mp4Box, error) {
+	var boxes []mp4Box
+	var hdr [16]byte
+	pos := start
+	for pos+8 <= end {
+		if _, err := r.ReadAt(hdr[:8], pos); err != nil {
+			return nil, fmt.Errorf("%w: truncated box header", errInvalidContainer)
+		}
+		size := int64(binary.BigEndian.Uint32(hdr[:4]))
+		typ := string(hdr[4:8])
+		headerLen := int64(8)
+
+		switch size {
+		case 0: // box extends to the end of its parent
+			size = end - pos
+		case 1: // 64-bit size follows the type
+			if pos+16 > end {
+				return nil, fmt.Errorf("%w: box %q has a truncated size", errInvalidContainer, typ)
+			}
+			if _, err := r.ReadAt(hdr[8:16], pos+8); err != nil {
+				return nil, fmt.Errorf("%w: box %q has a truncated size", errInvalidContainer, typ)
+			}
+			// Sizes above math.MaxInt64 turn negative and are rejected below
+			size = int64(binary.BigEndian.Uint64(hdr[8:16]))
+			headerLen = 16
+		}
+		// Compared against the room left so huge sizes cannot overflow pos
+		if size < headerLen || size > end-pos {
+			return nil, fmt.Errorf("%w: box %q has invalid size", errInvalidContainer, typ)
+		}
+
+		boxes = append(boxes, mp4Box{typ: typ, start: pos + headerLen, end: pos + size})
+		pos += size
+	}
+	// Fewer than 8 bytes left cannot hold a header, so the file was cut off
+	if pos < end {
+		return nil, fmt.Errorf("%w: truncated box header", errInvalidContainer)
+	}
+	return boxes, nil
+}
+
+func readMP4Payload(r io.ReaderAt, box mp4Box) ([]byte, error) {
+	n := min(box.end-box.start, maxMP4LeafBox)
+	buf := make([]byte, n)
+	if read, err := r.ReadAt(buf, box.start); int64(read) < n {
+		return nil, fmt.Errorf("%w: box %q is truncated: %v", errInvalidContainer, box.typ, err)
+	}
+	return buf, nil
+}
+
+func findMP4Box(boxes []mp4Box, typ string) (mp4Box, bool) {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b, true
+		}
+	}
+	return mp4Box{}, false
+}
+
+func probeMP4(r io.ReaderAt, size int64) (*VideoInfo, error) {
+	top, err := readMP4Boxes(r, 0, size)
+	if err != nil {
+		return nil, err
+	}
+	if len(top) == 0 || top[0].typ != "ftyp" {
+		return nil, fmt.Errorf("%w: missing ftyp box", errInvalidContainer)
+	}
+	moov, ok := findMP4Box(top, "moov")
+	if !ok {
+		return nil, fmt.Errorf("%w: missing moov box", errInvalidContainer)
+	}
+
+	children, err := readMP4Boxes(r, moov.start, moov.end)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &VideoInfo{Container: "mp4"}
+	if mvhd, ok := findMP4Box(children, "mvhd"); ok {
+		payload, err := readMP4Payload(r, mvhd)
+		if err != nil {
+			return nil, err
+		}
+		info.DurationSeconds = parseMP4Duration(payload)
+	}
+
+	for _, trak := range children {
+		if trak.typ != "trak" {
+			continue
+		}
+		if err := probeMP4Track(r, trak, info); err != nil {
+			return nil, err
+		}
+	}
+
+	if info.VideoCodec == "" {
+		return nil, fmt.Errorf("%w: no video track", errInvalidContainer)
+	}
+	return info, nil
+}
+
+// parseMP4Duration reads the duration from an mvhd or mdhd payload
+func parseMP4Duration(p []byte) float64 {
+	if len(p) < 4 {
+		return 0
+	}
+	var timescale uint32
+	var duration uint64
+	if p[0] == 1 {
+		if len(p) < 32 {
+			return 0
+		}
+		timescale = binary.BigEndian.Uint32(p[20:24])
+		duration = binary.BigEndian.Uint64(p[24:32])
+	} else {
+		if len(p) < 20 {
+			return 0
+		}
+		timescale = binary.BigEndian.Uint32(p[12:16])
+		duration = uint64(binary.BigEndian.Uint32(p[16:20]))
+	}
+	if timescale == 0 {
+		return 0
+	}
+	return float64(duration) / float64(timescale)
+}
+
+func probeMP4Track(r io.ReaderAt, trak mp4Box, info *VideoInfo) error {
+	children, err := readMP4Boxes(r, trak.start, trak.end)
+	if err != nil {
+		return err
+	}
+
+	var width, height int
+	if tkhd, ok := findMP4Box(children, "tkhd"); ok {
+		p, err := readMP4Payload(r, tkhd)
+		if err != nil {
+			return err
+		}
+		// Width and height are 16.16 fixed point at the end of the box
+		off := 76
+		if len(p) > 0 && p[0] == 1 {
+			off = 88
+		}
+		if len(p) >= off+8 {
+			width = int(binary.BigEndian.Uint32(p[off:off+4]) >> 16)
+			height = int(binary.BigEndian.Uint32(p[off+4:off+8]) >> 16)
+		}
+	}
+
+	mdia, ok := findMP4Box(children, "mdia")
+	if !ok {
+		return nil
+	}
+	mdiaChildren, err := readMP4Boxes(r, mdia.start, mdia.end)
+	if err != nil {
+		return err
+	}
+
+	var handler string
+	if hdlr, ok := findMP4Box(mdiaChildren, "hdlr"); ok {
+		p, err := readMP4Payload(r, hdlr)
+		if err != nil {
+			return err
+		}
+		if len(p) >= 12 {
+			handler = string(p[8:12])
+		}
+	}
+
+	codec, err := mp4SampleEntry(r, mdiaChildren)
+	if err != nil {
+		return err
+	}
+
+	switch handler {
+	case "vide":
+		if info.VideoCodec == "" {
+			info.VideoCodec = mp4CodecName(codec)
+			info.Width, info.Height = width, height
+		}
+	case "soun":
+		if info.AudioCodec == "" {
+			info.AudioCodec = mp4CodecName(codec)
+		}
+	}
+	return nil
+}
+
+// mp4SampleEntry returns the four-character code of the first sample entry
+// in mdia/minf/stbl/stsd
+func mp4SampleEntry(r io.ReaderAt, mdiaChildren []mp4Box) (string, error) {
+	boxes := mdiaChildren
+	for _, typ := range []string{"minf", "stbl"} {
+		box, ok := findMP4Box(boxes, typ)
+		if !ok {
+			return "", nil
+		}
+		var err error
+		if boxes, err = readMP4Boxes(r, box.start, box.end); err != nil {
+			return "", err
+		}
+	}
+
+	stsd, ok := findMP4Box(boxes, "stsd")
+	if !ok {
+		return "", nil
+	}
+	p, err := readMP4Payload(r, stsd)
+	if err != nil {
+		return "", err
+	}
+	// version/flags, entry count, then entry size and type
+	if len(p) < 16 {
+		return "", nil
+	}
+	return string(p[12:16]), nil
+}
+
+func mp4CodecName(fourcc string) string {
+	switch fourcc {
+	case "avc1", "avc3":
+		return "h264"
+	case "hvc1", "hev1":
+		return "hevc"
+	case "av01":
+		return "av1"
+	case "vp08":
+		return "vp8"
+	case "vp09":
+		return "vp9"
+	case "mp4a":
+		return "aac"
+	case "Opus":
+		return "opus"
+	case "ac-3":
+		return "ac3"
+	case "ec-3":
+		return "eac3"
+	default:
+		return strings.TrimSpace(fourcc)
+	}
+}
+
+// WebM (Matroska/EBML)
+
+const (
+	ebmlIDHeader        = 0x1A45DFA3
+	ebmlIDDocType       = 0x4282
+	ebmlIDSegment       = 0x18538067
+	ebmlIDInfo          = 0x1549A966
+	ebmlIDTimecodeScale = 0x2AD7B1
+	ebmlIDDuration      = 0x4489
+	ebmlIDTracks        = 0x1654AE6B
+	ebmlIDTrackEntry    = 0xAE
+	ebmlIDTrackType     = 0x83
+	ebmlIDCodecID       = 0x86
+	ebmlIDVideo         = 0xE0
+	ebmlIDPixelWidth    = 0xB0
+	ebmlIDPixelHeight   = 0xBA
+	ebmlIDCluster       = 0x1F43B675
+
+	// ebmlUnknownSize marks elements whose size was not known when written
+	ebmlUnknownSize = -1
+
+	// maxEBMLMasterRead bounds how much of Info or Tracks is read into memory
+	maxEBMLMasterRead = 1024 * 1024
+)
+
+type ebmlElement struct {
+	id         uint32
+	start, end int64 // payload range; end is -1 for unknown sizes
+}
+
+// readEBMLElement reads the element header at pos
+func readEBMLElement(r io.ReaderAt, pos int64) (ebmlElement, error) {
+	var buf [12]byte
+	n, _ := r.ReadAt(buf[:], pos)
+	if n == 0 {
+		return ebmlElement{}, fmt.Errorf("%w: truncated element", errInvalidContainer)
+	}
+	b := buf[:n]
+
+	idLen := vintLength(b[0])
+	if idLen == 0 || idLen > 4 || idLen >= len(b) {
+		return ebmlElement{}, fmt.Errorf("%w: bad element id", errInvalidContainer)
+	}
+	var id uint32
+	for _, c := range b[:idLen] {
+		id = id<<8 | uint32(c)
+	}
+
+	sizeLen := vintLength(b[idLen])
+	if sizeLen == 0 || idLen+sizeLen > len(b) {
+		return ebmlElement{}, fmt.Errorf("%w: bad element size", errInvalidContainer)
+	}
+	size := uint64(b[idLen]) & (0xFF >> sizeLen)
+	allOnes := size == uint64(0xFF>>sizeLen)
+	for _, c := range b[idLen+1 : idLen+sizeLen] {
+		size = size<<8 | uint64(c)
+		allOnes = allOnes && c == 0xFF
+	}
+
+	start := pos + int64(idLen+sizeLen)
+	if allOnes {
+		return ebmlElement{id: id, start: start, end: ebmlUnknownSize}, nil
+	}
+	return ebmlElement{id: id, start: start, end: start + int64(size)}, nil
+}
+
+// vintLength returns the length of an EBML variable-size integer from its
+// first byte, or 0 if the byte is invalid
+func vintLength(first byte) int {
+	for i := 0; i < 8; i++ {
+		if first&(0x80>>i) != 0 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// ebmlChildren parses the children of an element held fully in memory
+func ebmlChildren(data []byte) ([]ebmlElement, error) {
+	r := bytes.NewReader(data)
+	var elems []ebmlElement
+	for pos := int64(0); pos < int64(len(data)); {
+		el, err := readEBMLElement(r, pos)
+		if err != nil {
+			return nil, err
+		}
+		if el.end == ebmlUnknownSize || el.end > int64(len(data)) {
+			return nil, fmt.Errorf("%w: element overruns its parent", errInvalidContainer)
+		}
+		elems = append(elems, el)
+		pos = el.end
+	}
+	return elems, nil
+}
+
+func ebmlUint(p []byte) uint64 {
+	var v uint64
+	for _, c := range p {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func ebmlFloat(p []byte) float64 {
+	switch len(p) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(p)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(p))
+	default:
+		return 0
+	}
+}
+
+func readEBMLPayload(r io.ReaderAt, el ebmlElement) ([]byte, error) {
+	if el.end == ebmlUnknownSize || el.end-el.start > maxEBMLMasterRead {
+		return nil, fmt.Errorf("%w: element too large", errInvalidContainer)
+	}
+	buf := make([]byte, el.end-el.start)
+	if n, err := r.ReadAt(buf, el.start); n < len(buf) {
+		return nil, fmt.Errorf("%w: element is truncated: %v", errInvalidContainer, err)
+	}
+	return buf, nil
+}
+
+func probeWebM(r io.ReaderAt, size int64) (*VideoInfo, error) {
+	header, err := readEBMLElement(r, 0)
+	if err != nil || header.id != ebmlIDHeader {
+		return nil, fmt.Errorf("%w: missing EBML header", errInvalidContainer)
+	}
+	payload, err := readEBMLPayload(r, header)
+	if err != nil {
+		return nil, err
+	}
+	headerChildren, err := ebmlChildren(payload)
+	if err != nil {
+		return nil, err
+	}
+	docType := ""
+	for _, el := range headerChildren {
+		if el.id == ebmlIDDocType {
+			docType = string(bytes.TrimRight(payload[el.start:el.end], "\x00"))
+		}
+	}
+	if docType != "webm" && docType != "matroska" {
+		return nil, fmt.Errorf("%w: unexpected doc type %q", errInvalidContainer, docType)
+	}
+
+	segment, err := readEBMLElement(r, header.end)
+	if err != nil || segment.id != ebmlIDSegment {
+		return nil, fmt.Errorf("%w: missing segment", errInvalidContainer)
+	}
+	segmentEnd := segment.end
+	if segmentEnd == ebmlUnknownSize || segmentEnd > size {
+		segmentEnd = size
+	}
+
+	info := &VideoInfo{Container: "webm"}
+	timecodeScale := uint64(1000000)
+	var duration float64
+	seenInfo, seenTracks := false, false
+
+	// Info and Tracks precede the clusters in files written for streaming
+	for pos := segment.start; pos < segmentEnd && !(seenInfo && seenTracks); {
+		el, err := readEBMLElement(r, pos)
+		if err != nil {
+			break
+		}
+		if el.id == ebmlIDCluster || el.end == ebmlUnknownSize {
+			break
+		}
+
+		switch el.id {
+		case ebmlIDInfo:
+			p, err := readEBMLPayload(r, el)
+			if err != nil {
+				return nil, err
+			}
+			children, err := ebmlChildren(p)
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range children {
+				switch c.id {
+				case ebmlIDTimecodeScale:
+					timecodeScale = ebmlUint(p[c.start:c.end])
+				case ebmlIDDuration:
+					duration = ebmlFloat(p[c.start:c.end])
+				}
+			}
+			seenInfo = true
+		case ebmlIDTracks:
+			p, err := readEBMLPayload(r, el)
+			if err != nil {
+				return nil, err
+			}
+			if err := parseWebMTracks(p, info); err != nil {
+				return nil, err
+			}
+			seenTracks = true
+		}
+		pos = el.end
+	}
+
+	info.DurationSeconds = duration * float64(timecodeScale) / 1e9
+	if info.VideoCodec == "" {
+		return nil, fmt.Errorf("%w: no video track", errInvalidContainer)
+	}
+	return info, nil
+}
+
+func parseWebMTracks(p []byte, info *VideoInfo) error {
+	entries, err := ebmlChildren(p)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.id != ebmlIDTrackEntry {
+			continue
+		}
+		data := p[entry.start:entry.end]
+		fields, err := ebmlChildren(data)
+		if err != nil {
+			return err
+		}
+
+		var trackType uint64
+		var codecID string
+		var width, height int
+		for _, f := range fields {
+			switch f.id {
+			case ebmlIDTrackType:
+				trackType = ebmlUint(data[f.start:f.end])
+			case ebmlIDCodecID:
+				codecID = string(data[f.start:f.end])
+			case ebmlIDVideo:
+				video := data[f.start:f.end]
+				vfields, err := ebmlChildren(video)
+				if err != nil {
+					return err
+				}
+				for _, v := range vfields {
+					switch v.id {
+					case ebmlIDPixelWidth:
+						width = int(ebmlUint(video[v.start:v.end]))
+					case ebmlIDPixelHeight:
+						height = int(ebmlUint(video[v.start:v.end]))
+					}
+				}
+			}
+		}
+
+		switch trackType {
+		case 1:
+			if info.VideoCodec == "" {
+				info.VideoCodec = webmCodecName(codecID)
+				info.Width, info.Height = width, height
+			}
+		case 2:
+			if info.AudioCodec == "" {
+				info.AudioCodec = webmCodecName(codecID)
+			}
+		}
+	}
+	return nil
+}
+
+func webmCodecName(codecID string) string {
+	switch codecID {
+	case "V_VP8":
+		return "vp8"
+	case "V_VP9":
+		return "vp9"
+	case "V_AV1":
+		return "av1"
+	case "A_OPUS":
+		return "opus"
+	case "A_VORBIS":
+		return "vorbis"
+	default:
+		return strings.ToLower(codecID)
+	}
+}
@@ -0,0 +1,272 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+// box builds an MP4 box with a 32-bit size
+func box(typ string, children ...[]byte) []byte {
+	payload := bytes.Join(children, nil)
+	out := binary.BigEndian.AppendUint32(nil, uint32(8+len(payload)))
+	return append(append(out, typ...), payload...)
+}
+
+// boxWithSize builds an MP4 box whose size field is written as given
+func boxWithSize(size uint32, typ string, payload []byte) []byte {
+	out := binary.BigEndian.AppendUint32(nil, size)
+	return append(append(out, typ...), payload...)
+}
+
+// largeBox builds an MP4 box with a 64-bit size
+func largeBox(size uint64, typ string, payload []byte) []byte {
+	out := binary.BigEndian.AppendUint32(nil, 1)
+	out = append(out, typ...)
+	out = binary.BigEndian.AppendUint64(out, size)
+	return append(out, payload...)
+}
+
+func mvhd(timescale, duration uint32) []byte {
+	p := make([]byte, 100)
+	binary.BigEndian.PutUint32(p[12:], timescale)
+	binary.BigEndian.PutUint32(p[16:], duration)
+	return box("mvhd", p)
+}
+
+func tkhd(width, height uint32) []byte {
+	p := make([]byte, 84)
+	binary.BigEndian.PutUint32(p[76:], width<<16)
+	binary.BigEndian.PutUint32(p[80:], height<<16)
+	return box("tkhd", p)
+}
+
+func hdlr(handler string) []byte {
+	p := make([]byte, 24)
+	copy(p[8:], handler)
+	return box("hdlr", p)
+}
+
+func stsd(fourcc string) []byte {
+	p := make([]byte, 16)
+	binary.BigEndian.PutUint32(p[4:], 1)
+	copy(p[12:], fourcc)
+	return box("stsd", p)
+}
+
+func trak(handler, fourcc string, width, height uint32) []byte {
+	return box("trak", tkhd(width, height), box("mdia", hdlr(handler), box("minf", box("stbl", stsd(fourcc)))))
+}
+
+func ftyp() []byte {
+	return box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2avc1mp41"))
+}
+
+// validMP4 puts moov last, as most encoders do, so no proper prefix of the
+// file is itself a complete MP4
+func validMP4() []byte {
+	return bytes.Join([][]byte{
+		ftyp(),
+		box("mdat", make([]byte, 64)),
+		box("moov", mvhd(1000, 12500), trak("vide", "avc1", 1920, 1080), trak("soun", "mp4a", 0, 0)),
+	}, nil)
+}
+
+func TestProbeMP4(t *testing.T) {
+	want := VideoInfo{Container: "mp4", DurationSeconds: 12.5, Width: 1920, Height: 1080, VideoCodec: "h264", AudioCodec: "aac"}
+	moov := box("moov", mvhd(1000, 12500), trak("vide", "avc1", 1920, 1080), trak("soun", "mp4a", 0, 0))
+
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{name: "32-bit sizes", file: validMP4()},
+		{name: "size 0 runs to the end of the file", file: bytes.Join([][]byte{ftyp(), moov, boxWithSize(0, "mdat", make([]byte, 64))}, nil)},
+		{name: "size 0 on moov", file: bytes.Join([][]byte{ftyp(), boxWithSize(0, "moov", moov[8:])}, nil)},
+		{name: "size 1 with a 64-bit size", file: bytes.Join([][]byte{ftyp(), moov, largeBox(16+64, "mdat", make([]byte, 64))}, nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := probeVideo(bytes.NewReader(tt.file), int64(len(tt.file)), "mp4")
+			if err != nil {
+				t.Fatalf("probeVideo() error = %v", err)
+			}
+			if *got != want {
+				t.Errorf("probeVideo() = %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
+
+func TestProbeMP4Invalid(t *testing.T) {
+	valid := validMP4()
+	moov := box("moov", mvhd(1000, 12500), trak("vide", "avc1", 1920, 1080))
+
+	// A trak that claims more bytes than its moov holds
+	overrun := box("trak", tkhd(640, 480))
+	binary.BigEndian.PutUint32(overrun, uint32(len(overrun)+32))
+
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{name: "empty", file: nil},
+		{name: "truncated header", file: valid[:5]},
+		{name: "size below header length", file: append(ftyp(), boxWithSize(4, "moov", nil)...)},
+		{name: "size past end of file", file: append(ftyp(), boxWithSize(1<<20, "moov", moov[8:])...)},
+		{name: "size 1 with truncated 64-bit size", file: append(append(ftyp(), moov...), 0, 0, 0, 1, 'm', 'd', 'a', 't', 0, 0)},
+		{name: "size 1 with 64-bit size below header", file: append(append(ftyp(), moov...), largeBox(8, "mdat", nil)...)},
+		{name: "size 1 with 64-bit size past end", file: append(append(ftyp(), moov...), largeBox(1<<40, "mdat", nil)...)},
+		{name: "size 1 overflowing int64", file: append(append(ftyp(), moov...), largeBox(math.MaxInt64, "mdat", nil)...)},
+		{name: "size 1 negative as int64", file: append(append(ftyp(), moov...), largeBox(math.MaxUint64, "mdat", nil)...)},
+		{name: "nested box overruns its parent", file: append(ftyp(), box("moov", mvhd(1000, 1), overrun)...)},
+		{name: "nested stsd overruns stbl", file: append(ftyp(), box("moov", box("trak", box("mdia", box("minf", boxWithSize(64, "stbl", stsd("avc1"))))))...)},
+		{name: "missing ftyp", file: moov},
+		{name: "missing moov", file: ftyp()},
+		{name: "no video track", file: append(ftyp(), box("moov", mvhd(1000, 1), trak("soun", "mp4a", 0, 0))...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := probeVideo(bytes.NewReader(tt.file), int64(len(tt.file)), "mp4")
+			if !errors.Is(err, errInvalidContainer) {
+				t.Errorf("probeVideo() error = %v, want errInvalidContainer", err)
+			}
+		})
+	}
+}
+
+// ebml builds an EBML element with a one to eight byte size
+func ebml(id uint32, children ...[]byte) []byte {
+	payload := bytes.Join(children, nil)
+	return append(append(ebmlID(id), ebmlSize(uint64(len(payload)))...), payload...)
+}
+
+func ebmlID(id uint32) []byte {
+	var out []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> shift); b != 0 || len(out) > 0 {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+func ebmlSize(n uint64) []byte {
+	if n < 0x7F {
+		return []byte{0x80 | byte(n)}
+	}
+	out := []byte{0x01}
+	for shift := 48; shift >= 0; shift -= 8 {
+		out = append(out, byte(n>>shift))
+	}
+	return out
+}
+
+// ebmlUnknown builds an element whose size is the unknown marker
+func ebmlUnknown(id uint32, children ...[]byte) []byte {
+	return append(append(ebmlID(id), 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF), bytes.Join(children, nil)...)
+}
+
+func ebmlUintBytes(v uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, v)
+}
+
+func ebmlFloatBytes(v float64) []byte {
+	return binary.BigEndian.AppendUint64(nil, math.Float64bits(v))
+}
+
+func webmHeader() []byte {
+	return ebml(ebmlIDHeader, ebml(ebmlIDDocType, []byte("webm")))
+}
+
+func webmTracks() []byte {
+	return ebml(ebmlIDTracks,
+		ebml(ebmlIDTrackEntry,
+			ebml(ebmlIDTrackType, []byte{1}),
+			ebml(ebmlIDCodecID, []byte("V_VP9")),
+			ebml(ebmlIDVideo, ebml(ebmlIDPixelWidth, ebmlUintBytes(1280)), ebml(ebmlIDPixelHeight, ebmlUintBytes(720))),
+		),
+		ebml(ebmlIDTrackEntry, ebml(ebmlIDTrackType, []byte{2}), ebml(ebmlIDCodecID, []byte("A_OPUS"))),
+	)
+}
+
+func webmInfo() []byte {
+	return ebml(ebmlIDInfo, ebml(ebmlIDTimecodeScale, ebmlUintBytes(1000000)), ebml(ebmlIDDuration, ebmlFloatBytes(4500)))
+}
+
+func TestProbeWebM(t *testing.T) {
+	want := VideoInfo{Container: "webm", DurationSeconds: 4.5, Width: 1280, Height: 720, VideoCodec: "vp9", AudioCodec: "opus"}
+	cluster := ebml(ebmlIDCluster, make([]byte, 32))
+
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{name: "known sizes", file: append(webmHeader(), ebml(ebmlIDSegment, webmInfo(), webmTracks(), cluster)...)},
+		{name: "unknown-size segment", file: append(webmHeader(), ebmlUnknown(ebmlIDSegment, webmInfo(), webmTracks(), cluster)...)},
+		{name: "unknown-size cluster after tracks", file: append(webmHeader(), ebmlUnknown(ebmlIDSegment, webmInfo(), webmTracks(), ebmlUnknown(ebmlIDCluster, make([]byte, 32)))...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := probeVideo(bytes.NewReader(tt.file), int64(len(tt.file)), "webm")
+			if err != nil {
+				t.Fatalf("probeVideo() error = %v", err)
+			}
+			if *got != want {
+				t.Errorf("probeVideo() = %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
+
+func TestProbeWebMInvalid(t *testing.T) {
+	// A track entry whose CodecID claims more bytes than the entry holds
+	codec := ebml(ebmlIDCodecID, []byte("V_VP9"))
+	codec[1] = 0x80 | 40
+	overrun := ebml(ebmlIDTracks, ebml(ebmlIDTrackEntry, ebml(ebmlIDTrackType, []byte{1}), codec))
+
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{name: "empty", file: nil},
+		{name: "truncated header", file: webmHeader()[:3]},
+		{name: "not EBML", file: append(ebml(0x1A45DFA2), 0)},
+		{name: "wrong doc type", file: append(ebml(ebmlIDHeader, ebml(ebmlIDDocType, []byte("mkvx"))), ebml(ebmlIDSegment, webmTracks())...)},
+		{name: "unknown-size EBML header", file: append(ebmlUnknown(ebmlIDHeader, ebml(ebmlIDDocType, []byte("webm"))), ebml(ebmlIDSegment, webmTracks())...)},
+		{name: "unknown-size tracks", file: append(webmHeader(), ebml(ebmlIDSegment, webmInfo(), ebmlUnknown(ebmlIDTracks, webmTracks()[4:]))...)},
+		{name: "unknown-size child of tracks", file: append(webmHeader(), ebml(ebmlIDSegment, ebml(ebmlIDTracks, ebmlUnknown(ebmlIDTrackEntry, ebml(ebmlIDTrackType, []byte{1}))))...)},
+		{name: "child overruns its parent", file: append(webmHeader(), ebml(ebmlIDSegment, overrun)...)},
+		{name: "tracks past end of file", file: append(webmHeader(), ebml(ebmlIDSegment, webmTracks())[:40]...)},
+		{name: "invalid size byte", file: append(webmHeader(), 0x18, 0x53, 0x80, 0x67, 0x00)},
+		{name: "missing segment", file: webmHeader()},
+		{name: "no video track", file: append(webmHeader(), ebml(ebmlIDSegment, webmInfo())...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := probeVideo(bytes.NewReader(tt.file), int64(len(tt.file)), "webm")
+			if !errors.Is(err, errInvalidContainer) {
+				t.Errorf("probeVideo() error = %v, want errInvalidContainer", err)
+			}
+		})
+	}
+}
+
+// TestProbeVideoTruncated cuts valid files at every length: each prefix
+// must be rejected cleanly, never panic or loop
+func TestProbeVideoTruncated(t *testing.T) {
+	files := map[string][]byte{
+		"mp4":  validMP4(),
+		"webm": append(webmHeader(), ebml(ebmlIDSegment, webmInfo(), webmTracks())...),
+	}
+	for format, file := range files {
+		for n := 0; n < len(file); n++ {
+			prefix := file[:n]
+			if _, err := probeVideo(bytes.NewReader(prefix), int64(n), format); !errors.Is(err, errInvalidContainer) {
+				t.Errorf("%s truncated to %d of %d bytes: error = %v, want errInvalidContainer", format, n, len(file), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func collect(c Collector) string {
+	var sb strings.Builder
+	w := bufio.NewWriter(&sb)
+	c.Collect(w)
+	w.Flush()
+	return sb.String()
+}
+
+func TestCollect(t *testing.T) {
+	tests := []struct {
+		name   string
+		record func() Collector
+		want   string
+	}{
+		{
+			name: "counter",
+			record: func() Collector {
+				c := NewCounterVec("test_requests_total", "Requests.", "method", "status")
+				c.Inc("GET", "200")
+				c.Add(2, "GET", "200")
+				c.Inc("DELETE", "404")
+				return c
+			},
+			want: "# HELP test_requests_total Requests.\n# TYPE test_requests_total counter\n" +
+				"test_requests_total{method=\"DELETE\",status=\"404\"} 1\n" +
+				"test_requests_total{method=\"GET\",status=\"200\"} 3\n",
+		},
+		{
+			name: "gauge without labels",
+			record: func() Collector {
+				g := NewGaugeVec("test_in_flight", "In flight.")
+				g.Set(5)
+				g.Add(-2)
+				return g
+			},
+			want: "# HELP test_in_flight In flight.\n# TYPE test_in_flight gauge\ntest_in_flight 3\n",
+		},
+		{
+			name: "histogram buckets are cumulative",
+			record: func() Collector {
+				h := NewHistogramVec("test_duration_seconds", "Duration.", []float64{0.1, 1}, "route")
+				h.Observe(0.05, "/a")
+				h.Observe(0.1, "/a")
+				h.Observe(0.5, "/a")
+				h.Observe(3, "/a")
+				return h
+			},
+			want: "# HELP test_duration_seconds Duration.\n# TYPE test_duration_seconds histogram\n" +
+				"test_duration_seconds_bucket{route=\"/a\",le=\"0.1\"} 2\n" +
+				"test_duration_seconds_bucket{route=\"/a\",le=\"1\"} 3\n" +
+				"test_duration_seconds_bucket{route=\"/a\",le=\"+Inf\"} 4\n" +
+				"test_duration_seconds_sum{route=\"/a\"} 3.65\n" +
+				"test_duration_seconds_count{route=\"/a\"} 4\n",
+		},
+		{
+			name: "escaping",
+			record: func() Collector {
+				g := NewGaugeVec("test_escaped", "Help with \\ and\nnewline.", "value")
+				g.Set(math.Inf(1), "a\"b\\c\nd")
+				return g
+			},
+			want: "# HELP test_escaped Help with \\\\ and\\nnewline.\n# TYPE test_escaped gauge\n" +
+				"test_escaped{value=\"a\\\"b\\\\c\\nd\"} +Inf\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := collect(tt.record()); got != tt.want {
+				t.Errorf("Collect() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMisuse(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func()
+	}{
+		{name: "wrong label count", run: func() { NewCounterVec("test_misuse_labels", "x", "a").Inc() }},
+		{name: "negative counter delta", run: func() { NewCounterVec("test_misuse_negative", "x").Add(-1) }},
+		{name: "unsorted buckets", run: func() { NewHistogramVec("test_misuse_buckets", "x", []float64{1, 0.5}) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+			tt.run()
+		})
+	}
+}
+
+func TestRegistryHandler(t *testing.T) {
+	r := NewRegistry()
+	c := &CounterVec{newVec("test_handler_total", "Handled.", nil, func() *float64 { return new(float64) })}
+	r.MustRegister(c, &runtimeCollector{})
+	c.Inc()
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != ContentType {
+		t.Fatalf("status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+	for _, want := range []string{"test_handler_total 1\n", "# TYPE go_goroutines gauge\n", "go_info{version="} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body lacks %q", want)
+		}
+	}
+	if strings.Index(body, "test_handler_total") > strings.Index(body, "go_goroutines") {
+		t.Error("collectors not written in registration order")
+	}
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"example.com/myapp/internal/tracing"
+)
+
+var repositoryDuration = NewHistogramVec(
+	"repository_operation_duration_seconds",
+	"Latency of repository operations by repository, operation and outcome.",
+	FastBuckets,
+	"repository", "operation", "outcome",
+)
+
+// ObserveRepository runs fn as operation of repository: the call is timed
+// in repository_operation_duration_seconds and traced as a child span of
+// ctx named "<repository>.repository.<operation>". Instrumented
+// repositories forward each method through it.
+func ObserveRepository[T any](ctx context.Context, repository, operation string, fn func(context.Context) (T, error)) (T, error) {
+	ctx, span := tracing.Start(ctx, repository+".repository."+operation, tracing.WithAttributes(tracing.Attr("db.operation", operation)))
+	defer span.End()
+
+	start := time.Now()
+	result, err := fn(ctx)
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	repositoryDuration.Observe(time.Since(start).Seconds(), repository, operation, outcome)
+	span.SetError(err)
+	return result, err
+}
+
+// ObserveRepositoryCall is ObserveRepository for methods that only return
+// an error
+func ObserveRepositoryCall(ctx context.Context, repository, operation string, fn func(context.Context) error) error {
+	_, err := ObserveRepository(ctx, repository, operation, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
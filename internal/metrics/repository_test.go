@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"example.com/myapp/internal/tracing"
+)
+
+// recordingExporter keeps the spans it is given
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (e *recordingExporter) Export(_ context.Context, spans []tracing.SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func TestObserveRepository(t *testing.T) {
+	exporter := &recordingExporter{}
+	provider := tracing.NewProvider(exporter, 1)
+	tracing.SetProvider(provider)
+	t.Cleanup(func() { tracing.SetProvider(nil) })
+
+	ctx, parent := tracing.Start(context.Background(), "request")
+	var inner tracing.SpanContext
+	got, err := ObserveRepository(ctx, "test", "get_by_id", func(ctx context.Context) (string, error) {
+		inner = tracing.SpanContextFromContext(ctx)
+		return "found", nil
+	})
+	if got != "found" || err != nil {
+		t.Errorf("ObserveRepository() = %q, %v", got, err)
+	}
+	failure := errors.New("connection refused")
+	if err := ObserveRepositoryCall(ctx, "test", "save", func(context.Context) error { return failure }); err != failure {
+		t.Errorf("ObserveRepositoryCall() error = %v, want %v", err, failure)
+	}
+	parent.End()
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := make(map[string]tracing.SpanData)
+	for _, s := range exporter.spans {
+		spans[s.Name] = s
+	}
+	get, save := spans["test.repository.get_by_id"], spans["test.repository.save"]
+	if get.SpanContext != inner || get.ParentSpanID != parent.SpanContext().SpanID || get.Error != "" {
+		t.Errorf("get_by_id span = %+v, want a successful child of the request run with its context", get)
+	}
+	if save.Error != failure.Error() {
+		t.Errorf("save span error = %q, want %q", save.Error, failure)
+	}
+
+	out := collect(repositoryDuration)
+	for _, want := range []string{
+		`repository_operation_duration_seconds_count{repository="test",operation="get_by_id",outcome="ok"} 1`,
+		`repository_operation_duration_seconds_count{repository="test",operation="save",outcome="error"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %s:\n%s", want, out)
+		}
+	}
+}
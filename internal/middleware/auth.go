@@ -3,20 +3,62 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"time"
+
+	"example.com/myapp/internal/auth"
+	appErr "example.com/myapp/internal/errors"
 )
 
-// AuthMiddleware is a simple auth middleware that checks for authorization header
+// Authenticate verifies the bearer token or session cookie of every request
+// and stores the principal in the context. Requests without credentials
+// continue as anonymous; invalid or expired credentials get a 401. Without
+// a secret credentials are not verified and identify the caller directly.
+// Usage: Apply globally in routes.SetupRoutes, before rate limiting and CSRF
+func Authenticate(secret []byte) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := auth.Authenticate(r, secret, time.Now())
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				appErr.WriteError(w, r, &appErr.AppError{Code: appErr.ErrCodeUnauthorized, Message: "invalid credentials", Err: err})
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// AuthMiddleware passes the principal's ID to handlers under the "user"
+// context key. Credentials are verified by Authenticate.
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-	/*	
-		if authHeader == "" {
-			http.Error(w, "Missing authorization header", http.StatusUnauthorized)
-			return
-		}
-		*/
-		// Pass authenticated user info to context
-		ctx := context.WithValue(r.Context(), "user", authHeader)
+		principal := auth.FromRequest(r)
+		ctx := context.WithValue(r.Context(), "user", principal.ID)
+		ctx = auth.WithPrincipal(ctx, principal)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// RequireAdmin only lets principals listed in adminIDs through.
+// Usage: Apply after AuthMiddleware
+func RequireAdmin(adminIDs []string) func(next http.Handler) http.Handler {
+	admins := make(map[string]bool, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := auth.FromContext(r.Context())
+			if principal.IsAnonymous() {
+				appErr.WriteError(w, r, appErr.Unauthorized("authentication is required"))
+				return
+			}
+			if !admins[principal.ID] {
+				appErr.WriteError(w, r, appErr.Forbidden("admin access is required"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/myapp/internal/auth"
+)
+
+func TestAuthenticateAndRequireAdmin(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	issue := func(subject string, expires time.Time) string {
+		token, err := auth.IssueToken(secret, subject, expires)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + token
+	}
+	later := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name          string
+		secret        []byte
+		authorization string
+		want          int
+	}{
+		{name: "admin token", secret: secret, authorization: issue("ops", later), want: http.StatusOK},
+		{name: "non-admin token", secret: secret, authorization: issue("alice", later), want: http.StatusForbidden},
+		{name: "no credentials", secret: secret, want: http.StatusUnauthorized},
+		{name: "unsigned admin name", secret: secret, authorization: "Bearer ops", want: http.StatusUnauthorized},
+		{name: "expired admin token", secret: secret, authorization: issue("ops", time.Now().Add(-time.Minute)), want: http.StatusUnauthorized},
+		{name: "no secret takes the name at face value", secret: nil, authorization: "Bearer ops", want: http.StatusOK},
+		{name: "no secret without credentials", secret: nil, want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handled string
+			h := Authenticate(tt.secret)(AuthMiddleware(RequireAdmin([]string{"ops"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handled, _ = r.Context().Value("user").(string)
+			}))))
+
+			r := httptest.NewRequest(http.MethodGet, "/audit", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusOK && handled != "ops" {
+				t.Errorf("handler saw user %q, want ops", handled)
+			}
+			if tt.want == http.StatusUnauthorized && tt.authorization != "" && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("rejected credentials without a WWW-Authenticate header")
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"example.com/myapp/internal/config"
+	appErr "example.com/myapp/internal/errors"
+)
+
+// CORS returns a middleware that lets the browser origins in
+// cors.allowed_origins call the API. Preflight requests are answered here
+// with 204, or 403 when the origin, method or a header is not allowed;
+// they never reach the routes. Other requests from an allowed origin get
+// the Access-Control-Allow-* headers; from any other origin they are
+// served without them, so the browser withholds the response from the
+// calling script.
+// Usage: Apply globally in routes.SetupRoutes
+func CORS(settings *config.Store) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			cfg := settings.Current().CORS
+			if origin == "" || len(cfg.AllowedOrigins) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Responses differ by origin, so caches must keep them apart
+			h := w.Header()
+			h.Add("Vary", "Origin")
+
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			allowed := slices.Contains(cfg.AllowedOrigins, "*") || slices.Contains(cfg.AllowedOrigins, origin)
+			if !allowed {
+				if preflight {
+					appErr.WriteError(w, r, appErr.Forbidden("origin "+origin+" is not allowed"))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Credentialed responses must name the origin; validation rules
+			// out credentials with "*"
+			if slices.Contains(cfg.AllowedOrigins, "*") && !cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if len(cfg.ExposedHeaders) > 0 {
+					h.Set("Access-Control-Expose-Headers", strings.Join(cfg.ExposedHeaders, ", "))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+
+			method := r.Header.Get("Access-Control-Request-Method")
+			if !containsFold(cfg.AllowedMethods, method) {
+				appErr.WriteError(w, r, appErr.Forbidden("method "+method+" is not allowed for cross-origin requests"))
+				return
+			}
+			for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+				if header = strings.TrimSpace(header); header != "" && !containsFold(cfg.AllowedHeaders, header) {
+					appErr.WriteError(w, r, appErr.Forbidden("header "+header+" is not allowed for cross-origin requests"))
+					return
+				}
+			}
+
+			h.Set("Access-Control-Allow-Methods", strings.Join(cfg.AllowedMethods, ", "))
+			if len(cfg.AllowedHeaders) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
+			}
+			if cfg.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// containsFold reports whether list holds s, ignoring case
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(item string) bool { return strings.EqualFold(item, s) })
+}
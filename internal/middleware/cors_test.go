@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/myapp/internal/config"
+)
+
+func TestCORS(t *testing.T) {
+	const app = "https://app.example.com"
+
+	tests := []struct {
+		name        string
+		origins     []string
+		credentials bool
+		method      string
+		origin      string
+		reqMethod   string
+		reqHeaders  string
+		wantStatus  int
+		wantHeaders map[string]string
+		wantNext    bool
+	}{
+		{
+			name: "same-origin request", origins: []string{app}, method: http.MethodGet,
+			wantStatus: http.StatusOK, wantNext: true,
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "", "Vary": ""},
+		},
+		{
+			name: "CORS disabled", method: http.MethodGet, origin: app,
+			wantStatus: http.StatusOK, wantNext: true,
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name: "allowed origin", origins: []string{app}, method: http.MethodGet, origin: app,
+			wantStatus: http.StatusOK, wantNext: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":   app,
+				"Access-Control-Expose-Headers": "ETag, X-CSRF-Token",
+				"Vary":                          "Origin",
+			},
+		},
+		{
+			name: "credentials name the origin", origins: []string{app}, credentials: true, method: http.MethodGet, origin: app,
+			wantStatus: http.StatusOK, wantNext: true,
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": app, "Access-Control-Allow-Credentials": "true"},
+		},
+		{
+			name: "wildcard without credentials", origins: []string{"*"}, method: http.MethodGet, origin: "https://other.example",
+			wantStatus: http.StatusOK, wantNext: true,
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "*", "Access-Control-Allow-Credentials": ""},
+		},
+		{
+			name: "other origin served without CORS headers", origins: []string{app}, method: http.MethodGet, origin: "https://evil.example",
+			wantStatus: http.StatusOK, wantNext: true,
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "", "Vary": "Origin"},
+		},
+		{
+			name: "preflight", origins: []string{app}, method: http.MethodOptions, origin: app,
+			reqMethod: http.MethodPatch, reqHeaders: "content-type, x-csrf-token",
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  app,
+				"Access-Control-Allow-Methods": "GET, PATCH",
+				"Access-Control-Allow-Headers": "Content-Type, X-CSRF-Token",
+				"Access-Control-Max-Age":       "600",
+			},
+		},
+		{
+			name: "preflight from other origin", origins: []string{app}, method: http.MethodOptions, origin: "https://evil.example",
+			reqMethod: http.MethodPost, wantStatus: http.StatusForbidden,
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name: "preflight for disallowed method", origins: []string{app}, method: http.MethodOptions, origin: app,
+			reqMethod: http.MethodDelete, wantStatus: http.StatusForbidden,
+			wantHeaders: map[string]string{"Access-Control-Allow-Methods": ""},
+		},
+		{
+			name: "preflight for disallowed header", origins: []string{app}, method: http.MethodOptions, origin: app,
+			reqMethod: http.MethodPatch, reqHeaders: "Content-Type, X-Debug", wantStatus: http.StatusForbidden,
+			wantHeaders: map[string]string{"Access-Control-Allow-Headers": ""},
+		},
+		{
+			name: "plain OPTIONS reaches the routes", origins: []string{app}, method: http.MethodOptions, origin: app,
+			wantStatus: http.StatusOK, wantNext: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.CORS = config.CORSConfig{
+				AllowedOrigins:   tt.origins,
+				AllowedMethods:   []string{"GET", "PATCH"},
+				AllowedHeaders:   []string{"Content-Type", "X-CSRF-Token"},
+				ExposedHeaders:   []string{"ETag", "X-CSRF-Token"},
+				AllowCredentials: tt.credentials,
+				MaxAge:           10 * time.Minute,
+			}
+			var reached bool
+			h := CORS(config.NewStore(cfg, nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { reached = true }))
+
+			r := httptest.NewRequest(tt.method, "/media", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.reqMethod != "" {
+				r.Header.Set("Access-Control-Request-Method", tt.reqMethod)
+			}
+			if tt.reqHeaders != "" {
+				r.Header.Set("Access-Control-Request-Headers", tt.reqHeaders)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if reached != tt.wantNext {
+				t.Errorf("next handler reached = %v, want %v", reached, tt.wantNext)
+			}
+			for name, want := range tt.wantHeaders {
+				if got := w.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
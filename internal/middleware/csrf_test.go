@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/myapp/internal/auth"
+	"example.com/myapp/internal/config"
+)
+
+func TestCSRF(t *testing.T) {
+	tokenSecret := []byte("token-secret-0123456789abcdef0123")
+	csrfSecret := "csrf-secret-0123456789abcdef01234"
+	session := func(subject string) string {
+		tok, err := auth.IssueToken(tokenSecret, subject, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tok
+	}
+	alice, bob := session("alice"), session("bob")
+	aliceToken := csrfToken([]byte(csrfSecret), auth.Principal{ID: "alice"}, alice)
+
+	tests := []struct {
+		name          string
+		csrfSecret    string
+		method        string
+		cookie        string
+		authorization string
+		csrfHeader    string
+		wantStatus    int
+		wantToken     string
+	}{
+		{name: "anonymous write", method: http.MethodPost, wantStatus: http.StatusOK},
+		{name: "bearer write", method: http.MethodPost, authorization: "Bearer " + alice, wantStatus: http.StatusOK},
+		{name: "cookie read issues token", method: http.MethodGet, cookie: alice, wantStatus: http.StatusOK, wantToken: aliceToken},
+		{name: "cookie write with token", method: http.MethodPost, cookie: alice, csrfHeader: aliceToken, wantStatus: http.StatusOK, wantToken: aliceToken},
+		{name: "cookie delete with token", method: http.MethodDelete, cookie: alice, csrfHeader: aliceToken, wantStatus: http.StatusOK, wantToken: aliceToken},
+		{name: "cookie write without token", method: http.MethodPost, cookie: alice, wantStatus: http.StatusForbidden, wantToken: aliceToken},
+		{name: "cookie write with another session's token", method: http.MethodPut, cookie: bob, csrfHeader: aliceToken, wantStatus: http.StatusForbidden},
+		{name: "forged session cookie", method: http.MethodPost, cookie: "alice", csrfHeader: csrfToken([]byte(csrfSecret), auth.Principal{ID: "alice"}, "alice"), wantStatus: http.StatusUnauthorized},
+		{name: "no secret fails closed", csrfSecret: "-", method: http.MethodGet, cookie: alice, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Security.CSRFSecret = csrfSecret
+			if tt.csrfSecret == "-" {
+				cfg.Security.CSRFSecret = ""
+			}
+			h := Authenticate(tokenSecret)(CSRF(config.NewStore(cfg, nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+			r := httptest.NewRequest(tt.method, "/collections", nil)
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: auth.SessionCookie, Value: tt.cookie})
+			}
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.csrfHeader != "" {
+				r.Header.Set(CSRFHeader, tt.csrfHeader)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if got := w.Header().Get(CSRFHeader); tt.wantToken != "" && got != tt.wantToken {
+				t.Errorf("%s = %q, want %q", CSRFHeader, got, tt.wantToken)
+			}
+			if tt.cookie == "" && w.Header().Get(CSRFHeader) != "" {
+				t.Errorf("token issued to a request without a session")
+			}
+		})
+	}
+}
+
+func TestCSRFTokenIsStable(t *testing.T) {
+	secret := []byte("csrf-secret-0123456789abcdef01234")
+	alice := auth.Principal{ID: "alice"}
+
+	tests := []struct {
+		name      string
+		secret    []byte
+		principal auth.Principal
+		session   string
+		wantSame  bool
+	}{
+		{name: "same inputs, e.g. after a restart", secret: secret, principal: alice, session: "s1", wantSame: true},
+		{name: "other session", secret: secret, principal: alice, session: "s2"},
+		{name: "other principal", secret: secret, principal: auth.Principal{ID: "bob"}, session: "s1"},
+		{name: "rotated secret", secret: []byte("rotated-secret-0123456789abcdef01"), principal: alice, session: "s1"},
+	}
+
+	want := csrfToken(secret, alice, "s1")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := csrfToken(tt.secret, tt.principal, tt.session); (got == want) != tt.wantSame {
+				t.Errorf("csrfToken() = %q, same as original: %v, want %v", got, got == want, tt.wantSame)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net/http"
 
+	appErr "example.com/myapp/internal/errors"
 	"example.com/myapp/internal/users"
 )
 
@@ -17,16 +18,16 @@ func LoadUserMiddleware(repo users.Repository) func(next http.Handler) http.Hand
 			// Get validated ID from context (set by ValidateIDMiddleware)
 			id, ok := r.Context().Value("userID").(int)
 			if !ok {
-				slog.Error("User ID not found in context")
-				http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+				slog.ErrorContext(r.Context(), "User ID not found in context")
+				appErr.WriteError(w, r, appErr.Internal("user ID not found in context", nil))
 				return
 			}
 
 			// Fetch user from repository with request context
 			user, err := repo.GetByID(r.Context(), id)
 			if err != nil {
-				slog.Error("Failed to load user", "id", id, "error", err)
-				http.Error(w, "User not found", http.StatusNotFound)
+				slog.ErrorContext(r.Context(), "Failed to load user", "id", id, "error", err)
+				appErr.WriteError(w, r, appErr.NotFound("user not found"))
 				return
 			}
 
@@ -2,31 +2,81 @@ package middleware
 
 import (
 	"log/slog"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"slices"
 	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"example.com/myapp/internal/auth"
+	"example.com/myapp/internal/config"
 )
 
-// LoggingMiddleware logs HTTP request details with structured logging
-func LoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-
-		// Log request
-		slog.Info("Request received",
-			"method", r.Method,
-			"path", r.RequestURI,
-			"remote_addr", r.RemoteAddr,
-		)
-
-		next.ServeHTTP(w, r)
-
-		// Log response time
-		duration := time.Since(start)
-		slog.Info("Request completed",
-			"method", r.Method,
-			"path", r.RequestURI,
-			"duration_ms", duration.Milliseconds(),
-		)
-	})
+// redacted replaces the values of redacted query parameters
+const redacted = "REDACTED"
+
+// AccessLog returns a middleware that writes one structured access log
+// entry per request once the response is complete: status, bytes written,
+// latency, user agent and principal. The request ID and route pattern are
+// attached by the context log handler. Successful requests are sampled at
+// log.access_sample_rate; failed ones are always logged.
+func AccessLog(settings *config.Store) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r)
+
+			// Handlers that never write still answer 200
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			cfg := settings.Current().Log
+			if status < http.StatusBadRequest && rand.Float64() >= cfg.AccessSampleRate {
+				return
+			}
+
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			slog.Log(r.Context(), level, "Request completed",
+				"method", r.Method,
+				"path", redactURL(r.URL, cfg.RedactQueryParams),
+				"status", status,
+				"bytes", ww.BytesWritten(),
+				"duration_ms", time.Since(start).Milliseconds(),
+				"user_agent", r.UserAgent(),
+				"principal", auth.FromRequest(r).ID,
+				"remote_addr", r.RemoteAddr,
+			)
+		})
+	}
 }
 
+// redactURL returns the path and query of u with the values of the given
+// parameters replaced
+func redactURL(u *url.URL, params []string) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		// An unparsable query may hide anything; drop it entirely
+		return u.Path + "?" + redacted
+	}
+	for name, values := range query {
+		if slices.Contains(params, "*") || slices.Contains(params, name) {
+			for i := range values {
+				values[i] = redacted
+			}
+		}
+	}
+	return u.Path + "?" + query.Encode()
+}
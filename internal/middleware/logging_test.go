@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"example.com/myapp/internal/config"
+	"example.com/myapp/internal/logging"
+)
+
+// captureLogs sends the default logger to a buffer for the rest of the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(logging.NewContextHandler(slog.NewJSONHandler(&buf, nil))))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func TestAccessLog(t *testing.T) {
+	tests := []struct {
+		name       string
+		sampleRate float64
+		target     string
+		status     int
+		body       string
+		principal  string
+		wantLogged bool
+		wantLevel  string
+		wantPath   string
+	}{
+		{name: "success", sampleRate: 1, target: "/users/42", status: http.StatusOK, body: "hello", principal: "alice", wantLogged: true, wantLevel: "INFO", wantPath: "/users/42"},
+		{name: "implicit 200", sampleRate: 1, target: "/users/42", wantLogged: true, wantLevel: "INFO", wantPath: "/users/42"},
+		{name: "success not sampled", sampleRate: 0, target: "/users/42", status: http.StatusOK},
+		{name: "client error always logged", sampleRate: 0, target: "/users/42", status: http.StatusNotFound, wantLogged: true, wantLevel: "INFO", wantPath: "/users/42"},
+		{name: "server error logged as error", sampleRate: 0, target: "/users/42", status: http.StatusBadGateway, wantLogged: true, wantLevel: "ERROR", wantPath: "/users/42"},
+		{name: "query redacted", sampleRate: 1, target: "/users/42?token=secret&page=2", status: http.StatusOK, wantLogged: true, wantLevel: "INFO", wantPath: "/users/42?page=2&token=REDACTED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureLogs(t)
+			cfg := config.Default()
+			cfg.Log.AccessSampleRate = tt.sampleRate
+			cfg.Log.RedactQueryParams = []string{"token"}
+
+			r := chi.NewRouter()
+			r.Use(AccessLog(config.NewStore(cfg, nil)))
+			r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				w.Write([]byte(tt.body))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Header.Set("User-Agent", "test-agent")
+			if tt.principal != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.principal)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			if !tt.wantLogged {
+				if buf.Len() != 0 {
+					t.Errorf("logged %s, want nothing", buf)
+				}
+				return
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("decode %q: %v", buf, err)
+			}
+			wantStatus := tt.status
+			if wantStatus == 0 {
+				wantStatus = http.StatusOK
+			}
+			checks := map[string]interface{}{
+				"level":      tt.wantLevel,
+				"msg":        "Request completed",
+				"method":     "GET",
+				"path":       tt.wantPath,
+				"route":      "/users/{id}",
+				"status":     float64(wantStatus),
+				"bytes":      float64(len(tt.body)),
+				"user_agent": "test-agent",
+			}
+			for key, want := range checks {
+				if entry[key] != want {
+					t.Errorf("%s = %v, want %v", key, entry[key], want)
+				}
+			}
+			if _, ok := entry["duration_ms"]; !ok {
+				t.Error("duration_ms missing")
+			}
+			if tt.principal != "" && entry["principal"] != tt.principal {
+				t.Errorf("principal = %v, want %s", entry["principal"], tt.principal)
+			}
+		})
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		params []string
+		want   string
+	}{
+		{name: "no query", target: "/media/1", params: []string{"token"}, want: "/media/1"},
+		{name: "listed parameter", target: "/media/1?token=abc&x=1", params: []string{"token"}, want: "/media/1?token=REDACTED&x=1"},
+		{name: "repeated parameter", target: "/media/1?token=a&token=b", params: []string{"token"}, want: "/media/1?token=REDACTED&token=REDACTED"},
+		{name: "wildcard", target: "/media/1?a=1&b=2", params: []string{"*"}, want: "/media/1?a=REDACTED&b=REDACTED"},
+		{name: "nothing configured", target: "/media/1?token=abc", want: "/media/1?token=abc"},
+		{name: "unparsable query dropped", target: "/media/1?token=%zz", params: []string{"other"}, want: "/media/1?REDACTED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.ParseRequestURI(tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := redactURL(u, tt.params); got != tt.want {
+				t.Errorf("redactURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
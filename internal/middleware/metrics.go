@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"example.com/myapp/internal/metrics"
+)
+
+var (
+	httpRequests = metrics.NewCounterVec(
+		"http_requests_total",
+		"HTTP requests by method, route pattern and status code.",
+		"method", "route", "status",
+	)
+	httpRequestDuration = metrics.NewHistogramVec(
+		"http_request_duration_seconds",
+		"HTTP request latency by method, route pattern and status code.",
+		nil,
+		"method", "route", "status",
+	)
+	httpRequestsInFlight = metrics.NewGaugeVec(
+		"http_requests_in_flight",
+		"HTTP requests currently being served.",
+	)
+)
+
+// Metrics records the count, latency and concurrency of HTTP requests.
+// Requests are labelled with the chi route pattern rather than the path so
+// IDs do not create a series per resource; unmatched requests use
+// "unmatched".
+// Usage: Apply globally in routes.SetupRoutes
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		httpRequestsInFlight.Add(1)
+		defer httpRequestsInFlight.Add(-1)
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		labels := []string{r.Method, route, strconv.Itoa(status)}
+		httpRequests.Inc(labels...)
+		httpRequestDuration.Observe(time.Since(start).Seconds(), labels...)
+	})
+}
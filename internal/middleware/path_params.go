@@ -5,7 +5,9 @@ import (
 	"net/http"
 	"strconv"
 
+	appErr "example.com/myapp/internal/errors"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 // ValidateIDMiddleware validates and extracts the ID path parameter
@@ -13,11 +15,11 @@ import (
 func ValidateIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		idStr := chi.URLParam(r, "id")
-		
+
 		// Validate that ID is a valid integer
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			http.Error(w, "Invalid ID format", http.StatusBadRequest)
+			appErr.WriteError(w, r, appErr.InvalidID("invalid ID format"))
 			return
 		}
 
@@ -26,3 +28,18 @@ func ValidateIDMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// ValidateUUIDMiddleware rejects requests whose ID path parameter is not a
+// UUID in canonical lowercase form, the form IDs are generated in, so
+// malformed IDs never reach a repository
+func ValidateUUIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+
+		if id, err := uuid.Parse(idStr); err != nil || id.String() != idStr {
+			appErr.WriteError(w, r, appErr.InvalidID("invalid ID format: expected a UUID"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
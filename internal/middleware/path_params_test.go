@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestValidateUUIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want int
+	}{
+		{name: "canonical", id: "123e4567-e89b-12d3-a456-426614174000", want: http.StatusOK},
+		{name: "uppercase", id: "123E4567-E89B-12D3-A456-426614174000", want: http.StatusBadRequest},
+		{name: "without dashes", id: "123e4567e89b12d3a456426614174000", want: http.StatusBadRequest},
+		{name: "urn form", id: "urn:uuid:123e4567-e89b-12d3-a456-426614174000", want: http.StatusBadRequest},
+		{name: "integer", id: "42", want: http.StatusBadRequest},
+		{name: "path traversal", id: "..%2F..%2Fetc", want: http.StatusBadRequest},
+	}
+
+	r := chi.NewRouter()
+	r.With(ValidateUUIDMiddleware).Get("/media/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/media/"+tt.id, nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"example.com/myapp/internal/auth"
+	"example.com/myapp/internal/config"
+	"example.com/myapp/internal/ratelimit"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (ratelimit.Decision, error) {
+	return ratelimit.Decision{}, errors.New("store unavailable")
+}
+
+func TestRateLimit(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	token := func(subject string) string {
+		tok, err := auth.IssueToken(secret, subject, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + tok
+	}
+	alice, bob := token("alice"), token("bob")
+
+	type request struct {
+		authorization string
+		remote        string
+		forwardedFor  string
+		want          int
+	}
+	tests := []struct {
+		name     string
+		store    ratelimit.Store
+		group    string
+		requests []request
+	}{
+		{
+			name: "principal budget",
+			requests: []request{
+				{authorization: alice, want: http.StatusOK},
+				{authorization: alice, want: http.StatusOK},
+				{authorization: alice, want: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "principals have separate budgets on one address",
+			requests: []request{
+				{authorization: alice, want: http.StatusOK},
+				{authorization: alice, want: http.StatusOK},
+				{authorization: bob, want: http.StatusOK},
+				{authorization: bob, want: http.StatusOK},
+				{authorization: bob, want: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "principal budget follows the principal across addresses",
+			requests: []request{
+				{authorization: alice, remote: "203.0.113.1:1", want: http.StatusOK},
+				{authorization: alice, remote: "203.0.113.2:1", want: http.StatusOK},
+				{authorization: alice, remote: "203.0.113.3:1", want: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "invented principals are rejected before the limiter",
+			requests: []request{
+				{want: http.StatusOK},
+				{want: http.StatusOK},
+				{authorization: "Bearer mallory", want: http.StatusUnauthorized},
+				{authorization: "Bearer eve", want: http.StatusUnauthorized},
+				{want: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "anonymous budget per client address",
+			requests: []request{
+				{remote: "203.0.113.1:1", want: http.StatusOK},
+				{remote: "203.0.113.1:2", want: http.StatusOK},
+				{remote: "203.0.113.1:3", want: http.StatusTooManyRequests},
+				{remote: "203.0.113.2:1", want: http.StatusOK},
+			},
+		},
+		{
+			name: "forwarded address from trusted proxy",
+			requests: []request{
+				{remote: "10.0.0.2:1", forwardedFor: "198.51.100.1", want: http.StatusOK},
+				{remote: "10.0.0.3:1", forwardedFor: "198.51.100.1", want: http.StatusOK},
+				{remote: "10.0.0.2:1", forwardedFor: "198.51.100.1", want: http.StatusTooManyRequests},
+				{remote: "10.0.0.2:1", forwardedFor: "198.51.100.2", want: http.StatusOK},
+			},
+		},
+		{
+			name: "forwarded address from untrusted peer ignored",
+			requests: []request{
+				{remote: "203.0.113.1:1", forwardedFor: "198.51.100.1", want: http.StatusOK},
+				{remote: "203.0.113.1:1", forwardedFor: "198.51.100.2", want: http.StatusOK},
+				{remote: "203.0.113.1:1", forwardedFor: "198.51.100.3", want: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:  "ungrouped requests are not limited",
+			group: "-",
+			requests: []request{
+				{want: http.StatusOK},
+				{want: http.StatusOK},
+				{want: http.StatusOK},
+			},
+		},
+		{
+			name:  "store failure allows requests",
+			store: failingStore{},
+			requests: []request{
+				{want: http.StatusOK},
+				{want: http.StatusOK},
+				{want: http.StatusOK},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.RateLimit.Reads = config.Rate{Requests: 2, Period: time.Minute}
+			cfg.RateLimit.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+			store := tt.store
+			if store == nil {
+				store = ratelimit.NewMemoryStore()
+			}
+			group := func(*http.Request) string {
+				if tt.group == "-" {
+					return ""
+				}
+				return "reads"
+			}
+			h := Authenticate(secret)(RateLimit(config.NewStore(cfg, nil), store, group)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+			for i, req := range tt.requests {
+				r := httptest.NewRequest(http.MethodGet, "/users", nil)
+				r.RemoteAddr = "192.0.2.1:1234"
+				if req.remote != "" {
+					r.RemoteAddr = req.remote
+				}
+				if req.authorization != "" {
+					r.Header.Set("Authorization", req.authorization)
+				}
+				if req.forwardedFor != "" {
+					r.Header.Set("X-Forwarded-For", req.forwardedFor)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+
+				if w.Code != req.want {
+					t.Fatalf("request %d: status = %d, want %d", i, w.Code, req.want)
+				}
+				limited := tt.group != "-" && tt.store == nil && req.want != http.StatusUnauthorized
+				if got := w.Header().Get("RateLimit-Policy"); limited && got != "2;w=60" {
+					t.Errorf("request %d: RateLimit-Policy = %q, want 2;w=60", i, got)
+				}
+				if !limited && w.Header().Get("RateLimit-Limit") != "" {
+					t.Errorf("request %d: unexpected RateLimit headers", i)
+				}
+				if req.want == http.StatusTooManyRequests {
+					if got := w.Header().Get("Retry-After"); got != "30" {
+						t.Errorf("request %d: Retry-After = %q, want 30", i, got)
+					}
+					if got := w.Header().Get("RateLimit-Remaining"); got != "0" {
+						t.Errorf("request %d: RateLimit-Remaining = %q, want 0", i, got)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"example.com/myapp/internal/requestid"
+)
+
+// RequestID assigns every request a correlation ID. A valid X-Request-ID
+// sent by the client is kept, otherwise a new one is generated. The ID is
+// stored in the request context and echoed in the X-Request-ID response
+// header.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+	})
+}
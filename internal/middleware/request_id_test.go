@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/requestid"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantKept bool
+	}{
+		{name: "generated when missing"},
+		{name: "client ID kept", header: "client-req.42", wantKept: true},
+		{name: "invalid client ID replaced", header: "bad id\r\nX-Injected: 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = requestid.FromContext(r.Context())
+				appErr.WriteError(w, r, appErr.NotFound("no such thing"))
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/things/1", nil)
+			if tt.header != "" {
+				r.Header.Set(requestid.Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			echoed := w.Header().Get(requestid.Header)
+			if !requestid.Valid(echoed) || echoed != seen {
+				t.Fatalf("echoed %q, context %q", echoed, seen)
+			}
+			if tt.wantKept != (echoed == tt.header) {
+				t.Errorf("echoed %q for client ID %q, want kept %v", echoed, tt.header, tt.wantKept)
+			}
+
+			var body struct {
+				RequestID string `json:"request_id"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.RequestID != echoed {
+				t.Errorf("error body request_id = %q, want %q", body.RequestID, echoed)
+			}
+		})
+	}
+}
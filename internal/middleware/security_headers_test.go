@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/myapp/internal/config"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name        string
+		configure   func(*config.SecurityConfig)
+		tls         bool
+		proto       string
+		wantHeaders map[string]string
+	}{
+		{
+			name: "defaults over HTTP",
+			wantHeaders: map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"Referrer-Policy":           "no-referrer",
+				"Content-Security-Policy":   "default-src 'none'; frame-ancestors 'none'",
+				"X-Frame-Options":           "DENY",
+				"Strict-Transport-Security": "",
+			},
+		},
+		{
+			name: "HSTS over TLS", tls: true,
+			wantHeaders: map[string]string{"Strict-Transport-Security": "max-age=31536000; includeSubDomains"},
+		},
+		{
+			name: "HSTS behind a TLS-terminating proxy", proto: "https",
+			wantHeaders: map[string]string{"Strict-Transport-Security": "max-age=31536000; includeSubDomains"},
+		},
+		{
+			name: "HSTS disabled", tls: true,
+			configure:   func(c *config.SecurityConfig) { c.HSTSMaxAge = 0 },
+			wantHeaders: map[string]string{"Strict-Transport-Security": ""},
+		},
+		{
+			name: "custom policies",
+			configure: func(c *config.SecurityConfig) {
+				c.ContentSecurityPolicy = "default-src 'self'"
+				c.FrameOptions = "SAMEORIGIN"
+				c.HSTSMaxAge = time.Hour
+			},
+			tls: true,
+			wantHeaders: map[string]string{
+				"Content-Security-Policy":   "default-src 'self'",
+				"X-Frame-Options":           "SAMEORIGIN",
+				"Strict-Transport-Security": "max-age=3600; includeSubDomains",
+			},
+		},
+		{
+			name: "optional headers omitted",
+			configure: func(c *config.SecurityConfig) {
+				c.ContentSecurityPolicy = ""
+				c.FrameOptions = ""
+			},
+			wantHeaders: map[string]string{
+				"X-Content-Type-Options":  "nosniff",
+				"Content-Security-Policy": "",
+				"X-Frame-Options":         "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			if tt.configure != nil {
+				tt.configure(&cfg.Security)
+			}
+			// Headers must be present even on error responses
+			h := SecurityHeaders(config.NewStore(cfg, nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/media", nil)
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			if tt.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			for name, want := range tt.wantHeaders {
+				if got := w.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
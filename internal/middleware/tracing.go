@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"example.com/myapp/internal/tracing"
+)
+
+// Tracing starts a server span for every request, continuing the trace of
+// an incoming W3C traceparent header. The span is named after the method
+// and route pattern once routing has completed, and 5xx responses mark it
+// as failed.
+// Usage: Apply globally in routes.SetupRoutes, before handlers log
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.Extract(r.Context(), r.Header)
+		ctx, span := tracing.Start(ctx, r.Method, tracing.WithKind(tracing.KindServer), tracing.WithAttributes(
+			tracing.Attr("http.request.method", r.Method),
+			tracing.Attr("url.path", r.URL.Path),
+			tracing.Attr("user_agent.original", r.UserAgent()),
+		))
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(tracing.Attr("http.route", rctx.RoutePattern()))
+		}
+		span.SetAttributes(tracing.Attr("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetError(fmt.Errorf("HTTP %d", status))
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package outbox
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/validation"
+)
+
+// Dead letter query limits
+const (
+	DefaultDeadLetterLimit = 100
+	MaxDeadLetterLimit     = 1000
+)
+
+type Handler struct {
+	relay *Relay
+}
+
+func NewHandler(relay *Relay) *Handler {
+	return &Handler{relay: relay}
+}
+
+// RegisterRoutes registers the outbox routes with appropriate middleware
+// Middleware is passed as parameters to avoid circular imports
+func (h *Handler) RegisterRoutes(r chi.Router, loggingMw, authMw, adminMw func(http.Handler) http.Handler) {
+	r.Route("/outbox", func(r chi.Router) {
+		r.Use(loggingMw)
+		r.Use(authMw)
+		r.Use(adminMw)
+
+		r.Get("/metrics", h.GetMetrics)
+		r.Get("/dead-letters", h.ListDeadLetters)
+		r.Post("/dead-letters/{id}/requeue", h.Requeue)
+	})
+}
+
+// GetMetrics reports outbox lag and relay progress - GET /outbox/metrics
+func (h *Handler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	metrics, err := h.relay.Metrics(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get outbox metrics", "error", err)
+		appErr.WriteError(w, r, appErr.Internal("failed to get outbox metrics", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(metrics)
+}
+
+// ListDeadLetters returns entries that exhausted their attempts -
+// GET /outbox/dead-letters?limit=
+func (h *Handler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	v := validation.New()
+	limit := v.Int("limit", r.URL.Query().Get("limit"), DefaultDeadLetterLimit)
+	v.Range("limit", limit, 1, MaxDeadLetterLimit)
+	if err := v.Err(); err != nil {
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	entries, err := h.relay.DeadLettered(r.Context(), limit)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list outbox dead letters", "error", err)
+		appErr.WriteError(w, r, appErr.Internal("failed to list outbox dead letters", err))
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &EntryListResponse{Total: len(entries), Entries: entries})
+}
+
+// Requeue retries a dead-lettered entry - POST /outbox/dead-letters/{id}/requeue
+func (h *Handler) Requeue(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		appErr.WriteError(w, r, appErr.BadRequest("outbox entry ID must be a positive integer"))
+		return
+	}
+
+	entry, err := h.relay.Requeue(r.Context(), id)
+	if err != nil {
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Outbox entry requeued", "entry_id", id)
+	respondJSON(w, http.StatusAccepted, entry)
+}
+
+func respondJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
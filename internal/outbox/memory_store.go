@@ -0,0 +1,218 @@
+package outbox
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/events"
+	"example.com/myapp/internal/tracing"
+)
+
+// InMemoryStore is an in-memory implementation of Store
+type InMemoryStore struct {
+	mu      sync.Mutex
+	entries map[int64]*Entry
+	nextID  int64
+	notify  chan struct{}
+}
+
+// NewInMemoryStore creates an empty outbox
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{
+		entries: make(map[int64]*Entry),
+		nextID:  1,
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// Append stages events for the relay
+func (s *InMemoryStore) Append(ctx context.Context, evts ...events.Event) error {
+	if err := ctx.Err(); err != nil {
+		return appErr.Internal("context cancelled", err)
+	}
+
+	var traceParent string
+	if sc := tracing.SpanContextFromContext(ctx); sc.IsValid() {
+		traceParent = tracing.FormatTraceParent(sc)
+	}
+
+	s.mu.Lock()
+	now := time.Now().UTC()
+	for _, event := range evts {
+		s.entries[s.nextID] = &Entry{ID: s.nextID, Event: event, CreatedAt: now, NextAttemptAt: now, TraceParent: traceParent}
+		s.nextID++
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Due returns pending entries in write order
+func (s *InMemoryStore) Due(ctx context.Context, now time.Time, limit int) ([]*Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := make([]*Entry, 0)
+	for _, e := range s.entries {
+		if e.DeadLetteredAt.IsZero() && !e.NextAttemptAt.After(now) {
+			c := *e
+			due = append(due, &c)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].ID < due[j].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// MarkDelivered drops a published entry
+func (s *InMemoryStore) MarkDelivered(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return appErr.Internal("context cancelled", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[id]; !exists {
+		return appErr.NotFound("outbox entry not found")
+	}
+	delete(s.entries, id)
+	return nil
+}
+
+// MarkFailed schedules another attempt for an entry
+func (s *InMemoryStore) MarkFailed(ctx context.Context, id int64, cause error, next time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return appErr.Internal("context cancelled", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, exists := s.entries[id]
+	if !exists {
+		return appErr.NotFound("outbox entry not found")
+	}
+	e.Attempts++
+	e.LastError = cause.Error()
+	e.NextAttemptAt = next
+	return nil
+}
+
+// MarkDeadLettered stops retrying an entry
+func (s *InMemoryStore) MarkDeadLettered(ctx context.Context, id int64, cause error, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return appErr.Internal("context cancelled", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, exists := s.entries[id]
+	if !exists {
+		return appErr.NotFound("outbox entry not found")
+	}
+	e.Attempts++
+	e.LastError = cause.Error()
+	e.DeadLetteredAt = at
+	return nil
+}
+
+// DeadLettered returns dead-lettered entries in write order
+func (s *InMemoryStore) DeadLettered(ctx context.Context, limit int) ([]*Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dead := make([]*Entry, 0)
+	for _, e := range s.entries {
+		if !e.DeadLetteredAt.IsZero() {
+			c := *e
+			dead = append(dead, &c)
+		}
+	}
+	sort.Slice(dead, func(i, j int) bool {
+		return dead[i].ID < dead[j].ID
+	})
+	if len(dead) > limit {
+		dead = dead[:limit]
+	}
+	return dead, nil
+}
+
+// Requeue makes a dead-lettered entry due again
+func (s *InMemoryStore) Requeue(ctx context.Context, id int64, now time.Time) (*Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	s.mu.Lock()
+	e, exists := s.entries[id]
+	if !exists {
+		s.mu.Unlock()
+		return nil, appErr.NotFound("outbox entry not found")
+	}
+	if e.DeadLetteredAt.IsZero() {
+		s.mu.Unlock()
+		return nil, appErr.Conflict("only dead-lettered outbox entries can be requeued")
+	}
+	e.Attempts = 0
+	e.DeadLetteredAt = time.Time{}
+	e.NextAttemptAt = now
+	c := *e
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return &c, nil
+}
+
+// Stats counts pending and dead-lettered entries and finds the oldest
+// pending one
+func (s *InMemoryStore) Stats(ctx context.Context) (Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return Stats{}, appErr.Internal("context cancelled", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stats Stats
+	for _, e := range s.entries {
+		if !e.DeadLetteredAt.IsZero() {
+			stats.DeadLettered++
+			continue
+		}
+		stats.Pending++
+		if stats.OldestCreatedAt.IsZero() || e.CreatedAt.Before(stats.OldestCreatedAt) {
+			stats.OldestCreatedAt = e.CreatedAt
+		}
+	}
+	return stats, nil
+}
+
+// Notify signals when entries are appended so the relay need not wait for
+// its next poll
+func (s *InMemoryStore) Notify() <-chan struct{} {
+	return s.notify
+}
@@ -0,0 +1,23 @@
+package outbox
+
+import "example.com/myapp/internal/metrics"
+
+var (
+	outboxLag = metrics.NewGaugeVec(
+		"outbox_lag_seconds",
+		"Age of the oldest pending outbox entry; 0 when nothing is pending.",
+	)
+	outboxPending = metrics.NewGaugeVec(
+		"outbox_pending_entries",
+		"Outbox entries waiting to be published.",
+	)
+	outboxDeadLettered = metrics.NewGaugeVec(
+		"outbox_dead_lettered_entries",
+		"Outbox entries that exhausted their attempts and wait to be requeued.",
+	)
+	outboxPublished = metrics.NewCounterVec(
+		"outbox_published_total",
+		"Outbox entries published by outcome: delivered, failed or dead_lettered.",
+		"outcome",
+	)
+)
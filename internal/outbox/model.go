@@ -0,0 +1,56 @@
+package outbox
+
+import (
+	"time"
+
+	"example.com/myapp/internal/events"
+)
+
+// Entry is an event waiting in the outbox. The event ID doubles as the
+// idempotency key consumers use to discard redeliveries.
+type Entry struct {
+	ID            int64        `json:"id"`
+	Event         events.Event `json:"event"`
+	CreatedAt     time.Time    `json:"created_at"`
+	Attempts      int          `json:"attempts"`
+	LastError     string       `json:"last_error,omitempty"`
+	NextAttemptAt time.Time    `json:"next_attempt_at"`
+	// DeadLetteredAt is set once the entry failed RelayMaxAttempts times;
+	// the relay skips it until it is requeued
+	DeadLetteredAt time.Time `json:"dead_lettered_at,omitzero"`
+	// TraceParent is the W3C trace context of the write that staged the
+	// event, so publishing continues the same trace
+	TraceParent string `json:"trace_parent,omitempty"`
+}
+
+// Stats summarises the undelivered part of the outbox
+type Stats struct {
+	Pending      int
+	DeadLettered int
+	// OldestCreatedAt is when the oldest pending entry was written; zero
+	// when nothing is pending. Dead-lettered entries are not considered.
+	OldestCreatedAt time.Time
+}
+
+// EntryListResponse is the response when listing dead-lettered entries
+type EntryListResponse struct {
+	Total   int      `json:"total"`
+	Entries []*Entry `json:"entries"`
+}
+
+// Metrics describes how far the relay is behind the writes
+type Metrics struct {
+	Pending      int `json:"pending"`
+	DeadLettered int `json:"dead_lettered"`
+	// LagSeconds is the age of the oldest undelivered entry
+	LagSeconds float64 `json:"lag_seconds"`
+	// LastDeliveryLagSeconds is how long the last delivered entry waited
+	LastDeliveryLagSeconds float64   `json:"last_delivery_lag_seconds"`
+	Delivered              int64     `json:"delivered_total"`
+	Failures               int64     `json:"failures_total"`
+	DeadLetters            int64     `json:"dead_letters_total"`
+	LastDeliveredAt        time.Time `json:"last_delivered_at,omitzero"`
+	// LastPolledAt is when the relay last checked for due entries; it is
+	// zero until Run is started
+	LastPolledAt time.Time `json:"last_polled_at,omitzero"`
+}
@@ -0,0 +1,225 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"example.com/myapp/internal/events"
+	"example.com/myapp/internal/tracing"
+)
+
+// Relay tuning
+const (
+	RelayPollInterval = 500 * time.Millisecond
+	RelayBatchSize    = 100
+	RelayMaxBackoff   = time.Minute
+	// RelayMaxAttempts failed publishes dead-letter an entry, about 13
+	// minutes after it was written
+	RelayMaxAttempts = 20
+)
+
+// Relay publishes due outbox entries oldest first. An entry is removed only
+// after every subscriber handled it, so a crash or failure causes
+// redelivery rather than loss; consumers deduplicate on the event ID.
+//
+// Delivery is at least once but not ordered: a failing entry is retried
+// with backoff while later entries are published, and after
+// RelayMaxAttempts failures it is dead-lettered until requeued.
+// Consumers that need ordering must compare the events' OccurredAt.
+type Relay struct {
+	store     Store
+	publisher events.Publisher
+	wake      <-chan struct{}
+
+	mu              sync.Mutex
+	delivered       int64
+	failures        int64
+	deadLetters     int64
+	lastPolledAt    time.Time
+	lastDeliveredAt time.Time
+	lastDeliveryLag time.Duration
+}
+
+// NewRelay creates a relay. wake may be nil, in which case new entries are
+// picked up on the next poll.
+func NewRelay(store Store, publisher events.Publisher, wake <-chan struct{}) *Relay {
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		wake:      wake,
+	}
+}
+
+// Run relays entries until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(RelayPollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.relayDue(ctx)
+
+		r.mu.Lock()
+		r.lastPolledAt = time.Now().UTC()
+		r.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+func (r *Relay) relayDue(ctx context.Context) {
+	due, err := r.store.Due(ctx, time.Now().UTC(), RelayBatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Failed to read outbox", "error", err)
+		}
+		return
+	}
+
+	for _, entry := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		r.relay(ctx, entry)
+	}
+	r.updateGauges(ctx)
+}
+
+// updateGauges publishes the outbox size and lag to the metrics registry
+func (r *Relay) updateGauges(ctx context.Context) {
+	stats, err := r.store.Stats(ctx)
+	if err != nil {
+		return
+	}
+	var lag float64
+	if !stats.OldestCreatedAt.IsZero() {
+		lag = time.Since(stats.OldestCreatedAt).Seconds()
+	}
+	outboxLag.Set(lag)
+	outboxPending.Set(float64(stats.Pending))
+	outboxDeadLettered.Set(float64(stats.DeadLettered))
+}
+
+func (r *Relay) relay(ctx context.Context, entry *Entry) {
+	// Continue the trace of the write that staged the event
+	if sc, ok := tracing.ParseTraceParent(entry.TraceParent); ok {
+		ctx = tracing.ContextWithRemoteSpanContext(ctx, sc)
+	}
+	ctx, span := tracing.Start(ctx, "outbox.publish "+entry.Event.Type, tracing.WithAttributes(
+		tracing.Attr("event.id", entry.Event.ID),
+		tracing.Attr("event.type", entry.Event.Type),
+	))
+	defer span.End()
+
+	if err := r.publisher.Publish(ctx, entry.Event); err != nil {
+		span.SetError(err)
+		r.mu.Lock()
+		r.failures++
+		r.mu.Unlock()
+
+		if entry.Attempts+1 >= RelayMaxAttempts {
+			slog.ErrorContext(ctx, "Outbox entry dead-lettered", "entry_id", entry.ID, "event_id", entry.Event.ID, "type", entry.Event.Type, "attempts", entry.Attempts+1, "error", err)
+			outboxPublished.Inc("dead_lettered")
+			r.mu.Lock()
+			r.deadLetters++
+			r.mu.Unlock()
+			if err := r.store.MarkDeadLettered(ctx, entry.ID, err, time.Now().UTC()); err != nil {
+				slog.ErrorContext(ctx, "Failed to dead-letter outbox entry", "entry_id", entry.ID, "error", err)
+			}
+			return
+		}
+
+		slog.WarnContext(ctx, "Failed to publish outbox entry", "entry_id", entry.ID, "event_id", entry.Event.ID, "type", entry.Event.Type, "attempt", entry.Attempts+1, "error", err)
+		outboxPublished.Inc("failed")
+		next := time.Now().UTC().Add(relayBackoff(entry.Attempts + 1))
+		if err := r.store.MarkFailed(ctx, entry.ID, err, next); err != nil {
+			slog.ErrorContext(ctx, "Failed to record outbox failure", "entry_id", entry.ID, "error", err)
+		}
+		return
+	}
+
+	if err := r.store.MarkDelivered(ctx, entry.ID); err != nil {
+		// The entry will be published again; consumers deduplicate
+		slog.ErrorContext(ctx, "Failed to mark outbox entry delivered", "entry_id", entry.ID, "error", err)
+		return
+	}
+
+	outboxPublished.Inc("delivered")
+	now := time.Now().UTC()
+	r.mu.Lock()
+	r.delivered++
+	r.lastDeliveredAt = now
+	r.lastDeliveryLag = now.Sub(entry.CreatedAt)
+	r.mu.Unlock()
+}
+
+// Metrics reports the relay's progress and how far it lags behind writes
+func (r *Relay) Metrics(ctx context.Context) (*Metrics, error) {
+	stats, err := r.store.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := &Metrics{
+		Pending:                stats.Pending,
+		DeadLettered:           stats.DeadLettered,
+		LastDeliveryLagSeconds: r.lastDeliveryLag.Seconds(),
+		Delivered:              r.delivered,
+		Failures:               r.failures,
+		DeadLetters:            r.deadLetters,
+		LastPolledAt:           r.lastPolledAt,
+		LastDeliveredAt:        r.lastDeliveredAt,
+	}
+	if !stats.OldestCreatedAt.IsZero() {
+		m.LagSeconds = time.Since(stats.OldestCreatedAt).Seconds()
+	}
+	return m, nil
+}
+
+// DeadLettered returns up to limit dead-lettered entries, oldest first
+func (r *Relay) DeadLettered(ctx context.Context, limit int) ([]*Entry, error) {
+	return r.store.DeadLettered(ctx, limit)
+}
+
+// Requeue gives a dead-lettered entry a fresh retry budget
+func (r *Relay) Requeue(ctx context.Context, id int64) (*Entry, error) {
+	return r.store.Requeue(ctx, id, time.Now().UTC())
+}
+
+// Healthy reports an error when the relay has stopped polling or the
+// oldest undelivered entry is older than maxLag
+func (r *Relay) Healthy(ctx context.Context, maxLag time.Duration) error {
+	m, err := r.Metrics(ctx)
+	if err != nil {
+		return err
+	}
+	if m.LastPolledAt.IsZero() {
+		return errors.New("relay has not started")
+	}
+	if since := time.Since(m.LastPolledAt); since > maxLag {
+		return fmt.Errorf("relay last polled %s ago", since.Round(time.Second))
+	}
+	if lag := time.Duration(m.LagSeconds * float64(time.Second)); lag > maxLag {
+		return fmt.Errorf("%d entries pending, the oldest for %s", m.Pending, lag.Round(time.Second))
+	}
+	return nil
+}
+
+// relayBackoff doubles from 100ms per failed attempt up to RelayMaxBackoff
+func relayBackoff(attempts int) time.Duration {
+	if attempts > 20 {
+		return RelayMaxBackoff
+	}
+	return min(100*time.Millisecond<<attempts, RelayMaxBackoff)
+}
@@ -0,0 +1,194 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/events"
+	"example.com/myapp/internal/metrics"
+)
+
+// recordingPublisher fails events whose ID is in fail and records the IDs
+// of the others in publish order
+type recordingPublisher struct {
+	fail      map[string]bool
+	published []string
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event events.Event) error {
+	if p.fail[event.ID] {
+		return errors.New("subscriber unavailable")
+	}
+	p.published = append(p.published, event.ID)
+	return nil
+}
+
+func appendEvents(t *testing.T, store *InMemoryStore, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		if err := store.Append(context.Background(), events.Event{ID: id, Type: events.UserCreated}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// makeDue lets the entries' backoff pass
+func makeDue(store *InMemoryStore) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, e := range store.entries {
+		e.NextAttemptAt = time.Time{}
+	}
+}
+
+func TestRelayDelivery(t *testing.T) {
+	tests := []struct {
+		name          string
+		fail          []string
+		attempts      int
+		wantPublished []string
+		wantPending   int
+		wantDead      int
+	}{
+		{name: "all published in write order", attempts: 1, wantPublished: []string{"a", "b", "c"}},
+		{name: "failing entry does not block later ones", fail: []string{"a"}, attempts: 1, wantPublished: []string{"b", "c"}, wantPending: 1},
+		{name: "retried until dead-lettered", fail: []string{"b"}, attempts: RelayMaxAttempts, wantPublished: []string{"a", "c"}, wantDead: 1},
+		{name: "pending below the attempt limit", fail: []string{"b"}, attempts: RelayMaxAttempts - 1, wantPublished: []string{"a", "c"}, wantPending: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewInMemoryStore()
+			appendEvents(t, store, "a", "b", "c")
+
+			pub := &recordingPublisher{fail: make(map[string]bool)}
+			for _, id := range tt.fail {
+				pub.fail[id] = true
+			}
+			relay := NewRelay(store, pub, nil)
+			for range tt.attempts {
+				makeDue(store)
+				relay.relayDue(ctx)
+			}
+
+			if strings.Join(pub.published, ",") != strings.Join(tt.wantPublished, ",") {
+				t.Errorf("published %v, want %v", pub.published, tt.wantPublished)
+			}
+			m, err := relay.Metrics(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Pending != tt.wantPending || m.DeadLettered != tt.wantDead || m.DeadLetters != int64(tt.wantDead) {
+				t.Errorf("pending %d, dead-lettered %d (%d total), want %d and %d", m.Pending, m.DeadLettered, m.DeadLetters, tt.wantPending, tt.wantDead)
+			}
+
+			// Dead-lettered entries are no longer retried
+			published := len(pub.published)
+			makeDue(store)
+			relay.relayDue(ctx)
+			if tt.wantDead > 0 && len(pub.published) != published {
+				t.Errorf("dead-lettered entry was published again")
+			}
+		})
+	}
+}
+
+func TestRelayRequeue(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	appendEvents(t, store, "a", "b")
+
+	pub := &recordingPublisher{fail: map[string]bool{"a": true}}
+	relay := NewRelay(store, pub, nil)
+	for range RelayMaxAttempts {
+		makeDue(store)
+		relay.relayDue(ctx)
+	}
+
+	dead, err := relay.DeadLettered(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].Event.ID != "a" || dead[0].Attempts != RelayMaxAttempts || dead[0].LastError == "" {
+		t.Fatalf("dead letters = %+v", dead)
+	}
+
+	if _, err := relay.Requeue(ctx, dead[0].ID+1); !appErr.HasCode(err, appErr.ErrCodeNotFound) {
+		t.Errorf("Requeue(delivered entry) error = %v, want NotFound", err)
+	}
+
+	delete(pub.fail, "a")
+	entry, err := relay.Requeue(ctx, dead[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Attempts != 0 || !entry.DeadLetteredAt.IsZero() {
+		t.Errorf("requeued entry = %+v", entry)
+	}
+	if _, err := relay.Requeue(ctx, dead[0].ID); !appErr.HasCode(err, appErr.ErrCodeConflict) {
+		t.Errorf("Requeue(pending entry) error = %v, want Conflict", err)
+	}
+
+	relay.relayDue(ctx)
+	if strings.Join(pub.published, ",") != "b,a" {
+		t.Errorf("published %v, want [b a]", pub.published)
+	}
+}
+
+func TestRelayLagGauge(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	appendEvents(t, store, "a")
+	store.entries[1].CreatedAt = time.Now().Add(-time.Minute)
+
+	relay := NewRelay(store, &recordingPublisher{fail: map[string]bool{"a": true}}, nil)
+	relay.relayDue(ctx)
+
+	var out strings.Builder
+	if _, err := metrics.Default.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# TYPE outbox_lag_seconds gauge\noutbox_lag_seconds 60", "outbox_pending_entries 1", `outbox_published_total{outcome="failed"}`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics output lacks %q", want)
+		}
+	}
+}
+
+func TestZeroTimesAreOmitted(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value any
+		key   string
+		want  bool
+	}{
+		{name: "live entry", value: Entry{}, key: "dead_lettered_at"},
+		{name: "dead-lettered entry", value: Entry{DeadLetteredAt: at}, key: "dead_lettered_at", want: true},
+		{name: "nothing delivered", value: Metrics{}, key: "last_delivered_at"},
+		{name: "relay not started", value: Metrics{}, key: "last_polled_at"},
+		{name: "relay polled", value: Metrics{LastPolledAt: at}, key: "last_polled_at", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(data, &fields); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := fields[tt.key]; ok != tt.want {
+				t.Errorf("%s present = %v, want %v: %s", tt.key, ok, tt.want, data)
+			}
+		})
+	}
+}
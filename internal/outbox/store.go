@@ -0,0 +1,55 @@
+// Package outbox implements the transactional outbox: repositories stage
+// events in the same write as the change they describe, and a relay
+// publishes them afterwards with at-least-once delivery.
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"example.com/myapp/internal/events"
+)
+
+// Store persists outbox entries
+type Store interface {
+	// Append stages events. Repositories call it as part of the write the
+	// events describe so both are stored or neither is; a SQL
+	// implementation would use the same transaction.
+	Append(ctx context.Context, evts ...events.Event) error
+	// Due returns up to limit undelivered entries whose next attempt is due
+	// at now, oldest first. Dead-lettered entries are never due.
+	Due(ctx context.Context, now time.Time, limit int) ([]*Entry, error)
+	// MarkDelivered removes an entry after it has been published
+	MarkDelivered(ctx context.Context, id int64) error
+	// MarkFailed records a failed publish and when to try again
+	MarkFailed(ctx context.Context, id int64, cause error, next time.Time) error
+	// MarkDeadLettered records a failed publish and stops retrying the
+	// entry until it is requeued
+	MarkDeadLettered(ctx context.Context, id int64, cause error, at time.Time) error
+	// DeadLettered returns up to limit dead-lettered entries, oldest first
+	DeadLettered(ctx context.Context, limit int) ([]*Entry, error)
+	// Requeue makes a dead-lettered entry due at now with a fresh retry
+	// budget, returning a NotFound AppError when there is no such entry
+	// and a Conflict AppError when it is not dead-lettered
+	Requeue(ctx context.Context, id int64, now time.Time) (*Entry, error)
+	// Stats summarises the pending and dead-lettered entries
+	Stats(ctx context.Context) (Stats, error)
+}
+
+// Stage builds one event per type for the record identified by subject
+// and appends them to store. data is the record after the write, or
+// before it for deletions. Staging no types is a no-op.
+func Stage(ctx context.Context, store Store, subject string, data interface{}, eventTypes ...string) error {
+	if len(eventTypes) == 0 {
+		return nil
+	}
+	evts := make([]events.Event, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		event, err := events.New(eventType, subject, data)
+		if err != nil {
+			return err
+		}
+		evts = append(evts, event)
+	}
+	return store.Append(ctx, evts...)
+}
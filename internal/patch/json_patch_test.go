@@ -0,0 +1,330 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	appErr "example.com/myapp/internal/errors"
+)
+
+// jsonEqual reports whether a and b hold the same JSON value
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var va, vb interface{}
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatalf("invalid JSON %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// TestJSONPatchRFC6902 runs the examples of RFC 6902 Appendix A
+func TestJSONPatchRFC6902(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		want     string // expected document; empty when an error is expected
+		wantCode string
+	}{
+		{
+			name:  "A.1 adding an object member",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			want:  `{"baz": "qux", "foo": "bar"}`,
+		},
+		{
+			name:  "A.2 adding an array element",
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			want:  `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		{
+			name:  "A.3 removing an object member",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "remove", "path": "/baz"}]`,
+			want:  `{"foo": "bar"}`,
+		},
+		{
+			name:  "A.4 removing an array element",
+			doc:   `{"foo": ["bar", "qux", "baz"]}`,
+			patch: `[{"op": "remove", "path": "/foo/1"}]`,
+			want:  `{"foo": ["bar", "baz"]}`,
+		},
+		{
+			name:  "A.5 replacing a value",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			want:  `{"baz": "boo", "foo": "bar"}`,
+		},
+		{
+			name:  "A.6 moving a value",
+			doc:   `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch: `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			want:  `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{
+			name:  "A.7 moving an array element",
+			doc:   `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch: `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			want:  `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		{
+			name: "A.8 testing a value: success",
+			doc:  `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			patch: `[{"op": "test", "path": "/baz", "value": "qux"},
+			         {"op": "test", "path": "/foo/1", "value": 2}]`,
+			want: `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		},
+		{
+			name:     "A.9 testing a value: error",
+			doc:      `{"baz": "qux"}`,
+			patch:    `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			wantCode: appErr.ErrCodeConflict,
+		},
+		{
+			name:  "A.10 adding a nested member object",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			want:  `{"foo": "bar", "child": {"grandchild": {}}}`,
+		},
+		{
+			name:  "A.11 ignoring unrecognized elements",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux", "xyz": 123}]`,
+			want:  `{"foo": "bar", "baz": "qux"}`,
+		},
+		{
+			name:     "A.12 adding to a nonexistent target",
+			doc:      `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+			wantCode: appErr.ErrCodeBadRequest,
+		},
+		{
+			// Duplicate members are resolved to the last one, a remove of a
+			// member that does not exist
+			name:     "A.13 invalid JSON patch document",
+			doc:      `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/baz", "value": "qux", "op": "remove"}]`,
+			wantCode: appErr.ErrCodeBadRequest,
+		},
+		{
+			name:  "A.14 ~ escape ordering",
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": 10}]`,
+			want:  `{"/": 9, "~1": 10}`,
+		},
+		{
+			name:     "A.15 comparing strings and numbers",
+			doc:      `{"/": 9, "~1": 10}`,
+			patch:    `[{"op": "test", "path": "/~01", "value": "10"}]`,
+			wantCode: appErr.ErrCodeConflict,
+		},
+		{
+			name:  "A.16 adding an array value",
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			want:  `{"foo": ["bar", ["abc", "def"]]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runPatch(t, tt.doc, tt.patch, tt.want, tt.wantCode)
+		})
+	}
+}
+
+func TestJSONPatchEdgeCases(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		want     string
+		wantCode string
+	}{
+		{
+			name:  "- appends to an empty array",
+			doc:   `{"a": []}`,
+			patch: `[{"op": "add", "path": "/a/-", "value": 1}]`,
+			want:  `{"a": [1]}`,
+		},
+		{
+			name:  "index equal to the length appends",
+			doc:   `{"a": [1, 2]}`,
+			patch: `[{"op": "add", "path": "/a/2", "value": 3}]`,
+			want:  `{"a": [1, 2, 3]}`,
+		},
+		{
+			name:     "- cannot be removed",
+			doc:      `{"a": [1]}`,
+			patch:    `[{"op": "remove", "path": "/a/-"}]`,
+			wantCode: appErr.ErrCodeBadRequest,
+		},
+		{
+			name:     "- cannot be read",
+			doc:      `{"a": [1]}`,
+			patch:    `[{"op": "copy", "from": "/a/-", "path": "/b"}]`,
+			wantCode: appErr.ErrCodeBadRequest,
+		},
+		{
+			name:     "- cannot be traversed",
+			doc:      `{"a": [{"b": 1}]}`,
+			patch:    `[{"op": "add", "path": "/a/-/b", "value": 2}]`,
+			wantCode: appErr.ErrCodeBadRequest,
+		},
+		{
+			name:  "~1 and ~0 in member names",
+			doc:   `{"a/b": 1, "m~n": 2}`,
+			patch: `[{"op": "replace", "path": "/a~1b", "value": 3}, {"op": "remove", "path": "/m~0n"}]`,
+			want:  `{"a/b": 3}`,
+		},
+		{
+			name:  "~01 is ~1, not /",
+			doc:   `{"~1": 1, "/": 2}`,
+			patch: `[{"op": "remove", "path": "/~01"}]`,
+			want:  `{"/": 2}`,
+		},
+		{
+			name:     "move into its own child",
+			doc:      `{"a": {"b": {}}}`,
+			patch:    `[{"op": "move", "from": "/a", "path": "/a/b/c"}]`,
+			wantCode: appErr.ErrCodeBadRequest,
+		},
+		{
+			name:  "move onto itself",
+			doc:   `{"a": {"b": 1}}`,
+			patch: `[{"op": "move", "from": "/a", "path": "/a"}]`,
+			want:  `{"a": {"b": 1}}`,
+		},
+		{
+			name:  "move to a sibling sharing a name prefix",
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "move", "from": "/a", "path": "/ab"}]`,
+			want:  `{"ab": 1}`,
+		},
+		{
+			name:  "copy is deep",
+			doc:   `{"a": {"b": 1}}`,
+			patch: `[{"op": "copy", "from": "/a", "path": "/c"}, {"op": "replace", "path": "/c/b", "value": 2}]`,
+			want:  `{"a": {"b": 1}, "c": {"b": 2}}`,
+		},
+		{
+			name:  "replace the whole document",
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "replace", "path": "", "value": {"b": 2}}]`,
+			want:  `{"b": 2}`,
+		},
+		{
+			name:  "explicit null value",
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "add", "path": "/a", "value": null}]`,
+			want:  `{"a": null}`,
+		},
+		{name: "add index past the end", doc: `{"a": [1]}`, patch: `[{"op": "add", "path": "/a/2", "value": 0}]`, wantCode: appErr.ErrCodeBadRequest},
+		{name: "remove index past the end", doc: `{"a": [1]}`, patch: `[{"op": "remove", "path": "/a/1"}]`, wantCode: appErr.ErrCodeBadRequest},
+		{name: "replace in an empty array", doc: `{"a": []}`, patch: `[{"op": "replace", "path": "/a/0", "value": 0}]`, wantCode: appErr.ErrCodeBadRequest},
+		{name: "huge index", doc: `{"a": [1]}`, patch: `[{"op": "remove", "path": "/a/99999999999999999999"}]`, wantCode: appErr.ErrCodeBadRequest},
+		{name: "negative index", doc: `{"a": [1]}`, patch: `[{"op": "remove", "path": "/a/-1"}]`, wantCode: appErr.ErrCodeBadRequest},
+		{name: "leading zero index", doc: `{"a": [1, 2]}`, patch: `[{"op": "remove", "path": "/a/01"}]`, wantCode: appErr.ErrCodeBadRequest},
+		{name: "index into a scalar", doc: `{"a": 1}`, patch: `[{"op": "test", "path": "/a/0", "value": 1}]`, wantCode: appErr.ErrCodeBadRequest},
+		{name: "pointer without slash", doc: `{"a": 1}`, patch: `[{"op": "remove", "path": "a"}]`, wantCode: appErr.ErrCodeBadRequest},
+		{name: "missing value", doc: `{"a": 1}`, patch: `[{"op": "replace", "path": "/a"}]`, wantCode: appErr.ErrCodeBadRequest},
+		{name: "unknown op", doc: `{"a": 1}`, patch: `[{"op": "increment", "path": "/a"}]`, wantCode: appErr.ErrCodeBadRequest},
+		{name: "not an array", doc: `{"a": 1}`, patch: `{"op": "remove", "path": "/a"}`, wantCode: appErr.ErrCodeBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runPatch(t, tt.doc, tt.patch, tt.want, tt.wantCode)
+		})
+	}
+}
+
+// runPatch applies patch to doc and checks the result, or that the patch
+// failed with wantCode and left the input document untouched
+func runPatch(t *testing.T, doc, patch, want, wantCode string) {
+	t.Helper()
+	input := []byte(doc)
+	got, err := JSONPatch(input, []byte(patch))
+
+	if wantCode != "" {
+		if !appErr.HasCode(err, wantCode) {
+			t.Fatalf("JSONPatch() error = %v, want code %s", err, wantCode)
+		}
+		if status := appErr.StatusCode(err); status >= http.StatusInternalServerError {
+			t.Errorf("status = %d, want a client error", status)
+		}
+		if got != nil || !bytes.Equal(input, []byte(doc)) {
+			t.Errorf("failed patch returned %s and left the document as %s", got, input)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("JSONPatch() error = %v", err)
+	}
+	if !jsonEqual(t, got, []byte(want)) {
+		t.Errorf("JSONPatch() = %s, want %s", got, want)
+	}
+}
+
+func TestJSONPatchFailedTestIsAtomic(t *testing.T) {
+	doc := []byte(`{"name": "Ada", "tags": ["a"]}`)
+	patch := []byte(`[
+		{"op": "replace", "path": "/name", "value": "Grace"},
+		{"op": "add", "path": "/tags/-", "value": "b"},
+		{"op": "test", "path": "/name", "value": "Ada"}
+	]`)
+
+	got, err := JSONPatch(doc, patch)
+	if !appErr.HasCode(err, appErr.ErrCodeConflict) {
+		t.Fatalf("JSONPatch() error = %v, want CONFLICT", err)
+	}
+	if got != nil {
+		t.Errorf("JSONPatch() returned %s after a failed test", got)
+	}
+	if ae := appErr.GetAppError(err); ae.Message != `operation 2 (test /name): test failed` {
+		t.Errorf("message = %q, want it to name the failed operation", ae.Message)
+	}
+}
+
+func TestApply(t *testing.T) {
+	doc := []byte(`{"a": 1, "b": {"c": 2}}`)
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		want        string
+		wantCode    string
+	}{
+		{name: "default is merge patch", patch: `{"b": {"c": null}}`, want: `{"a": 1, "b": {}}`},
+		{name: "application/json is merge patch", contentType: "application/json; charset=utf-8", patch: `{"a": 3}`, want: `{"a": 3, "b": {"c": 2}}`},
+		{name: "merge patch replaces non-objects", contentType: MergePatchContentType, patch: `{"b": [1]}`, want: `{"a": 1, "b": [1]}`},
+		{name: "json patch", contentType: JSONPatchContentType, patch: `[{"op": "remove", "path": "/a"}]`, want: `{"b": {"c": 2}}`},
+		{name: "other type", contentType: "text/plain", patch: `{}`, wantCode: appErr.ErrCodeUnsupported},
+		{name: "malformed type", contentType: "application/", patch: `{}`, wantCode: appErr.ErrCodeUnsupported},
+		{name: "malformed merge patch", contentType: MergePatchContentType, patch: `{`, wantCode: appErr.ErrCodeBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(tt.contentType, doc, []byte(tt.patch))
+			if tt.wantCode != "" {
+				if !appErr.HasCode(err, tt.wantCode) {
+					t.Fatalf("Apply() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if !jsonEqual(t, got, []byte(tt.want)) {
+				t.Errorf("Apply() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Package patch applies partial-update documents to JSON resources. It
+// supports JSON Merge Patch (RFC 7396) and JSON Patch (RFC 6902).
+package patch
+
+import (
+	"encoding/json"
+	"mime"
+
+	appErr "example.com/myapp/internal/errors"
+)
+
+// Media types accepted for PATCH requests
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// Apply applies patch to the JSON document doc according to contentType.
+// Plain application/json (or no content type) is treated as a merge patch.
+func Apply(contentType string, doc, patch []byte) ([]byte, error) {
+	mediaType := MergePatchContentType
+	if contentType != "" {
+		parsed, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, appErr.UnsupportedType("invalid content type")
+		}
+		mediaType = parsed
+	}
+
+	switch mediaType {
+	case MergePatchContentType, "application/json":
+		return MergePatch(doc, patch)
+	case JSONPatchContentType:
+		return JSONPatch(doc, patch)
+	default:
+		return nil, appErr.UnsupportedType("patch content type must be " + MergePatchContentType + " or " + JSONPatchContentType)
+	}
+}
+
+// MergePatch applies a JSON Merge Patch document: objects are merged
+// recursively, null removes a member and any other value replaces it
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, p interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, appErr.Internal("invalid target document", err)
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, appErr.BadRequest("invalid merge patch document")
+	}
+	return json.Marshal(mergeValue(target, p))
+}
+
+func mergeValue(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+			continue
+		}
+		t[key] = mergeValue(t[key], value)
+	}
+	return t
+}
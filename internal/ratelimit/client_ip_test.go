@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+	}
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		trusted   []netip.Prefix
+		want      string
+	}{
+		{name: "direct client", remote: "203.0.113.7:5000", trusted: trusted, want: "203.0.113.7"},
+		{name: "header from untrusted peer ignored", remote: "203.0.113.7:5000", forwarded: []string{"198.51.100.1"}, trusted: trusted, want: "203.0.113.7"},
+		{name: "no trusted proxies configured", remote: "10.0.0.2:5000", forwarded: []string{"198.51.100.1"}, want: "10.0.0.2"},
+		{name: "trusted proxy", remote: "10.0.0.2:5000", forwarded: []string{"198.51.100.1"}, trusted: trusted, want: "198.51.100.1"},
+		{name: "trusted proxy without header", remote: "10.0.0.2:5000", trusted: trusted, want: "10.0.0.2"},
+		{name: "spoofed leftmost hop ignored", remote: "10.0.0.2:5000", forwarded: []string{"1.2.3.4, 198.51.100.1"}, trusted: trusted, want: "198.51.100.1"},
+		{name: "chain of trusted proxies skipped", remote: "10.0.0.2:5000", forwarded: []string{"1.2.3.4, 198.51.100.1, 10.0.0.9, 10.0.0.3"}, trusted: trusted, want: "198.51.100.1"},
+		{name: "repeated headers are one list", remote: "10.0.0.2:5000", forwarded: []string{"1.2.3.4", "198.51.100.1"}, trusted: trusted, want: "198.51.100.1"},
+		{name: "all hops trusted", remote: "10.0.0.2:5000", forwarded: []string{"10.0.0.5, 10.0.0.9"}, trusted: trusted, want: "10.0.0.5"},
+		{name: "invalid hop stops the walk", remote: "10.0.0.2:5000", forwarded: []string{"198.51.100.1, bogus"}, trusted: trusted, want: "10.0.0.2"},
+		{name: "hop with port", remote: "10.0.0.2:5000", forwarded: []string{"198.51.100.1:4711"}, trusted: trusted, want: "198.51.100.1"},
+		{name: "IPv6 proxy and client", remote: "[fd00::1]:5000", forwarded: []string{"2001:db8::5"}, trusted: trusted, want: "2001:db8::5"},
+		{name: "IPv4-mapped IPv6 peer", remote: "[::ffff:10.0.0.2]:5000", forwarded: []string{"198.51.100.1"}, trusted: trusted, want: "198.51.100.1"},
+		{name: "unparsable remote address", remote: "pipe", forwarded: []string{"198.51.100.1"}, trusted: trusted, want: "pipe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := ClientIP(r, tt.trusted); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Package ratelimit implements token-bucket rate limiting. Buckets are kept
+// in a Store so limits can be shared between instances; MemoryStore keeps
+// them in the process.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit allows Requests per Period. The bucket holds up to Requests tokens,
+// so a client that has been idle may spend its whole budget at once.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Enabled reports whether the limit restricts anything
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// perSecond is the refill rate in tokens per second
+func (l Limit) perSecond() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Decision is the outcome of taking a token
+type Decision struct {
+	Allowed bool
+	// Limit is the bucket capacity
+	Limit int
+	// Remaining is the number of whole tokens left
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next token is available; zero when
+	// the request was allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets
+type Store interface {
+	// Take removes one token from the bucket identified by key, creating a
+	// full bucket for limit when there is none. When limit changed since
+	// the bucket was last used, the bucket is resized to the new capacity.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Decision, error)
+}
+
+// bucket is the state of one key
+type bucket struct {
+	tokens   float64
+	updated  time.Time
+	period   time.Duration
+	capacity int
+}
+
+// take refills b for the time since it was last updated and removes a
+// token if one is available
+func (b *bucket) take(limit Limit, now time.Time) Decision {
+	capacity := float64(limit.Requests)
+	rate := limit.perSecond()
+
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * rate
+	}
+	// A raised limit grants the extra tokens at once; a lowered one caps
+	// the bucket
+	if limit.Requests > b.capacity {
+		b.tokens += float64(limit.Requests - b.capacity)
+	}
+	b.tokens = math.Min(capacity, b.tokens)
+	b.updated = now
+	b.period = limit.Period
+	b.capacity = limit.Requests
+
+	d := Decision{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = seconds((capacity - b.tokens) / rate)
+	return d
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	perMinute := func(n int) Limit { return Limit{Requests: n, Period: time.Minute} }
+
+	type take struct {
+		key           string
+		at            time.Duration
+		limit         Limit
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+		wantReset     time.Duration
+	}
+	tests := []struct {
+		name  string
+		takes []take
+	}{
+		{
+			name: "burst up to capacity then reject",
+			takes: []take{
+				{key: "a", limit: perMinute(3), wantAllowed: true, wantRemaining: 2, wantReset: 20 * time.Second},
+				{key: "a", limit: perMinute(3), wantAllowed: true, wantRemaining: 1, wantReset: 40 * time.Second},
+				{key: "a", limit: perMinute(3), wantAllowed: true, wantRemaining: 0, wantReset: time.Minute},
+				{key: "a", limit: perMinute(3), wantRemaining: 0, wantRetry: 20 * time.Second, wantReset: time.Minute},
+			},
+		},
+		{
+			name: "refills evenly over the period",
+			takes: []take{
+				{key: "a", limit: perMinute(2), wantAllowed: true, wantRemaining: 1, wantReset: 30 * time.Second},
+				{key: "a", limit: perMinute(2), wantAllowed: true, wantRemaining: 0, wantReset: time.Minute},
+				{key: "a", at: 10 * time.Second, limit: perMinute(2), wantRetry: 20 * time.Second, wantReset: 50 * time.Second},
+				{key: "a", at: 30 * time.Second, limit: perMinute(2), wantAllowed: true, wantRemaining: 0, wantReset: time.Minute},
+			},
+		},
+		{
+			name: "never exceeds capacity after idling",
+			takes: []take{
+				{key: "a", limit: perMinute(2), wantAllowed: true, wantRemaining: 1, wantReset: 30 * time.Second},
+				{key: "a", at: time.Hour, limit: perMinute(2), wantAllowed: true, wantRemaining: 1, wantReset: 30 * time.Second},
+			},
+		},
+		{
+			name: "keys have separate buckets",
+			takes: []take{
+				{key: "a", limit: perMinute(1), wantAllowed: true, wantReset: time.Minute},
+				{key: "a", limit: perMinute(1), wantRetry: time.Minute, wantReset: time.Minute},
+				{key: "b", limit: perMinute(1), wantAllowed: true, wantReset: time.Minute},
+			},
+		},
+		{
+			name: "raised limit grants the extra tokens",
+			takes: []take{
+				{key: "a", limit: perMinute(1), wantAllowed: true, wantReset: time.Minute},
+				{key: "a", limit: perMinute(3), wantAllowed: true, wantRemaining: 1, wantReset: 40 * time.Second},
+			},
+		},
+		{
+			name: "lowered limit caps the bucket",
+			takes: []take{
+				{key: "a", limit: perMinute(10), wantAllowed: true, wantRemaining: 9, wantReset: 6 * time.Second},
+				{key: "a", limit: perMinute(2), wantAllowed: true, wantRemaining: 1, wantReset: 30 * time.Second},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			for i, tk := range tt.takes {
+				d, err := store.Take(context.Background(), tk.key, tk.limit, base.Add(tk.at))
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := Decision{
+					Allowed:    tk.wantAllowed,
+					Limit:      tk.limit.Requests,
+					Remaining:  tk.wantRemaining,
+					Reset:      tk.wantReset,
+					RetryAfter: tk.wantRetry,
+				}
+				if !closeDecision(d, want) {
+					t.Errorf("take %d: got %+v, want %+v", i, d, want)
+				}
+			}
+		})
+	}
+}
+
+// closeDecision compares decisions, allowing durations to differ by float
+// rounding
+func closeDecision(got, want Decision) bool {
+	near := func(a, b time.Duration) bool {
+		diff := a - b
+		return diff > -time.Millisecond && diff < time.Millisecond
+	}
+	return got.Allowed == want.Allowed && got.Limit == want.Limit && got.Remaining == want.Remaining &&
+		near(got.Reset, want.Reset) && near(got.RetryAfter, want.RetryAfter)
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	limit := Limit{Requests: 1, Period: time.Minute}
+
+	store.Take(context.Background(), "idle", limit, base.Add(SweepInterval))
+	store.Take(context.Background(), "active", limit, base.Add(SweepInterval+59*time.Second))
+	store.Take(context.Background(), "trigger", limit, base.Add(2*SweepInterval+30*time.Second))
+
+	for key, want := range map[string]bool{"idle": false, "active": true, "trigger": true} {
+		if _, ok := store.buckets[key]; ok != want {
+			t.Errorf("bucket %q kept = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestLimitEnabled(t *testing.T) {
+	tests := []struct {
+		limit Limit
+		want  bool
+	}{
+		{Limit{Requests: 10, Period: time.Minute}, true},
+		{Limit{Requests: 0, Period: time.Minute}, false},
+		{Limit{Requests: 10}, false},
+		{Limit{}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.limit.Enabled(); got != tt.want {
+			t.Errorf("%+v.Enabled() = %v, want %v", tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{id: "123e4567-e89b-12d3-a456-426614174000", want: true},
+		{id: "req_1.2:3", want: true},
+		{id: strings.Repeat("a", MaxLength), want: true},
+		{id: strings.Repeat("a", MaxLength+1)},
+		{id: ""},
+		{id: "has space"},
+		{id: "line\nbreak"},
+		{id: `quote"`},
+		{id: "<script>"},
+		{id: "ünïcode"},
+	}
+	for _, tt := range tests {
+		if got := Valid(tt.id); got != tt.want {
+			t.Errorf("Valid(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if id := FromContext(context.Background()); id != "" {
+		t.Errorf("FromContext(empty) = %q", id)
+	}
+	id := New()
+	if !Valid(id) {
+		t.Errorf("New() = %q is not valid", id)
+	}
+	if got := FromContext(NewContext(context.Background(), id)); got != id {
+		t.Errorf("FromContext() = %q, want %q", got, id)
+	}
+}
@@ -1,20 +1,67 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 
 	"example.com/myapp/internal/container"
+	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/metrics"
 	mw "example.com/myapp/internal/middleware"
 )
 
 func SetupRoutes(c *container.Container) *chi.Mux {
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.Use(mw.RequestID)
+	r.Use(mw.Tracing)
+	r.Use(mw.Metrics)
+	// Browser protections run before rate limiting so even rejected
+	// responses carry security and CORS headers; preflights are answered
+	// without using a budget
+	r.Use(mw.SecurityHeaders(c.Settings))
+	r.Use(mw.CORS(c.Settings))
+	// Credentials are verified before anything keys on the principal
+	r.Use(mw.Authenticate(c.TokenSecret))
+	r.Use(mw.RateLimit(c.Settings, c.RateLimitStore, rateLimitGroup))
+	r.Use(mw.CSRF(c.Settings))
+
+	// Unmatched routes and methods use the common problem format
+	r.NotFound(appErr.NotFoundHandler)
+	r.MethodNotAllowed(appErr.MethodNotAllowedHandler)
+
+	// Prometheus scrape endpoint. Metrics reveal traffic and internals, so
+	// scrapers authenticate as an admin principal.
+	r.With(mw.RequireAdmin(c.AdminIDs)).Method(http.MethodGet, "/metrics", metrics.Default.Handler())
+
+	// Liveness and readiness probes
+	c.Health.RegisterRoutes(r)
 
 	// Register handler routes with middleware
-	c.UserHandler.RegisterRoutes(r, mw.LoggingMiddleware, mw.AuthMiddleware, mw.LoadUserMiddleware(c.UserRepository), mw.ValidateIDMiddleware)
-	c.MediaHandler.RegisterRoutes(r, mw.LoggingMiddleware, mw.AuthMiddleware, mw.ValidateIDMiddleware)
+	accessLog := mw.AccessLog(c.Settings)
+	c.UserHandler.RegisterRoutes(r, accessLog, mw.AuthMiddleware, mw.LoadUserMiddleware(c.UserRepository), mw.ValidateIDMiddleware)
+	c.MediaHandler.RegisterRoutes(r, accessLog, mw.AuthMiddleware, mw.ValidateUUIDMiddleware)
+	c.CollectionHandler.RegisterRoutes(r, accessLog, mw.AuthMiddleware)
+	c.AuditHandler.RegisterRoutes(r, accessLog, mw.AuthMiddleware, mw.RequireAdmin(c.AdminIDs))
+	c.WebhookHandler.RegisterRoutes(r, accessLog, mw.AuthMiddleware, mw.RequireAdmin(c.AdminIDs))
+	c.OutboxHandler.RegisterRoutes(r, accessLog, mw.AuthMiddleware, mw.RequireAdmin(c.AdminIDs))
+	c.ConfigHandler.RegisterRoutes(r, accessLog, mw.AuthMiddleware, mw.RequireAdmin(c.AdminIDs))
 
 	return r
 }
+
+// rateLimitGroup assigns a request the budget it is counted against.
+// Uploads are limited separately from other writes since each may carry a
+// large file; probes and metrics scrapes are never limited.
+func rateLimitGroup(r *http.Request) string {
+	switch {
+	case r.URL.Path == "/healthz" || r.URL.Path == "/readyz" || r.URL.Path == "/metrics":
+		return ""
+	case r.Method == http.MethodPost && r.URL.Path == "/media/upload":
+		return "uploads"
+	case r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions:
+		return "reads"
+	default:
+		return "writes"
+	}
+}
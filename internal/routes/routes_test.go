@@ -0,0 +1,88 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/myapp/internal/auth"
+	"example.com/myapp/internal/config"
+	"example.com/myapp/internal/container"
+)
+
+const testSecret = "routes-test-secret-0123456789abcdef"
+
+func TestMetricsRequiresAdmin(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.TokenSecret = testSecret
+	cfg.Auth.AdminPrincipals = []string{"ops"}
+	cfg.Media.StoragePath = t.TempDir()
+	r := SetupRoutes(container.NewContainer(config.NewStore(cfg, nil)))
+
+	token := func(subject string) string {
+		tok, err := auth.IssueToken([]byte(testSecret), subject, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tok
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "anonymous", wantStatus: http.StatusUnauthorized},
+		{name: "unsigned principal", token: "ops", wantStatus: http.StatusUnauthorized},
+		{name: "non-admin", token: token("alice"), wantStatus: http.StatusForbidden},
+		{name: "admin", token: token("ops"), wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			exposed := strings.Contains(w.Body.String(), "# TYPE")
+			if exposed != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("metrics exposed = %v for status %d", exposed, w.Code)
+			}
+		})
+	}
+}
+
+func TestRateLimitGroup(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/healthz", ""},
+		{http.MethodGet, "/readyz", ""},
+		{http.MethodGet, "/metrics", ""},
+		{http.MethodPost, "/media/upload", "uploads"},
+		{http.MethodGet, "/media/upload", "reads"},
+		{http.MethodGet, "/users/1", "reads"},
+		{http.MethodHead, "/users/1", "reads"},
+		{http.MethodOptions, "/users", "reads"},
+		{http.MethodPost, "/users", "writes"},
+		{http.MethodDelete, "/users/1", "writes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if got := rateLimitGroup(httptest.NewRequest(tt.method, tt.path, nil)); got != tt.want {
+				t.Errorf("rateLimitGroup() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// collector is an OTLP/HTTP endpoint recording the decoded requests
+type collector struct {
+	mu       sync.Mutex
+	requests []otlpRequest
+	status   int
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+	var req otlpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req)
+	if c.status != 0 {
+		w.WriteHeader(c.status)
+	}
+}
+
+// spans returns the spans of every request, keyed by name
+func (c *collector) spans(t *testing.T) map[string]otlpSpan {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]otlpSpan)
+	for _, req := range c.requests {
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					out[span.Name] = span
+				}
+			}
+		}
+	}
+	return out
+}
+
+func attribute(span otlpSpan, key string) map[string]interface{} {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return nil
+}
+
+func TestOTLPExporterBatchesSpansUntilShutdown(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	provider := NewProvider(NewOTLPExporter(srv.URL, "myapp-test"), 1)
+	SetProvider(provider)
+	t.Cleanup(func() { SetProvider(nil) })
+
+	ctx, parent := Start(context.Background(), "GET /users/{id}", WithKind(KindServer),
+		WithAttributes(Attr("http.method", "GET")))
+	_, child := Start(ctx, "users.repository.Get")
+	child.SetAttributes(Attr("db.rows", 3), Attr("cache.hit", false), Attr("ratio", 0.5), Attr("bytes", int64(1<<40)))
+	child.SetError(errors.New("not found"))
+	child.End()
+	parent.End()
+
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	c.mu.Lock()
+	requests := len(c.requests)
+	resource := c.requests[0].ResourceSpans[0].Resource
+	c.mu.Unlock()
+	if requests != 1 {
+		t.Fatalf("collector received %d requests, want one batch", requests)
+	}
+	if len(resource.Attributes) != 1 || resource.Attributes[0].Key != "service.name" || resource.Attributes[0].Value["stringValue"] != "myapp-test" {
+		t.Errorf("resource attributes = %v", resource.Attributes)
+	}
+
+	spans := c.spans(t)
+	server, repo := spans["GET /users/{id}"], spans["users.repository.Get"]
+	psc, csc := parent.SpanContext(), child.SpanContext()
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"parent trace ID", server.TraceID, psc.TraceID.String()},
+		{"parent span ID", server.SpanID, psc.SpanID.String()},
+		{"parent has no parent", server.ParentSpanID, ""},
+		{"parent kind", server.Kind, int(KindServer)},
+		{"parent status", server.Status.Code, 1},
+		{"parent attribute", attribute(server, "http.method")["stringValue"], "GET"},
+		{"child trace ID", repo.TraceID, psc.TraceID.String()},
+		{"child span ID", repo.SpanID, csc.SpanID.String()},
+		{"child parent", repo.ParentSpanID, psc.SpanID.String()},
+		{"child kind", repo.Kind, int(KindInternal)},
+		{"child status", repo.Status, otlpStatus{Code: 2, Message: "not found"}},
+		{"int attribute", attribute(repo, "db.rows")["intValue"], "3"},
+		{"int64 attribute", attribute(repo, "bytes")["intValue"], strconv.FormatInt(1<<40, 10)},
+		{"bool attribute", attribute(repo, "cache.hit")["boolValue"], false},
+		{"float attribute", attribute(repo, "ratio")["doubleValue"], 0.5},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	start, _ := strconv.ParseInt(repo.StartTimeUnixNano, 10, 64)
+	end, _ := strconv.ParseInt(repo.EndTimeUnixNano, 10, 64)
+	if start == 0 || end < start {
+		t.Errorf("timestamps = %s..%s", repo.StartTimeUnixNano, repo.EndTimeUnixNano)
+	}
+}
+
+func TestOTLPExporterReportsCollectorErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "accepted", status: http.StatusOK},
+		{name: "partial success", status: http.StatusAccepted},
+		{name: "rejected", status: http.StatusBadRequest, wantErr: true},
+		{name: "unavailable", status: http.StatusServiceUnavailable, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(&collector{status: tt.status})
+			defer srv.Close()
+
+			span := SpanData{Name: "op", Kind: KindInternal, SpanContext: SpanContext{TraceID: newTraceID(), SpanID: newSpanID()}}
+			err := NewOTLPExporter(srv.URL, "svc").Export(context.Background(), []SpanData{span})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Export() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUnsampledSpansAreNotExported(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	provider := NewProvider(NewOTLPExporter(srv.URL, "svc"), 0)
+	SetProvider(provider)
+	t.Cleanup(func() { SetProvider(nil) })
+
+	ctx, span := Start(context.Background(), "unsampled")
+	_, child := Start(ctx, "child")
+	child.End()
+	span.End()
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !span.SpanContext().IsValid() || child.SpanContext().TraceID != span.SpanContext().TraceID {
+		t.Error("unsampled spans should still carry IDs for propagation")
+	}
+	if got := c.spans(t); len(got) != 0 {
+		t.Errorf("exported %d spans, want none", len(got))
+	}
+}
@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TraceParentHeader is the W3C Trace Context header
+const TraceParentHeader = "traceparent"
+
+// ParseTraceParent parses a W3C traceparent value such as
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ParseTraceParent(value string) (SpanContext, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) < 55 {
+		return SpanContext{}, false
+	}
+
+	version := value[0:2]
+	if !isLowerHex(version) || version == "ff" {
+		return SpanContext{}, false
+	}
+	// Version 00 has a fixed length; later versions may append fields
+	if (version == "00" && len(value) != 55) || (len(value) > 55 && value[55] != '-') {
+		return SpanContext{}, false
+	}
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return SpanContext{}, false
+	}
+
+	traceHex, spanHex, flagsHex := value[3:35], value[36:52], value[53:55]
+	if !isLowerHex(traceHex) || !isLowerHex(spanHex) || !isLowerHex(flagsHex) {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	hex.Decode(sc.TraceID[:], []byte(traceHex))
+	hex.Decode(sc.SpanID[:], []byte(spanHex))
+	var flags [1]byte
+	hex.Decode(flags[:], []byte(flagsHex))
+	sc.Sampled = flags[0]&1 == 1
+
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+// FormatTraceParent renders sc as a version 00 traceparent value
+func FormatTraceParent(sc SpanContext) string {
+	flags := 0
+	if sc.Sampled {
+		flags = 1
+	}
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, flags)
+}
+
+// Extract returns a copy of ctx whose next span continues the trace in the
+// traceparent header, if the header is present and valid
+func Extract(ctx context.Context, header http.Header) context.Context {
+	if sc, ok := ParseTraceParent(header.Get(TraceParentHeader)); ok {
+		return ContextWithRemoteSpanContext(ctx, sc)
+	}
+	return ctx
+}
+
+// Inject sets the traceparent header to the span in ctx
+func Inject(ctx context.Context, header http.Header) {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		header.Set(TraceParentHeader, FormatTraceParent(sc))
+	}
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
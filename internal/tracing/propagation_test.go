@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		wantOK      bool
+		wantSampled bool
+	}{
+		{name: "sampled", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", wantOK: true, wantSampled: true},
+		{name: "not sampled", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", wantOK: true},
+		{name: "surrounding whitespace", value: " 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01 ", wantOK: true, wantSampled: true},
+		{name: "future version with extra fields", value: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", wantOK: true, wantSampled: true},
+		{name: "empty", value: ""},
+		{name: "version ff", value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "all-zero trace ID", value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "all-zero span ID", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{name: "uppercase hex", value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{name: "version 00 with extra fields", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{name: "future version without separator", value: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01x"},
+		{name: "short trace ID", value: "00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01"},
+		{name: "wrong separator", value: "00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01"},
+		{name: "non-hex flags", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := ParseTraceParent(tt.value)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseTraceParent(%q) ok = %v, want %v", tt.value, ok, tt.wantOK)
+			}
+			if !ok {
+				if sc != (SpanContext{}) {
+					t.Errorf("rejected value returned %+v", sc)
+				}
+				return
+			}
+			if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" {
+				t.Errorf("IDs = %s/%s", sc.TraceID, sc.SpanID)
+			}
+			if sc.Sampled != tt.wantSampled {
+				t.Errorf("Sampled = %v, want %v", sc.Sampled, tt.wantSampled)
+			}
+		})
+	}
+}
+
+func TestTraceParentRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		sampled bool
+	}{
+		{name: "sampled", sampled: true},
+		{name: "not sampled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: tt.sampled}
+
+			// A remote parent arrives in a request header and is passed on
+			// unchanged when no local span was started
+			incoming := http.Header{}
+			incoming.Set(TraceParentHeader, FormatTraceParent(want))
+			ctx := Extract(context.Background(), incoming)
+			if got := SpanContextFromContext(ctx); got != want {
+				t.Fatalf("extracted %+v, want %+v", got, want)
+			}
+
+			// A local span continues the trace under a new span ID
+			ctx, span := Start(ctx, "child")
+			defer span.End()
+			outgoing := http.Header{}
+			Inject(ctx, outgoing)
+			got, ok := ParseTraceParent(outgoing.Get(TraceParentHeader))
+			if !ok {
+				t.Fatalf("injected invalid header %q", outgoing.Get(TraceParentHeader))
+			}
+			if got.TraceID != want.TraceID || got.Sampled != want.Sampled {
+				t.Errorf("injected %+v, want trace %s sampled %v", got, want.TraceID, want.Sampled)
+			}
+			if got.SpanID == want.SpanID || got.SpanID != span.SpanContext().SpanID {
+				t.Errorf("injected span ID %s, want the child's %s", got.SpanID, span.SpanContext().SpanID)
+			}
+		})
+	}
+}
+
+func TestExtractIgnoresInvalidHeaders(t *testing.T) {
+	tests := []string{
+		"",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-0000000000000000-01",
+		"garbage",
+	}
+
+	for _, value := range tests {
+		header := http.Header{}
+		header.Set(TraceParentHeader, value)
+		_, span := Start(Extract(context.Background(), header), "root")
+		if sc := span.SpanContext(); !sc.IsValid() || sc.TraceID.String() == "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("header %q: span %+v should start a new trace", value, sc)
+		}
+		if span.data.ParentSpanID.IsValid() {
+			t.Errorf("header %q: span has parent %s", value, span.data.ParentSpanID)
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,141 @@
+package tracing
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Export batching
+const (
+	QueueSize      = 2048
+	MaxBatchSize   = 512
+	ExportInterval = 2 * time.Second
+	ExportTimeout  = 10 * time.Second
+)
+
+// Exporter sends ended spans to a backend
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+}
+
+// Provider samples new traces and exports ended spans in batches from a
+// background goroutine, so ending a span never blocks on the exporter
+type Provider struct {
+	exporter   Exporter
+	sampleRate float64
+
+	queue   chan SpanData
+	dropped atomic.Int64
+	stop    chan struct{}
+	stopped sync.Once
+	wg      sync.WaitGroup
+}
+
+var globalProvider atomic.Pointer[Provider]
+
+// SetProvider installs p for every subsequently started span. A nil
+// provider disables recording; IDs are still generated and propagated.
+func SetProvider(p *Provider) {
+	globalProvider.Store(p)
+}
+
+// NewProvider starts a provider exporting to exporter. sampleRate is the
+// fraction of new traces recorded; child spans follow their parent.
+func NewProvider(exporter Exporter, sampleRate float64) *Provider {
+	p := &Provider{
+		exporter:   exporter,
+		sampleRate: sampleRate,
+		queue:      make(chan SpanData, QueueSize),
+		stop:       make(chan struct{}),
+	}
+	p.wg.Add(1)
+	go p.run()
+	return p
+}
+
+// sample decides whether a new trace is recorded
+func (p *Provider) sample() bool {
+	if p == nil {
+		return false
+	}
+	return p.sampleRate >= 1 || rand.Float64() < p.sampleRate
+}
+
+// enqueue queues a span for export, dropping it when the queue is full
+func (p *Provider) enqueue(span SpanData) {
+	select {
+	case p.queue <- span:
+	default:
+		if p.dropped.Add(1) == 1 {
+			slog.Warn("Trace export queue full, dropping spans")
+		}
+	}
+}
+
+func (p *Provider) run() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(ExportInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, MaxBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), ExportTimeout)
+		defer cancel()
+		if err := p.exporter.Export(ctx, batch); err != nil {
+			slog.Error("Failed to export spans", "spans", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= MaxBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-p.stop:
+			// Drain spans ended before shutdown
+			for {
+				select {
+				case span := <-p.queue:
+					batch = append(batch, span)
+					if len(batch) >= MaxBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// Shutdown exports queued spans and stops the provider. Spans ended later
+// are dropped.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	p.stopped.Do(func() { close(p.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
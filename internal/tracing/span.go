@@ -0,0 +1,245 @@
+// Package tracing records OpenTelemetry-style spans, propagates them with
+// W3C Trace Context headers and exports them to stdout or an OTLP/HTTP
+// collector. Spans are started with Start; they are only recorded once a
+// Provider has been installed with SetProvider.
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// String returns the lowercase hex form used in traceparent headers
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+// IsValid reports whether t is not all zeros
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// String returns the lowercase hex form used in traceparent headers
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// IsValid reports whether s is not all zeros
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// SpanContext is the part of a span that is propagated to other processes
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether both IDs are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Kind describes the relationship of a span to its remote peers; values
+// match the OTLP SpanKind enumeration
+type Kind int
+
+const (
+	KindInternal Kind = 1
+	KindServer   Kind = 2
+	KindClient   Kind = 3
+)
+
+// Attribute is a key/value pair describing a span. Values should be
+// strings, bools, ints or floats.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr creates an attribute
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// SpanData is the immutable record of an ended span handed to exporters
+type SpanData struct {
+	Name         string
+	Kind         Kind
+	SpanContext  SpanContext
+	ParentSpanID SpanID
+	Start        time.Time
+	End          time.Time
+	Attributes   []Attribute
+	// Error describes why the span failed; empty when it succeeded
+	Error string
+}
+
+// Span is an operation being timed. All methods are safe on spans that are
+// not recorded, so callers never need to check.
+type Span struct {
+	provider *Provider
+
+	mu   sync.Mutex
+	data SpanData
+	done bool
+}
+
+// Option configures a span at start
+type Option func(*SpanData)
+
+// WithKind sets the span kind; the default is KindInternal
+func WithKind(kind Kind) Option {
+	return func(d *SpanData) { d.Kind = kind }
+}
+
+// WithAttributes adds attributes at start
+func WithAttributes(attrs ...Attribute) Option {
+	return func(d *SpanData) { d.Attributes = append(d.Attributes, attrs...) }
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// Start begins a span named name as a child of the span in ctx, or of a
+// remote parent extracted from a request, and returns a context carrying
+// it. The caller must call End.
+func Start(ctx context.Context, name string, opts ...Option) (context.Context, *Span) {
+	provider := globalProvider.Load()
+	parent := SpanContextFromContext(ctx)
+
+	data := SpanData{
+		Name:  name,
+		Kind:  KindInternal,
+		Start: time.Now(),
+	}
+	for _, opt := range opts {
+		opt(&data)
+	}
+
+	data.SpanContext.SpanID = newSpanID()
+	if parent.IsValid() {
+		data.SpanContext.TraceID = parent.TraceID
+		data.SpanContext.Sampled = parent.Sampled
+		data.ParentSpanID = parent.SpanID
+	} else {
+		data.SpanContext.TraceID = newTraceID()
+		data.SpanContext.Sampled = provider.sample()
+	}
+
+	span := &Span{data: data}
+	if data.SpanContext.Sampled {
+		span.provider = provider
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SpanContext returns the IDs of the span
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.SpanContext
+}
+
+// SetName renames the span, e.g. once the route is known
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+// SetError marks the span as failed; a nil err is ignored
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Error = err.Error()
+}
+
+// End finishes the span and queues it for export. Later calls do nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return
+	}
+	s.done = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if s.provider != nil {
+		s.provider.enqueue(data)
+	}
+}
+
+// SpanFromContext returns the span started with ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SpanContextFromContext returns the IDs of the local span in ctx, or of
+// the remote parent when no local span has been started
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// ContextWithRemoteSpanContext returns a copy of ctx whose next span is a
+// child of sc, a span in another process or a stored trace context
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	if !sc.IsValid() {
+		return ctx
+	}
+	return context.WithValue(context.WithValue(ctx, spanKey{}, (*Span)(nil)), remoteKey{}, sc)
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		putUint64(id[:8], rand.Uint64())
+		putUint64(id[8:], rand.Uint64())
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		putUint64(id[:], rand.Uint64())
+	}
+	return id
+}
+
+func putUint64(b []byte, v uint64) {
+	for i := range 8 {
+		b[i] = byte(v >> (56 - 8*i))
+	}
+}
@@ -2,10 +2,13 @@ package users
 
 import (
 	"encoding/json"
+	"io"
 	"log/slog"
 	"net/http"
 
 	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/etag"
+	"example.com/myapp/internal/patch"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -35,11 +38,12 @@ func (h *Handler) RegisterRoutes(r chi.Router, loggingMw, authMw, loadUserMw fun
 		// Nested route for ID-specific operations
 		r.Route("/{id}", func(r chi.Router) {
 			// Middleware ONLY for routes with {id}
-			r.Use(loadUserMw)
 			r.Use(validateIDMw)
+			r.Use(loadUserMw)
 
 			r.Get("/", h.GetUser)
 			r.Put("/", h.UpdateUser)
+			r.Patch("/", h.PatchUser)
 			r.Delete("/", h.DeleteUser)
 		})
 	})
@@ -50,19 +54,20 @@ func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var req CreateUserRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		slog.Error("Failed to decode request", "error", err)
-		respondError(w, appErr.BadRequest("invalid request body"), http.StatusBadRequest)
+		slog.ErrorContext(r.Context(), "Failed to decode request", "error", err)
+		appErr.WriteError(w, r, appErr.BadRequest("invalid request body"))
 		return
 	}
 
 	user, err := h.service.CreateUser(r.Context(), &req)
 	if err != nil {
-		slog.Error("Failed to create user", "error", err)
-		respondError(w, err, getStatusCode(err))
+		slog.ErrorContext(r.Context(), "Failed to create user", "error", err)
+		appErr.WriteError(w, r, err)
 		return
 	}
 
-	slog.Info("User created", "user_id", user.ID)
+	slog.InfoContext(r.Context(), "User created", "user_id", user.ID)
+	etag.Set(w, user.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(user)
@@ -72,8 +77,8 @@ func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	users, err := h.service.GetAllUsers(r.Context())
 	if err != nil {
-		slog.Error("Failed to get all users", "error", err)
-		respondError(w, err, getStatusCode(err))
+		slog.ErrorContext(r.Context(), "Failed to get all users", "error", err)
+		appErr.WriteError(w, r, err)
 		return
 	}
 
@@ -87,92 +92,98 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (loaded by LoadUserMiddleware)
 	user, ok := r.Context().Value("user").(*User)
 	if !ok {
-		slog.Error("User not found in context")
-		respondError(w, appErr.NotFound("user not found"), http.StatusNotFound)
+		slog.ErrorContext(r.Context(), "User not found in context")
+		appErr.WriteError(w, r, appErr.NotFound("user not found"))
 		return
 	}
 
+	etag.Set(w, user.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(user)
 }
 
 // Update a user - PUT /users/{id}
+// An If-Match header makes the update conditional on the user's ETag
 func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	// Extract validated ID from context (set by ValidateIDMiddleware)
 	id, ok := r.Context().Value("userID").(int)
 	if !ok {
-		respondError(w, appErr.InvalidID("invalid user id"), http.StatusBadRequest)
+		appErr.WriteError(w, r, appErr.InvalidID("invalid user id"))
 		return
 	}
 
 	var req UpdateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		slog.Error("Failed to decode request", "error", err)
-		respondError(w, appErr.BadRequest("invalid request body"), http.StatusBadRequest)
+		slog.ErrorContext(r.Context(), "Failed to decode request", "error", err)
+		appErr.WriteError(w, r, appErr.BadRequest("invalid request body"))
 		return
 	}
 
-	user, err := h.service.UpdateUser(r.Context(), id, &req)
+	user, err := h.service.UpdateUser(r.Context(), id, &req, etag.IfMatch(r))
 	if err != nil {
-		slog.Error("Failed to update user", "id", id, "error", err)
-		respondError(w, err, getStatusCode(err))
+		slog.ErrorContext(r.Context(), "Failed to update user", "id", id, "error", err)
+		appErr.WriteError(w, r, err)
 		return
 	}
 
-	slog.Info("User updated", "user_id", id)
+	slog.InfoContext(r.Context(), "User updated", "user_id", id)
+	etag.Set(w, user.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(user)
 }
 
-// Delete a user - DELETE /users/{id}
-func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+// Partially update a user - PATCH /users/{id}
+// Accepts application/merge-patch+json or application/json-patch+json
+func (h *Handler) PatchUser(w http.ResponseWriter, r *http.Request) {
 	// Extract validated ID from context (set by ValidateIDMiddleware)
 	id, ok := r.Context().Value("userID").(int)
 	if !ok {
-		respondError(w, appErr.InvalidID("invalid user id"), http.StatusBadRequest)
+		appErr.WriteError(w, r, appErr.InvalidID("invalid user id"))
 		return
 	}
 
-	err := h.service.DeleteUser(r.Context(), id)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxPatchSize))
 	if err != nil {
-		slog.Error("Failed to delete user", "id", id, "error", err)
-		respondError(w, err, getStatusCode(err))
+		slog.ErrorContext(r.Context(), "Failed to read patch document", "error", err)
+		appErr.WriteError(w, r, appErr.BadRequest("invalid request body"))
 		return
 	}
 
-	slog.Info("User deleted", "user_id", id)
-	w.WriteHeader(http.StatusNoContent)
-}
-
-// Helper functions
-
-func getStatusCode(err error) int {
-	if err == nil {
-		return http.StatusOK
+	user, err := h.service.PatchUser(r.Context(), id, r.Header.Get("Content-Type"), body, etag.IfMatch(r))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to patch user", "id", id, "error", err)
+		if appErr.HasCode(err, appErr.ErrCodeUnsupported) {
+			w.Header().Set("Accept-Patch", patch.MergePatchContentType+", "+patch.JSONPatchContentType)
+		}
+		appErr.WriteError(w, r, err)
+		return
 	}
 
-	ae := appErr.GetAppError(err)
-	if ae == nil {
-		return http.StatusInternalServerError
-	}
+	slog.InfoContext(r.Context(), "User patched", "user_id", id)
+	etag.Set(w, user.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
 
-	switch ae.Code {
-	case appErr.ErrCodeNotFound:
-		return http.StatusNotFound
-	case appErr.ErrCodeBadRequest, appErr.ErrCodeInvalidID:
-		return http.StatusBadRequest
-	default:
-		return http.StatusInternalServerError
+// Delete a user - DELETE /users/{id}
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	// Extract validated ID from context (set by ValidateIDMiddleware)
+	id, ok := r.Context().Value("userID").(int)
+	if !ok {
+		appErr.WriteError(w, r, appErr.InvalidID("invalid user id"))
+		return
 	}
-}
 
-func respondError(w http.ResponseWriter, err error, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	response := map[string]interface{}{
-		"error": err.Error(),
+	err := h.service.DeleteUser(r.Context(), id, etag.IfMatch(r))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to delete user", "id", id, "error", err)
+		appErr.WriteError(w, r, err)
+		return
 	}
-	json.NewEncoder(w).Encode(response)
+
+	slog.InfoContext(r.Context(), "User deleted", "user_id", id)
+	w.WriteHeader(http.StatusNoContent)
 }
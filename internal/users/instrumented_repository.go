@@ -0,0 +1,56 @@
+package users
+
+import (
+	"context"
+
+	"example.com/myapp/internal/metrics"
+)
+
+// instrumentedRepository records the latency of every call to the wrapped
+// repository and traces it as a child span of the caller
+type instrumentedRepository struct {
+	next Repository
+}
+
+// NewInstrumentedRepository wraps repo with operation metrics and spans
+func NewInstrumentedRepository(repo Repository) Repository {
+	return &instrumentedRepository{next: repo}
+}
+
+func (r *instrumentedRepository) Create(ctx context.Context, user *User, eventTypes ...string) error {
+	return metrics.ObserveRepositoryCall(ctx, "users", "create", func(ctx context.Context) error {
+		return r.next.Create(ctx, user, eventTypes...)
+	})
+}
+
+func (r *instrumentedRepository) GetByID(ctx context.Context, id int) (*User, error) {
+	return metrics.ObserveRepository(ctx, "users", "get_by_id", func(ctx context.Context) (*User, error) {
+		return r.next.GetByID(ctx, id)
+	})
+}
+
+func (r *instrumentedRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	return metrics.ObserveRepository(ctx, "users", "get_by_email", func(ctx context.Context) (*User, error) {
+		return r.next.GetByEmail(ctx, email)
+	})
+}
+
+func (r *instrumentedRepository) GetAll(ctx context.Context) ([]*User, error) {
+	return metrics.ObserveRepository(ctx, "users", "get_all", r.next.GetAll)
+}
+
+func (r *instrumentedRepository) Update(ctx context.Context, user *User, eventTypes ...string) error {
+	return metrics.ObserveRepositoryCall(ctx, "users", "update", func(ctx context.Context) error {
+		return r.next.Update(ctx, user, eventTypes...)
+	})
+}
+
+func (r *instrumentedRepository) Delete(ctx context.Context, id, version int, eventTypes ...string) error {
+	return metrics.ObserveRepositoryCall(ctx, "users", "delete", func(ctx context.Context) error {
+		return r.next.Delete(ctx, id, version, eventTypes...)
+	})
+}
+
+func (r *instrumentedRepository) Ping(ctx context.Context) error {
+	return metrics.ObserveRepositoryCall(ctx, "users", "ping", r.next.Ping)
+}
@@ -2,23 +2,31 @@ package users
 
 import (
 	"context"
+	"strconv"
+	"strings"
 	"sync"
 
 	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/outbox"
 )
 
 // InMemoryRepository is an in-memory implementation of Repository
 type InMemoryRepository struct {
-	mu     sync.RWMutex
-	users  map[int]*User
-	nextID int
+	mu      sync.RWMutex
+	users   map[int]*User
+	byEmail map[string]int // normalized email -> user ID
+	nextID  int
+	outbox  outbox.Store
 }
 
-// NewInMemoryRepository creates a new in-memory user repository
-func NewInMemoryRepository() *InMemoryRepository {
+// NewInMemoryRepository creates a new in-memory user repository that stages
+// events in outboxStore
+func NewInMemoryRepository(outboxStore outbox.Store) *InMemoryRepository {
 	repo := &InMemoryRepository{
-		users:  make(map[int]*User),
-		nextID: 1,
+		users:   make(map[int]*User),
+		byEmail: make(map[string]int),
+		nextID:  1,
+		outbox:  outboxStore,
 	}
 
 	// Add 3 dummy users for testing
@@ -36,7 +44,7 @@ func NewInMemoryRepository() *InMemoryRepository {
 }
 
 // Create adds a new user to the repository
-func (r *InMemoryRepository) Create(ctx context.Context, user *User) error {
+func (r *InMemoryRepository) Create(ctx context.Context, user *User, eventTypes ...string) error {
 	// Check context cancellation
 	if err := ctx.Err(); err != nil {
 		return appErr.Internal("context cancelled", err)
@@ -45,8 +53,21 @@ func (r *InMemoryRepository) Create(ctx context.Context, user *User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	user.ID = r.nextID
-	r.users[r.nextID] = user
+	key := normalizeEmail(user.Email)
+	if _, taken := r.byEmail[key]; taken {
+		return appErr.Conflict("email is already in use")
+	}
+
+	created := cloneUser(user)
+	created.ID = r.nextID
+	created.Version = 1
+	if err := outbox.Stage(ctx, r.outbox, strconv.Itoa(created.ID), created, eventTypes...); err != nil {
+		return appErr.Internal("failed to stage user events", err)
+	}
+
+	user.ID, user.Version = created.ID, created.Version
+	r.users[r.nextID] = created
+	r.byEmail[key] = user.ID
 	r.nextID++
 	return nil
 }
@@ -64,7 +85,23 @@ func (r *InMemoryRepository) GetByID(ctx context.Context, id int) (*User, error)
 	if !exists {
 		return nil, appErr.NotFound("user not found")
 	}
-	return user, nil
+	return cloneUser(user), nil
+}
+
+// GetByEmail retrieves a user by email, ignoring case
+func (r *InMemoryRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, exists := r.byEmail[normalizeEmail(email)]
+	if !exists {
+		return nil, appErr.NotFound("user not found")
+	}
+	return cloneUser(r.users[id]), nil
 }
 
 // GetAll retrieves all users
@@ -78,13 +115,14 @@ func (r *InMemoryRepository) GetAll(ctx context.Context) ([]*User, error) {
 
 	users := make([]*User, 0, len(r.users))
 	for _, user := range r.users {
-		users = append(users, user)
+		users = append(users, cloneUser(user))
 	}
 	return users, nil
 }
 
-// Update modifies an existing user
-func (r *InMemoryRepository) Update(ctx context.Context, user *User) error {
+// Update modifies an existing user. user.Version must match the stored
+// version; on success it is incremented.
+func (r *InMemoryRepository) Update(ctx context.Context, user *User, eventTypes ...string) error {
 	if err := ctx.Err(); err != nil {
 		return appErr.Internal("context cancelled", err)
 	}
@@ -92,15 +130,34 @@ func (r *InMemoryRepository) Update(ctx context.Context, user *User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.users[user.ID]; !exists {
+	existing, exists := r.users[user.ID]
+	if !exists {
 		return appErr.NotFound("user not found")
 	}
-	r.users[user.ID] = user
+	if existing.Version != user.Version {
+		return appErr.PreconditionFailed("user was modified concurrently")
+	}
+
+	oldKey, newKey := normalizeEmail(existing.Email), normalizeEmail(user.Email)
+	if ownerID, taken := r.byEmail[newKey]; taken && ownerID != user.ID {
+		return appErr.Conflict("email is already in use")
+	}
+
+	updated := cloneUser(user)
+	updated.Version++
+	if err := outbox.Stage(ctx, r.outbox, strconv.Itoa(updated.ID), updated, eventTypes...); err != nil {
+		return appErr.Internal("failed to stage user events", err)
+	}
+
+	delete(r.byEmail, oldKey)
+	r.byEmail[newKey] = user.ID
+	user.Version = updated.Version
+	r.users[user.ID] = updated
 	return nil
 }
 
-// Delete removes a user from the repository
-func (r *InMemoryRepository) Delete(ctx context.Context, id int) error {
+// Delete removes a user from the repository if it is still at version
+func (r *InMemoryRepository) Delete(ctx context.Context, id, version int, eventTypes ...string) error {
 	if err := ctx.Err(); err != nil {
 		return appErr.Internal("context cancelled", err)
 	}
@@ -108,9 +165,40 @@ func (r *InMemoryRepository) Delete(ctx context.Context, id int) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.users[id]; !exists {
+	user, exists := r.users[id]
+	if !exists {
 		return appErr.NotFound("user not found")
 	}
+	if user.Version != version {
+		return appErr.PreconditionFailed("user was modified concurrently")
+	}
+	if err := outbox.Stage(ctx, r.outbox, strconv.Itoa(id), user, eventTypes...); err != nil {
+		return appErr.Internal("failed to stage user events", err)
+	}
+	delete(r.byEmail, normalizeEmail(user.Email))
 	delete(r.users, id)
 	return nil
 }
+
+// Ping checks that the repository lock can be taken, so a deadlocked
+// repository fails readiness
+func (r *InMemoryRepository) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.RLock()
+	r.mu.RUnlock()
+	return nil
+}
+
+// cloneUser returns a copy so callers never share the stored user
+func cloneUser(user *User) *User {
+	c := *user
+	return &c
+}
+
+// normalizeEmail is the key emails are compared by
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -5,6 +5,8 @@ type User struct {
 	Name  string `json:"name"`
 	Email string `json:"email"`
 	Age   int    `json:"age"`
+	// Version increments on every update and is exposed as the ETag
+	Version int `json:"version"`
 }
 
 type CreateUserRequest struct {
@@ -13,8 +15,10 @@ type CreateUserRequest struct {
 	Age   int    `json:"age"`
 }
 
+// UpdateUserRequest is a full replacement of a user. Age is a pointer so an
+// omitted age is reported as missing instead of being zeroed.
 type UpdateUserRequest struct {
 	Name  string `json:"name"`
 	Email string `json:"email"`
-	Age   int    `json:"age"`
+	Age   *int   `json:"age"`
 }
@@ -2,11 +2,25 @@ package users
 
 import "context"
 
-// Repository defines the interface for user persistence
+// Repository defines the interface for user persistence.
+// Implementations must keep emails unique ignoring case and return a
+// Conflict AppError from Create and Update when an email is already taken.
+// Users are returned as copies. Writes are optimistic: Update and Delete
+// fail with a PreconditionFailed AppError when the given version is no
+// longer the stored one.
+//
+// Write methods take the types of the events describing the change. They
+// are staged in the outbox together with the write, so an event exists if
+// and only if the write happened; the event data is the user after the
+// write, or before it for Delete.
 type Repository interface {
-	Create(ctx context.Context, user *User) error
+	Create(ctx context.Context, user *User, eventTypes ...string) error
 	GetByID(ctx context.Context, id int) (*User, error)
+	// GetByEmail looks a user up by email, ignoring case
+	GetByEmail(ctx context.Context, email string) (*User, error)
 	GetAll(ctx context.Context) ([]*User, error)
-	Update(ctx context.Context, user *User) error
-	Delete(ctx context.Context, id int) error
+	Update(ctx context.Context, user *User, eventTypes ...string) error
+	Delete(ctx context.Context, id, version int, eventTypes ...string) error
+	// Ping reports whether the underlying store can serve requests
+	Ping(ctx context.Context) error
 }
@@ -1,19 +1,31 @@
 package users
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"strconv"
+	"strings"
 
+	"example.com/myapp/internal/audit"
 	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/etag"
+	"example.com/myapp/internal/events"
+	"example.com/myapp/internal/patch"
+	"example.com/myapp/internal/validation"
 )
 
 type Service struct {
-	repo Repository
+	repo  Repository
+	audit audit.Recorder
 }
 
-func NewService(repo Repository) *Service {
+func NewService(repo Repository, auditRecorder audit.Recorder) *Service {
 	return &Service{
-		repo: repo,
+		repo:  repo,
+		audit: auditRecorder,
 	}
 }
 
@@ -23,8 +35,8 @@ func (s *Service) CreateUser(ctx context.Context, req *CreateUserRequest) (*User
 		return nil, appErr.Internal("context cancelled", err)
 	}
 
-	if req.Name == "" || req.Email == "" {
-		return nil, appErr.BadRequest("name and email are required")
+	if err := req.Validate(); err != nil {
+		return nil, err
 	}
 
 	user := &User{
@@ -32,11 +44,29 @@ func (s *Service) CreateUser(ctx context.Context, req *CreateUserRequest) (*User
 		Email: req.Email,
 		Age:   req.Age,
 	}
-	err := s.repo.Create(ctx, user)
+	err := s.repo.Create(ctx, user, events.UserCreated)
 	if err != nil {
-		slog.Error("Failed to create user", "error", err)
+		slog.ErrorContext(ctx, "Failed to create user", "error", err)
+		if appErr.HasCode(err, appErr.ErrCodeConflict) {
+			return nil, err
+		}
 		return nil, appErr.Internal("failed to create user", err)
 	}
+	s.audit.Record(ctx, audit.ActionUserCreate, audit.TargetUser, strconv.Itoa(user.ID), nil, user)
+	return user, nil
+}
+
+// Get user by email, ignoring case
+func (s *Service) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	user, err := s.repo.GetByEmail(ctx, email)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get user by email", "error", err)
+		return nil, err
+	}
 	return user, nil
 }
 
@@ -52,7 +82,7 @@ func (s *Service) GetUser(ctx context.Context, id int) (*User, error) {
 
 	user, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		slog.Error("Failed to get user", "id", id, "error", err)
+		slog.ErrorContext(ctx, "Failed to get user", "id", id, "error", err)
 		return nil, err
 	}
 	return user, nil
@@ -66,14 +96,14 @@ func (s *Service) GetAllUsers(ctx context.Context) ([]*User, error) {
 
 	users, err := s.repo.GetAll(ctx)
 	if err != nil {
-		slog.Error("Failed to get all users", "error", err)
+		slog.ErrorContext(ctx, "Failed to get all users", "error", err)
 		return nil, appErr.Internal("failed to retrieve users", err)
 	}
 	return users, nil
 }
 
-// Update user
-func (s *Service) UpdateUser(ctx context.Context, id int, req *UpdateUserRequest) (*User, error) {
+// Update user, provided its current version satisfies cond
+func (s *Service) UpdateUser(ctx context.Context, id int, req *UpdateUserRequest, cond etag.Condition) (*User, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, appErr.Internal("context cancelled", err)
 	}
@@ -82,30 +112,113 @@ func (s *Service) UpdateUser(ctx context.Context, id int, req *UpdateUserRequest
 		return nil, appErr.InvalidID("user id must be positive")
 	}
 
-	if req.Name == "" || req.Email == "" {
-		return nil, appErr.BadRequest("name and email are required")
+	if err := req.Validate(); err != nil {
+		return nil, err
 	}
 
 	user, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		slog.Error("Failed to get user for update", "id", id, "error", err)
+		slog.ErrorContext(ctx, "Failed to get user for update", "id", id, "error", err)
+		return nil, err
+	}
+	if err := cond.Check(user.Version); err != nil {
 		return nil, err
 	}
 
-	user.Name = req.Name
-	user.Email = req.Email
-	user.Age = req.Age
+	return s.replaceUser(ctx, user, req)
+}
 
-	err = s.repo.Update(ctx, user)
+// Patch user with a JSON Merge Patch or JSON Patch document, selected by
+// contentType. Validation is applied to the resulting user.
+func (s *Service) PatchUser(ctx context.Context, id int, contentType string, patchDoc []byte, cond etag.Condition) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	if id <= 0 {
+		return nil, appErr.InvalidID("user id must be positive")
+	}
+
+	user, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		slog.Error("Failed to update user", "id", id, "error", err)
+		slog.ErrorContext(ctx, "Failed to get user for patch", "id", id, "error", err)
+		return nil, err
+	}
+	if err := cond.Check(user.Version); err != nil {
+		return nil, err
+	}
+
+	current, err := json.Marshal(user)
+	if err != nil {
+		return nil, appErr.Internal("failed to encode user", err)
+	}
+	patched, err := patch.Apply(contentType, current, patchDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := decodePatchedUser(patched, user)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.replaceUser(ctx, user, req)
+}
+
+// replaceUser stores a validated full replacement of user
+func (s *Service) replaceUser(ctx context.Context, user *User, req *UpdateUserRequest) (*User, error) {
+	// Modify a copy so the repository can compare against the stored email
+	updated := *user
+	updated.Name = req.Name
+	updated.Email = req.Email
+	updated.Age = *req.Age
+
+	err := s.repo.Update(ctx, &updated, events.UserUpdated)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to update user", "id", user.ID, "error", err)
+		if appErr.HasCode(err, appErr.ErrCodeConflict, appErr.ErrCodeNotFound, appErr.ErrCodePrecondition) {
+			return nil, err
+		}
 		return nil, appErr.Internal("failed to update user", err)
 	}
-	return user, nil
+	s.audit.Record(ctx, audit.ActionUserUpdate, audit.TargetUser, strconv.Itoa(user.ID), user, &updated)
+	return &updated, nil
+}
+
+// decodePatchedUser converts a patched user document back into a full
+// replacement request. Unknown members, wrongly typed values and changes
+// to the ID or version are rejected.
+func decodePatchedUser(doc []byte, user *User) (*UpdateUserRequest, error) {
+	var patched struct {
+		ID      *int `json:"id"`
+		Version *int `json:"version"`
+		UpdateUserRequest
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&patched); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, appErr.Validation(appErr.FieldError{Field: typeErr.Field, Message: "must be of type " + typeErr.Type.String()})
+		}
+		return nil, appErr.BadRequest("patched user is invalid: " + strings.TrimPrefix(err.Error(), "json: "))
+	}
+
+	v := validation.New()
+	v.Check(patched.ID != nil && *patched.ID == user.ID, "id", "cannot be changed")
+	v.Check(patched.Version != nil && *patched.Version == user.Version, "version", "cannot be changed")
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return &patched.UpdateUserRequest, nil
 }
 
-// Delete user
-func (s *Service) DeleteUser(ctx context.Context, id int) error {
+// Delete user, provided its current version satisfies cond
+func (s *Service) DeleteUser(ctx context.Context, id int, cond etag.Condition) error {
 	if err := ctx.Err(); err != nil {
 		return appErr.Internal("context cancelled", err)
 	}
@@ -114,10 +227,20 @@ func (s *Service) DeleteUser(ctx context.Context, id int) error {
 		return appErr.InvalidID("user id must be positive")
 	}
 
-	err := s.repo.Delete(ctx, id)
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get user for deletion", "id", id, "error", err)
+		return err
+	}
+	if err := cond.Check(user.Version); err != nil {
+		return err
+	}
+
+	err = s.repo.Delete(ctx, id, user.Version, events.UserDeleted)
 	if err != nil {
-		slog.Error("Failed to delete user", "id", id, "error", err)
+		slog.ErrorContext(ctx, "Failed to delete user", "id", id, "error", err)
 		return err
 	}
+	s.audit.Record(ctx, audit.ActionUserDelete, audit.TargetUser, strconv.Itoa(id), user, nil)
 	return nil
 }
@@ -0,0 +1,246 @@
+package users
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"example.com/myapp/internal/audit"
+	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/etag"
+	"example.com/myapp/internal/outbox"
+)
+
+// newTestService returns a service over a fresh repository, which starts
+// with users 1 to 3 (john@, jane@ and bob@example.com)
+func newTestService() *Service {
+	return NewService(NewInMemoryRepository(outbox.NewInMemoryStore()), audit.NewService(audit.NewInMemoryRepository()))
+}
+
+func intPtr(n int) *int { return &n }
+
+func TestCreateUserUniqueEmail(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		wantCode string
+	}{
+		{name: "new email", email: "ada@example.com"},
+		{name: "taken email", email: "john@example.com", wantCode: appErr.ErrCodeConflict},
+		{name: "taken email in other case", email: "John@Example.COM", wantCode: appErr.ErrCodeConflict},
+		{name: "invalid email is validated first", email: "john", wantCode: appErr.ErrCodeValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService()
+			user, err := s.CreateUser(context.Background(), &CreateUserRequest{Name: "Ada", Email: tt.email, Age: 36})
+			if tt.wantCode != "" {
+				if !appErr.HasCode(err, tt.wantCode) {
+					t.Fatalf("CreateUser() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateUser() error = %v", err)
+			}
+			if user.ID != 4 || user.Version != 1 {
+				t.Errorf("created user ID %d version %d, want 4 and 1", user.ID, user.Version)
+			}
+		})
+	}
+}
+
+func TestUpdateUserUniqueEmail(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		wantCode string
+	}{
+		{name: "keeps own email", email: "john@example.com"},
+		{name: "changes case of own email", email: "JOHN@example.com"},
+		{name: "new email", email: "johnny@example.com"},
+		{name: "another user's email", email: "Jane@example.com", wantCode: appErr.ErrCodeConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService()
+			req := &UpdateUserRequest{Name: "John", Email: tt.email, Age: intPtr(31)}
+			_, err := s.UpdateUser(context.Background(), 1, req, etag.Condition{})
+			if tt.wantCode != "" {
+				if !appErr.HasCode(err, tt.wantCode) {
+					t.Fatalf("UpdateUser() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateUser() error = %v", err)
+			}
+			if got, err := s.GetUserByEmail(context.Background(), tt.email); err != nil || got.ID != 1 {
+				t.Errorf("GetUserByEmail(%q) = %v, %v, want user 1", tt.email, got, err)
+			}
+		})
+	}
+}
+
+func TestEmailReleasedOnChangeAndDelete(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService()
+
+	if _, err := s.UpdateUser(ctx, 1, &UpdateUserRequest{Name: "John", Email: "johnny@example.com", Age: intPtr(30)}, etag.Condition{}); err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	if _, err := s.CreateUser(ctx, &CreateUserRequest{Name: "New John", Email: "john@example.com", Age: 20}); err != nil {
+		t.Errorf("CreateUser() with a released email error = %v", err)
+	}
+
+	if err := s.DeleteUser(ctx, 2, etag.Condition{}); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if _, err := s.CreateUser(ctx, &CreateUserRequest{Name: "New Jane", Email: "JANE@example.com", Age: 20}); err != nil {
+		t.Errorf("CreateUser() with a deleted user's email error = %v", err)
+	}
+	if _, err := s.GetUserByEmail(ctx, "bob@EXAMPLE.com"); err != nil {
+		t.Errorf("GetUserByEmail() ignoring case error = %v", err)
+	}
+}
+
+func TestPatchUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		wantName    string
+		wantAge     int
+		wantCode    string
+	}{
+		{name: "merge patch", contentType: "application/merge-patch+json", patch: `{"age": 31}`, wantName: "John Doe", wantAge: 31},
+		{name: "json patch", contentType: "application/json-patch+json", patch: `[{"op": "replace", "path": "/name", "value": "J. Doe"}]`, wantName: "J. Doe", wantAge: 30},
+		{name: "failed test", contentType: "application/json-patch+json", patch: `[{"op": "replace", "path": "/age", "value": 99}, {"op": "test", "path": "/name", "value": "Jane"}]`, wantCode: appErr.ErrCodeConflict},
+		{name: "out of range index", contentType: "application/json-patch+json", patch: `[{"op": "add", "path": "/name/5", "value": "x"}]`, wantCode: appErr.ErrCodeBadRequest},
+		{name: "invalid result", contentType: "application/merge-patch+json", patch: `{"age": 200}`, wantCode: appErr.ErrCodeValidation},
+		{name: "removing a required field", contentType: "application/json-patch+json", patch: `[{"op": "remove", "path": "/age"}]`, wantCode: appErr.ErrCodeValidation},
+		{name: "wrong type", contentType: "application/merge-patch+json", patch: `{"age": "old"}`, wantCode: appErr.ErrCodeValidation},
+		{name: "changing the ID", contentType: "application/merge-patch+json", patch: `{"id": 7}`, wantCode: appErr.ErrCodeValidation},
+		{name: "unknown member", contentType: "application/merge-patch+json", patch: `{"role": "admin"}`, wantCode: appErr.ErrCodeBadRequest},
+		{name: "taken email", contentType: "application/merge-patch+json", patch: `{"email": "bob@example.com"}`, wantCode: appErr.ErrCodeConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestService()
+
+			got, err := s.PatchUser(ctx, 1, tt.contentType, []byte(tt.patch), etag.Condition{})
+			if tt.wantCode != "" {
+				if !appErr.HasCode(err, tt.wantCode) {
+					t.Fatalf("PatchUser() error = %v, want code %s", err, tt.wantCode)
+				}
+				stored, _ := s.GetUser(ctx, 1)
+				if stored.Name != "John Doe" || stored.Age != 30 || stored.Version != 1 {
+					t.Errorf("failed patch changed the user to %+v", stored)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PatchUser() error = %v", err)
+			}
+			if got.Name != tt.wantName || got.Age != tt.wantAge || got.Version != 2 {
+				t.Errorf("PatchUser() = %+v, want name %q, age %d, version 2", got, tt.wantName, tt.wantAge)
+			}
+		})
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	ifMatch := func(header string) etag.Condition {
+		r := httptest.NewRequest(http.MethodPut, "/users/1", nil)
+		r.Header.Set("If-Match", header)
+		return etag.IfMatch(r)
+	}
+	req := &UpdateUserRequest{Name: "John", Email: "john@example.com", Age: intPtr(31)}
+
+	tests := []struct {
+		name     string
+		run      func(s *Service) error
+		wantCode string
+	}{
+		{name: "update with current tag", run: func(s *Service) error {
+			_, err := s.UpdateUser(context.Background(), 1, req, ifMatch(`"1"`))
+			return err
+		}},
+		{name: "update with stale tag", run: func(s *Service) error {
+			_, err := s.UpdateUser(context.Background(), 1, req, ifMatch(`"0"`))
+			return err
+		}, wantCode: appErr.ErrCodePrecondition},
+		{name: "patch with stale tag", run: func(s *Service) error {
+			_, err := s.PatchUser(context.Background(), 1, "", []byte(`{"age": 1}`), ifMatch(`"2"`))
+			return err
+		}, wantCode: appErr.ErrCodePrecondition},
+		{name: "delete with wildcard", run: func(s *Service) error {
+			return s.DeleteUser(context.Background(), 1, ifMatch(`*`))
+		}},
+		{name: "delete with stale tag", run: func(s *Service) error {
+			return s.DeleteUser(context.Background(), 1, ifMatch(`"7"`))
+		}, wantCode: appErr.ErrCodePrecondition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService()
+			err := tt.run(s)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("error = %v", err)
+				}
+				return
+			}
+			if !appErr.HasCode(err, tt.wantCode) {
+				t.Fatalf("error = %v, want code %s", err, tt.wantCode)
+			}
+			if stored, err := s.GetUser(context.Background(), 1); err != nil || stored.Version != 1 {
+				t.Errorf("rejected write changed the user: %+v, %v", stored, err)
+			}
+		})
+	}
+}
+
+// TestConcurrentUpdates races writers that all read version 1; exactly one
+// may win and the others must see a precondition failure
+func TestConcurrentUpdates(t *testing.T) {
+	repo := NewInMemoryRepository(outbox.NewInMemoryStore())
+	const writers = 8
+
+	var wg sync.WaitGroup
+	results := make(chan error, writers)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := repo.GetByID(context.Background(), 1)
+			if err != nil {
+				results <- err
+				return
+			}
+			user.Age = 40 + i
+			results <- repo.Update(context.Background(), user)
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	won := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			won++
+		case !appErr.HasCode(err, appErr.ErrCodePrecondition):
+			t.Errorf("Update() error = %v, want PRECONDITION_FAILED", err)
+		}
+	}
+	// Writers that read after the winner committed also succeed, so at
+	// least one wins and the final version counts every win
+	user, _ := repo.GetByID(context.Background(), 1)
+	if won == 0 || user.Version != 1+won {
+		t.Errorf("%d writers won and the version is %d, want version %d", won, user.Version, 1+won)
+	}
+}
@@ -0,0 +1,42 @@
+package users
+
+import "example.com/myapp/internal/validation"
+
+// Field limits for user requests
+const (
+	MaxNameLength  = 100
+	MaxEmailLength = 254
+	MinAge         = 0
+	MaxAge         = 150
+
+	// MaxPatchSize limits the size of PATCH documents in bytes
+	MaxPatchSize = 64 * 1024
+)
+
+// Validate checks a create request, reporting every invalid field
+func (req *CreateUserRequest) Validate() error {
+	return validateUserFields(req.Name, req.Email, req.Age)
+}
+
+// Validate checks an update request, reporting every invalid field
+func (req *UpdateUserRequest) Validate() error {
+	v := userFieldsValidator(req.Name, req.Email)
+	v.Check(req.Age != nil, "age", "is required")
+	if req.Age != nil {
+		v.Range("age", *req.Age, MinAge, MaxAge)
+	}
+	return v.Err()
+}
+
+func validateUserFields(name, email string, age int) error {
+	v := userFieldsValidator(name, email)
+	v.Range("age", age, MinAge, MaxAge)
+	return v.Err()
+}
+
+func userFieldsValidator(name, email string) *validation.Validator {
+	v := validation.New()
+	v.Required("name", name).MaxLength("name", name, MaxNameLength)
+	v.Required("email", email).MaxLength("email", email, MaxEmailLength).Email("email", email)
+	return v
+}
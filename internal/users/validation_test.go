@@ -0,0 +1,65 @@
+package users
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	appErr "example.com/myapp/internal/errors"
+)
+
+func fieldNames(err error) []string {
+	ae := appErr.GetAppError(err)
+	if ae == nil {
+		return nil
+	}
+	names := make([]string, len(ae.Fields))
+	for i, f := range ae.Fields {
+		names[i] = f.Field
+	}
+	return names
+}
+
+func TestCreateUserRequestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		req  CreateUserRequest
+		want []string
+	}{
+		{name: "valid", req: CreateUserRequest{Name: "Ada", Email: "ada@example.com", Age: 36}},
+		{name: "age bounds are inclusive", req: CreateUserRequest{Name: "Ada", Email: "ada@example.com", Age: MaxAge}},
+		{name: "every field invalid", req: CreateUserRequest{Name: " ", Email: "ada", Age: -1}, want: []string{"name", "email", "age"}},
+		{name: "name too long", req: CreateUserRequest{Name: strings.Repeat("a", MaxNameLength+1), Email: "ada@example.com"}, want: []string{"name"}},
+		{name: "email too long", req: CreateUserRequest{Name: "Ada", Email: strings.Repeat("a", MaxEmailLength) + "@example.com"}, want: []string{"email"}},
+		{name: "age too high", req: CreateUserRequest{Name: "Ada", Email: "ada@example.com", Age: MaxAge + 1}, want: []string{"age"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if got := fieldNames(err); !slices.Equal(got, tt.want) {
+				t.Errorf("Validate() fields = %v, want %v (error %v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestUpdateUserRequestValidate(t *testing.T) {
+	age := 40
+	tests := []struct {
+		name string
+		req  UpdateUserRequest
+		want []string
+	}{
+		{name: "valid", req: UpdateUserRequest{Name: "Ada", Email: "ada@example.com", Age: &age}},
+		{name: "missing age", req: UpdateUserRequest{Name: "Ada", Email: "ada@example.com"}, want: []string{"age"}},
+		{name: "missing everything", req: UpdateUserRequest{}, want: []string{"name", "email", "age"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if got := fieldNames(err); !slices.Equal(got, tt.want) {
+				t.Errorf("Validate() fields = %v, want %v (error %v)", got, tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Package validation collects field-level rule violations for request
+// payloads and query parameters and reports them together as one error.
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	appErr "example.com/myapp/internal/errors"
+)
+
+// Validator accumulates field errors. Rules are chained per field and only
+// the first failing rule of each field is reported.
+type Validator struct {
+	errors []appErr.FieldError
+	failed map[string]bool
+}
+
+// New creates an empty validator
+func New() *Validator {
+	return &Validator{failed: make(map[string]bool)}
+}
+
+// Check records message for field when ok is false
+func (v *Validator) Check(ok bool, field, message string) *Validator {
+	if !ok && !v.failed[field] {
+		v.failed[field] = true
+		v.errors = append(v.errors, appErr.FieldError{Field: field, Message: message})
+	}
+	return v
+}
+
+// Required rejects empty or whitespace-only strings
+func (v *Validator) Required(field, value string) *Validator {
+	return v.Check(strings.TrimSpace(value) != "", field, "is required")
+}
+
+// MinLength rejects strings shorter than min characters
+func (v *Validator) MinLength(field, value string, min int) *Validator {
+	return v.Check(utf8.RuneCountInString(value) >= min, field, fmt.Sprintf("must be at least %d characters", min))
+}
+
+// MaxLength rejects strings longer than max characters
+func (v *Validator) MaxLength(field, value string, max int) *Validator {
+	return v.Check(utf8.RuneCountInString(value) <= max, field, fmt.Sprintf("must be at most %d characters", max))
+}
+
+// Email rejects values that are not a bare address such as user@example.com.
+// Empty values pass so optional fields can be validated; combine with
+// Required when the field is mandatory.
+func (v *Validator) Email(field, value string) *Validator {
+	if value == "" {
+		return v
+	}
+	return v.Check(isEmail(value), field, "must be a valid email address")
+}
+
+// Range rejects integers outside [min, max]
+func (v *Validator) Range(field string, value, min, max int) *Validator {
+	return v.Check(value >= min && value <= max, field, fmt.Sprintf("must be between %d and %d", min, max))
+}
+
+// OneOf rejects values not in allowed. Empty values pass.
+func (v *Validator) OneOf(field, value string, allowed ...string) *Validator {
+	if value == "" {
+		return v
+	}
+	for _, a := range allowed {
+		if value == a {
+			return v
+		}
+	}
+	return v.Check(false, field, "must be one of: "+strings.Join(allowed, ", "))
+}
+
+// Matches rejects non-empty values that do not match re
+func (v *Validator) Matches(field, value string, re *regexp.Regexp, message string) *Validator {
+	if value == "" {
+		return v
+	}
+	return v.Check(re.MatchString(value), field, message)
+}
+
+// MaxItems rejects lists with more than max entries
+func (v *Validator) MaxItems(field string, n, max int) *Validator {
+	return v.Check(n <= max, field, fmt.Sprintf("must contain at most %d items", max))
+}
+
+// Int parses an optional integer parameter, returning def when value is
+// empty and recording an error when it is not a number
+func (v *Validator) Int(field, value string, def int) int {
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	v.Check(err == nil, field, "must be an integer")
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// Time parses an optional RFC 3339 timestamp parameter, returning the zero
+// time when value is empty and recording an error when it is malformed
+func (v *Validator) Time(field, value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	v.Check(err == nil, field, "must be an RFC 3339 timestamp")
+	return t
+}
+
+// Valid reports whether no rule has failed
+func (v *Validator) Valid() bool {
+	return len(v.errors) == 0
+}
+
+// Errors returns the recorded field errors
+func (v *Validator) Errors() []appErr.FieldError {
+	return v.errors
+}
+
+// Err returns nil when valid, otherwise a validation AppError listing every
+// failed field
+func (v *Validator) Err() error {
+	if v.Valid() {
+		return nil
+	}
+	return appErr.Validation(v.errors...)
+}
+
+func isEmail(value string) bool {
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value || addr.Name != "" {
+		return false
+	}
+	at := strings.LastIndexByte(value, '@')
+	return at > 0 && strings.Contains(value[at+1:], ".")
+}
@@ -0,0 +1,119 @@
+package validation
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	appErr "example.com/myapp/internal/errors"
+)
+
+func TestRules(t *testing.T) {
+	slug := regexp.MustCompile(`^[a-z-]+$`)
+
+	tests := []struct {
+		name string
+		run  func(v *Validator)
+		want string // expected message, "" when the rule passes
+	}{
+		{name: "required", run: func(v *Validator) { v.Required("f", "x") }},
+		{name: "required empty", run: func(v *Validator) { v.Required("f", "") }, want: "is required"},
+		{name: "required blank", run: func(v *Validator) { v.Required("f", " \t") }, want: "is required"},
+		{name: "min length counts runes", run: func(v *Validator) { v.MinLength("f", "né", 2) }},
+		{name: "min length", run: func(v *Validator) { v.MinLength("f", "a", 2) }, want: "must be at least 2 characters"},
+		{name: "max length counts runes", run: func(v *Validator) { v.MaxLength("f", "ééé", 3) }},
+		{name: "max length", run: func(v *Validator) { v.MaxLength("f", "abcd", 3) }, want: "must be at most 3 characters"},
+		{name: "email", run: func(v *Validator) { v.Email("f", "ada@example.com") }},
+		{name: "email empty passes", run: func(v *Validator) { v.Email("f", "") }},
+		{name: "email with display name", run: func(v *Validator) { v.Email("f", "Ada <ada@example.com>") }, want: "must be a valid email address"},
+		{name: "email without domain dot", run: func(v *Validator) { v.Email("f", "ada@localhost") }, want: "must be a valid email address"},
+		{name: "email without at", run: func(v *Validator) { v.Email("f", "ada.example.com") }, want: "must be a valid email address"},
+		{name: "range inclusive", run: func(v *Validator) { v.Range("f", 10, 1, 10) }},
+		{name: "range below", run: func(v *Validator) { v.Range("f", 0, 1, 10) }, want: "must be between 1 and 10"},
+		{name: "one of", run: func(v *Validator) { v.OneOf("f", "b", "a", "b") }},
+		{name: "one of empty passes", run: func(v *Validator) { v.OneOf("f", "", "a") }},
+		{name: "one of other", run: func(v *Validator) { v.OneOf("f", "c", "a", "b") }, want: "must be one of: a, b"},
+		{name: "matches", run: func(v *Validator) { v.Matches("f", "a-b", slug, "must be a slug") }},
+		{name: "matches fails", run: func(v *Validator) { v.Matches("f", "A B", slug, "must be a slug") }, want: "must be a slug"},
+		{name: "max items", run: func(v *Validator) { v.MaxItems("f", 3, 3) }},
+		{name: "max items exceeded", run: func(v *Validator) { v.MaxItems("f", 4, 3) }, want: "must contain at most 3 items"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			tt.run(v)
+			var want []appErr.FieldError
+			if tt.want != "" {
+				want = []appErr.FieldError{{Field: "f", Message: tt.want}}
+			}
+			if !slices.Equal(v.Errors(), want) {
+				t.Errorf("Errors() = %v, want %v", v.Errors(), want)
+			}
+		})
+	}
+}
+
+func TestFirstFailurePerField(t *testing.T) {
+	v := New().
+		Required("name", "").
+		MinLength("name", "", 2).
+		Email("email", "nope").
+		Required("email", "nope").
+		Range("age", 200, 0, 150)
+
+	want := []appErr.FieldError{
+		{Field: "name", Message: "is required"},
+		{Field: "email", Message: "must be a valid email address"},
+		{Field: "age", Message: "must be between 0 and 150"},
+	}
+	if !slices.Equal(v.Errors(), want) {
+		t.Errorf("Errors() = %v, want %v", v.Errors(), want)
+	}
+
+	err := v.Err()
+	ae := appErr.GetAppError(err)
+	if ae == nil || ae.Code != appErr.ErrCodeValidation || !slices.Equal(ae.Fields, want) {
+		t.Errorf("Err() = %v, want a validation error listing every field", err)
+	}
+	if err := New().Required("name", "x").Err(); err != nil {
+		t.Errorf("Err() of a valid request = %v, want nil", err)
+	}
+}
+
+func TestParsers(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantInt int
+		wantErr bool
+	}{
+		{name: "empty uses default", value: "", wantInt: 20},
+		{name: "number", value: "5", wantInt: 5},
+		{name: "negative", value: "-3", wantInt: -3},
+		{name: "not a number", value: "ten", wantInt: 20, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run("int "+tt.name, func(t *testing.T) {
+			v := New()
+			if got := v.Int("limit", tt.value, 20); got != tt.wantInt {
+				t.Errorf("Int() = %d, want %d", got, tt.wantInt)
+			}
+			if v.Valid() == tt.wantErr {
+				t.Errorf("Valid() = %v, want %v", v.Valid(), !tt.wantErr)
+			}
+		})
+	}
+
+	v := New()
+	if got := v.Time("since", "2024-05-01T10:00:00Z"); !got.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) || !v.Valid() {
+		t.Errorf("Time() = %v, valid %v", got, v.Valid())
+	}
+	if got := v.Time("since", ""); !got.IsZero() || !v.Valid() {
+		t.Errorf("Time(\"\") = %v, want zero time", got)
+	}
+	if v.Time("until", "yesterday"); v.Valid() {
+		t.Error("Time() accepted a malformed timestamp")
+	}
+}
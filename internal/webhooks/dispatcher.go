@@ -0,0 +1,324 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	appErr "example.com/myapp/internal/errors"
+	"example.com/myapp/internal/events"
+	"example.com/myapp/internal/tracing"
+)
+
+// Delivery tuning
+const (
+	MaxAttempts     = 8
+	InitialBackoff  = time.Second
+	MaxBackoff      = 10 * time.Minute
+	DeliveryTimeout = 10 * time.Second
+	PollInterval    = time.Second
+	MaxConcurrency  = 4
+	// ClaimLease is how long a claimed delivery stays delivering. A
+	// delivery whose lease runs out, because the process sending it
+	// stopped, is claimed again.
+	ClaimLease = 6 * DeliveryTimeout
+)
+
+// Dispatcher turns published events into deliveries and sends them,
+// retrying failures with exponential backoff until MaxAttempts is reached
+// and the delivery is dead-lettered
+type Dispatcher struct {
+	repo   Repository
+	client *http.Client
+	wake   chan struct{}
+	slots  chan struct{}
+	wg     sync.WaitGroup
+
+	// checkTarget vets subscription URLs before every attempt
+	checkTarget func(*url.URL) error
+
+	// polledAt is the Unix time in nanoseconds of the last poll
+	polledAt atomic.Int64
+}
+
+// NewDispatcher creates a dispatcher. Call Run to start sending.
+func NewDispatcher(repo Repository) *Dispatcher {
+	return &Dispatcher{
+		repo:        repo,
+		client:      newClient(),
+		checkTarget: checkTarget,
+		wake:        make(chan struct{}, 1),
+		slots:       make(chan struct{}, MaxConcurrency),
+	}
+}
+
+// HandleEvent is an events.Handler that queues a delivery for every
+// subscription interested in the event. Delivery IDs are derived from the
+// event and subscription IDs, so an event handled twice is queued once.
+func (d *Dispatcher) HandleEvent(ctx context.Context, event events.Event) error {
+	subs, err := d.repo.GetAllSubscriptions(ctx)
+	if err != nil {
+		return fmt.Errorf("load webhook subscriptions: %w", err)
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode webhook payload: %w", err)
+	}
+
+	var errs []error
+
+	var traceParent string
+	if sc := tracing.SpanContextFromContext(ctx); sc.IsValid() {
+		traceParent = tracing.FormatTraceParent(sc)
+	}
+
+	now := time.Now().UTC()
+	for _, sub := range subs {
+		if !sub.Wants(event.Type) {
+			continue
+		}
+		delivery := &Delivery{
+			ID:             deliveryID(event.ID, sub.ID),
+			SubscriptionID: sub.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        payload,
+			Status:         StatusPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+			TraceParent:    traceParent,
+		}
+		err := d.repo.AddDelivery(ctx, delivery)
+		switch {
+		case appErr.HasCode(err, appErr.ErrCodeConflict):
+			slog.InfoContext(ctx, "Skipping duplicate webhook delivery", "event_id", event.ID, "subscription_id", sub.ID)
+		case err != nil:
+			errs = append(errs, fmt.Errorf("queue webhook delivery for subscription %s: %w", sub.ID, err))
+		}
+	}
+	d.Notify()
+	return errors.Join(errs...)
+}
+
+// deliveryID is the stable ID of the delivery of an event to a subscription
+func deliveryID(eventID, subscriptionID string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("webhook-delivery:"+eventID+"/"+subscriptionID)).String()
+}
+
+// Notify wakes the dispatcher so newly due deliveries are sent without
+// waiting for the next poll
+func (d *Dispatcher) Notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends due deliveries until ctx is cancelled, then waits for
+// in-flight requests to finish
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatchDue(ctx)
+		d.polledAt.Store(time.Now().UnixNano())
+
+		select {
+		case <-ctx.Done():
+			d.wg.Wait()
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// LastPolledAt reports when the dispatcher last checked for due
+// deliveries; it is zero until Run is started
+func (d *Dispatcher) LastPolledAt() time.Time {
+	if ns := d.polledAt.Load(); ns != 0 {
+		return time.Unix(0, ns).UTC()
+	}
+	return time.Time{}
+}
+
+// Healthy reports an error when the dispatcher has not polled for due
+// deliveries within maxLag. Failing receivers do not affect it; their
+// deliveries are retried and dead-lettered.
+func (d *Dispatcher) Healthy(maxLag time.Duration) error {
+	polled := d.LastPolledAt()
+	if polled.IsZero() {
+		return errors.New("dispatcher has not started")
+	}
+	if since := time.Since(polled); since > maxLag {
+		return fmt.Errorf("dispatcher last polled %s ago", since.Round(time.Second))
+	}
+	return nil
+}
+
+// dispatchDue claims as many due deliveries as there are free slots
+func (d *Dispatcher) dispatchDue(ctx context.Context) {
+	free := cap(d.slots) - len(d.slots)
+	if free == 0 {
+		return
+	}
+
+	due, err := d.repo.ClaimDue(ctx, time.Now().UTC(), ClaimLease, free)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to claim webhook deliveries", "error", err)
+		return
+	}
+
+	for _, delivery := range due {
+		d.slots <- struct{}{}
+		d.wg.Add(1)
+		go func(delivery *Delivery) {
+			defer func() {
+				<-d.slots
+				d.wg.Done()
+			}()
+			d.attempt(delivery)
+		}(delivery)
+	}
+}
+
+// attempt sends a delivery once and records the outcome. In-flight
+// requests are not tied to the Run context so shutdown lets them finish.
+// Each attempt is a client span in the trace the event was published in.
+func (d *Dispatcher) attempt(delivery *Delivery) {
+	ctx, cancel := context.WithTimeout(context.Background(), DeliveryTimeout)
+	defer cancel()
+
+	if sc, ok := tracing.ParseTraceParent(delivery.TraceParent); ok {
+		ctx = tracing.ContextWithRemoteSpanContext(ctx, sc)
+	}
+	ctx, span := tracing.Start(ctx, "webhook.deliver "+delivery.EventType, tracing.WithKind(tracing.KindClient), tracing.WithAttributes(
+		tracing.Attr("webhook.delivery_id", delivery.ID),
+		tracing.Attr("webhook.subscription_id", delivery.SubscriptionID),
+		tracing.Attr("webhook.attempt", delivery.FailedAttempts+1),
+	))
+	defer span.End()
+
+	sub, err := d.repo.GetSubscription(ctx, delivery.SubscriptionID)
+	if appErr.HasCode(err, appErr.ErrCodeNotFound) {
+		delivery.Attempts = append(delivery.Attempts, Attempt{At: time.Now().UTC(), Error: "subscription no longer exists"})
+		span.SetError(err)
+		d.finish(ctx, delivery, StatusDeadLettered)
+		return
+	}
+	if err != nil {
+		// The subscription could not be loaded, which says nothing about
+		// the receiver; release the lease without counting an attempt
+		span.SetError(err)
+		slog.ErrorContext(ctx, "Failed to load webhook subscription", "delivery_id", delivery.ID, "subscription_id", delivery.SubscriptionID, "error", err)
+		delivery.NextAttemptAt = time.Now().UTC().Add(backoff(delivery.FailedAttempts + 1))
+		d.finish(ctx, delivery, StatusPending)
+		return
+	}
+	// Subscriptions created before targets were restricted may point at
+	// internal hosts; retrying would not help
+	if err := d.vet(sub.URL); err != nil {
+		delivery.Attempts = append(delivery.Attempts, Attempt{At: time.Now().UTC(), Error: err.Error()})
+		span.SetError(err)
+		slog.WarnContext(ctx, "Webhook delivery dead-lettered", "delivery_id", delivery.ID, "subscription_id", sub.ID, "error", err)
+		d.finish(ctx, delivery, StatusDeadLettered)
+		return
+	}
+
+	start := time.Now()
+	status, err := d.send(ctx, sub, delivery)
+	span.SetAttributes(tracing.Attr("http.response.status_code", status))
+	span.SetError(err)
+	result := Attempt{
+		At:             start.UTC(),
+		ResponseStatus: status,
+		DurationMs:     time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	delivery.Attempts = append(delivery.Attempts, result)
+	if err != nil {
+		delivery.FailedAttempts++
+	}
+
+	switch {
+	case err == nil:
+		d.finish(ctx, delivery, StatusSucceeded)
+	case delivery.FailedAttempts >= MaxAttempts:
+		slog.WarnContext(ctx, "Webhook delivery dead-lettered", "delivery_id", delivery.ID, "subscription_id", sub.ID, "error", err)
+		d.finish(ctx, delivery, StatusDeadLettered)
+	default:
+		delivery.NextAttemptAt = time.Now().UTC().Add(backoff(delivery.FailedAttempts))
+		d.finish(ctx, delivery, StatusPending)
+	}
+}
+
+// vet parses a subscription URL and checks it with checkTarget
+func (d *Dispatcher) vet(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	return d.checkTarget(u)
+}
+
+// send posts the signed payload and treats any 2xx response as success
+func (d *Dispatcher) send(ctx context.Context, sub *Subscription, delivery *Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDeliveryID, delivery.ID)
+	req.Header.Set(HeaderIdempotencyKey, delivery.EventID)
+	req.Header.Set(HeaderEventType, delivery.EventType)
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, time.Now().Unix(), delivery.Payload))
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) finish(ctx context.Context, delivery *Delivery, status string) {
+	delivery.Status = status
+	delivery.UpdatedAt = time.Now().UTC()
+	if err := d.repo.SaveDelivery(ctx, delivery); err != nil {
+		slog.ErrorContext(ctx, "Failed to save webhook delivery", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// backoff returns the delay before the next attempt: InitialBackoff doubled
+// per failed attempt, capped at MaxBackoff, with up to 20% jitter so
+// retries from many deliveries spread out
+func backoff(failedAttempts int) time.Duration {
+	delay := MaxBackoff
+	if shift := failedAttempts - 1; shift < 20 {
+		delay = min(InitialBackoff<<shift, MaxBackoff)
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
@@ -0,0 +1,249 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/myapp/internal/events"
+)
+
+func TestClaimDueLease(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRepository()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	add := func(id, status string, next time.Time) {
+		t.Helper()
+		if err := repo.AddDelivery(ctx, &Delivery{ID: id, Status: status, NextAttemptAt: next, CreatedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("due", StatusPending, now.Add(-time.Second))
+	add("later", StatusPending, now.Add(time.Minute))
+	add("stale", StatusDelivering, now.Add(-2*time.Second))
+	add("leased", StatusDelivering, now.Add(time.Second))
+	add("done", StatusSucceeded, now.Add(-time.Hour))
+	add("dead", StatusDeadLettered, now.Add(-time.Hour))
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want []string
+	}{
+		{name: "due and expired leases, oldest first", at: now, want: []string{"stale", "due"}},
+		{name: "claimed deliveries are leased", at: now.Add(30 * time.Second), want: []string{"leased"}},
+		{name: "crashed claims are reclaimed once the lease runs out", at: now.Add(2 * time.Minute), want: []string{"due", "later", "stale", "leased"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claimed, err := repo.ClaimDue(ctx, tt.at, time.Minute, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, d := range claimed {
+				got = append(got, d.ID)
+				if d.Status != StatusDelivering || !d.NextAttemptAt.Equal(tt.at.Add(time.Minute)) {
+					t.Errorf("%s: status %s, lease until %s", d.ID, d.Status, d.NextAttemptAt)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("claimed %v, want %v", got, tt.want)
+			}
+			if tt.at.After(now.Add(time.Minute)) {
+				// Leases taken at the same time tie
+				sort.Strings(got[:3])
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("claimed %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	if claimed, _ := repo.ClaimDue(ctx, now.Add(3*time.Minute), time.Minute, 1); len(claimed) != 1 {
+		t.Errorf("claimed %d deliveries with limit 1", len(claimed))
+	}
+}
+
+// newTestDispatcher returns a dispatcher that trusts srv's certificate and
+// allows its loopback address
+func newTestDispatcher(repo Repository, srv *httptest.Server) *Dispatcher {
+	d := NewDispatcher(repo)
+	d.client = srv.Client()
+	d.checkTarget = func(*url.URL) error { return nil }
+	return d
+}
+
+func TestDispatcherDelivers(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRepository()
+
+	var received atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !Verify("0123456789abcdef", r.Header.Get(HeaderSignature), body) {
+			t.Error("signature does not verify")
+		}
+		if r.Header.Get(HeaderIdempotencyKey) != "evt-1" {
+			t.Errorf("%s = %q", HeaderIdempotencyKey, r.Header.Get(HeaderIdempotencyKey))
+		}
+		received.Add(1)
+	}))
+	defer srv.Close()
+
+	sub := &Subscription{ID: "sub-1", URL: srv.URL, Secret: "0123456789abcdef"}
+	if err := repo.SaveSubscription(ctx, sub); err != nil {
+		t.Fatal(err)
+	}
+
+	d := newTestDispatcher(repo, srv)
+	event := events.Event{ID: "evt-1", Type: events.UserCreated, Data: json.RawMessage(`{}`)}
+	for range 2 {
+		if err := d.HandleEvent(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.dispatchDue(ctx)
+	d.wg.Wait()
+
+	delivery, err := repo.GetDelivery(ctx, deliveryID("evt-1", "sub-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != StatusSucceeded || len(delivery.Attempts) != 1 || received.Load() != 1 {
+		t.Errorf("status %s after %d attempts, %d received", delivery.Status, len(delivery.Attempts), received.Load())
+	}
+}
+
+func TestDispatcherDeadLettersInternalTargets(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRepository()
+
+	var received atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	defer srv.Close()
+
+	// Stored before targets were restricted
+	if err := repo.SaveSubscription(ctx, &Subscription{ID: "sub-1", URL: srv.URL, Secret: "0123456789abcdef"}); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDispatcher(repo)
+	d.client = srv.Client()
+	if err := d.HandleEvent(ctx, events.Event{ID: "evt-1", Type: events.UserCreated}); err != nil {
+		t.Fatal(err)
+	}
+	d.dispatchDue(ctx)
+	d.wg.Wait()
+
+	delivery, err := repo.GetDelivery(ctx, deliveryID("evt-1", "sub-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != StatusDeadLettered || received.Load() != 0 {
+		t.Errorf("status %s, %d received; want dead-lettered without a request", delivery.Status, received.Load())
+	}
+}
+
+func TestDispatcherRetriesFailures(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRepository()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://169.254.169.254/", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	if err := repo.SaveSubscription(ctx, &Subscription{ID: "sub-1", URL: srv.URL, Secret: "0123456789abcdef"}); err != nil {
+		t.Fatal(err)
+	}
+	d := newTestDispatcher(repo, srv)
+	d.client.CheckRedirect = newClient().CheckRedirect
+	if err := d.HandleEvent(ctx, events.Event{ID: "evt-1", Type: events.UserCreated}); err != nil {
+		t.Fatal(err)
+	}
+	d.dispatchDue(ctx)
+	d.wg.Wait()
+
+	delivery, err := repo.GetDelivery(ctx, deliveryID("evt-1", "sub-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != StatusPending || delivery.FailedAttempts != 1 || delivery.Attempts[0].ResponseStatus != http.StatusFound {
+		t.Errorf("status %s, %d failed attempts, %+v; want a retried 302", delivery.Status, delivery.FailedAttempts, delivery.Attempts)
+	}
+	if !delivery.NextAttemptAt.After(time.Now()) {
+		t.Errorf("next attempt %s is not backed off", delivery.NextAttemptAt)
+	}
+}
+
+// unavailableSubscriptions fails subscription lookups as a storage outage
+// would
+type unavailableSubscriptions struct {
+	Repository
+}
+
+func (unavailableSubscriptions) GetSubscription(context.Context, string) (*Subscription, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestDispatcherSubscriptionLookup(t *testing.T) {
+	tests := []struct {
+		name       string
+		repo       func(Repository) Repository
+		wantStatus string
+		wantTried  int
+	}{
+		{
+			name:       "deleted subscription is dead-lettered",
+			repo:       func(r Repository) Repository { return r },
+			wantStatus: StatusDeadLettered,
+			wantTried:  1,
+		},
+		{
+			name:       "lookup failure is retried",
+			repo:       func(r Repository) Repository { return unavailableSubscriptions{r} },
+			wantStatus: StatusPending,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewInMemoryRepository()
+			now := time.Now().UTC()
+			if err := repo.AddDelivery(ctx, &Delivery{ID: "del-1", SubscriptionID: "sub-1", Status: StatusPending, NextAttemptAt: now, CreatedAt: now}); err != nil {
+				t.Fatal(err)
+			}
+
+			d := NewDispatcher(tt.repo(repo))
+			d.dispatchDue(ctx)
+			d.wg.Wait()
+
+			delivery, err := repo.GetDelivery(ctx, "del-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if delivery.Status != tt.wantStatus || len(delivery.Attempts) != tt.wantTried || delivery.FailedAttempts != 0 {
+				t.Errorf("status %s, attempts %+v, %d failed; want %s with %d attempts", delivery.Status, delivery.Attempts, delivery.FailedAttempts, tt.wantStatus, tt.wantTried)
+			}
+			if tt.wantStatus == StatusPending && !delivery.NextAttemptAt.After(now.Add(InitialBackoff/2)) {
+				t.Errorf("next attempt %s is not backed off", delivery.NextAttemptAt)
+			}
+		})
+	}
+}
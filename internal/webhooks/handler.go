@@ -0,0 +1,176 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	appErr "example.com/myapp/internal/errors"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the webhook routes with appropriate middleware
+// Middleware is passed as parameters to avoid circular imports
+func (h *Handler) RegisterRoutes(r chi.Router, loggingMw, authMw, adminMw func(http.Handler) http.Handler) {
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Use(loggingMw)
+		r.Use(authMw)
+		r.Use(adminMw)
+
+		r.Post("/", h.CreateSubscription)
+		r.Get("/", h.GetAllSubscriptions)
+
+		r.Route("/deliveries", func(r chi.Router) {
+			r.Get("/", h.ListDeliveries)
+			r.Get("/{id}", h.GetDelivery)
+			r.Post("/{id}/redeliver", h.Redeliver)
+		})
+
+		r.Route("/{id}", func(r chi.Router) {
+			r.Get("/", h.GetSubscription)
+			r.Delete("/", h.DeleteSubscription)
+			r.Get("/deliveries", h.ListSubscriptionDeliveries)
+		})
+	})
+}
+
+// CreateSubscription registers a webhook - POST /webhooks
+func (h *Handler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var req CreateSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to decode request", "error", err)
+		appErr.WriteError(w, r, appErr.BadRequest("invalid request body"))
+		return
+	}
+
+	sub, err := h.service.CreateSubscription(r.Context(), &req)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create webhook subscription", "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Webhook subscription created", "id", sub.ID, "url", sub.URL)
+	respondJSON(w, http.StatusCreated, &CreateSubscriptionResponse{Subscription: sub, Secret: sub.Secret})
+}
+
+// GetAllSubscriptions lists webhooks - GET /webhooks
+func (h *Handler) GetAllSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.service.GetAllSubscriptions(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get webhook subscriptions", "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &SubscriptionListResponse{Total: len(subs), Subscriptions: subs})
+}
+
+// GetSubscription retrieves a webhook - GET /webhooks/{id}
+func (h *Handler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	sub, err := h.service.GetSubscription(r.Context(), id)
+	if err != nil {
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, sub)
+}
+
+// DeleteSubscription removes a webhook - DELETE /webhooks/{id}
+func (h *Handler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := h.service.DeleteSubscription(r.Context(), id); err != nil {
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Webhook subscription deleted", "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries returns the delivery log -
+// GET /webhooks/deliveries?subscription_id=&event_type=&status=&limit=
+func (h *Handler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	filter, err := ParseDeliveryFilter(r.URL.Query())
+	if err != nil {
+		appErr.WriteError(w, r, err)
+		return
+	}
+	h.listDeliveries(w, r, filter)
+}
+
+// ListSubscriptionDeliveries returns the delivery log of one webhook -
+// GET /webhooks/{id}/deliveries?event_type=&status=&limit=
+func (h *Handler) ListSubscriptionDeliveries(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if _, err := h.service.GetSubscription(r.Context(), id); err != nil {
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	filter, err := ParseDeliveryFilter(r.URL.Query())
+	if err != nil {
+		appErr.WriteError(w, r, err)
+		return
+	}
+	filter.SubscriptionID = id
+	h.listDeliveries(w, r, filter)
+}
+
+func (h *Handler) listDeliveries(w http.ResponseWriter, r *http.Request, filter *DeliveryFilter) {
+	deliveries, err := h.service.ListDeliveries(r.Context(), *filter)
+	if err != nil {
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, &DeliveryListResponse{Total: len(deliveries), Deliveries: deliveries})
+}
+
+// GetDelivery retrieves a delivery and its attempts - GET /webhooks/deliveries/{id}
+func (h *Handler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	delivery, err := h.service.GetDelivery(r.Context(), id)
+	if err != nil {
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, delivery)
+}
+
+// Redeliver requeues a dead-lettered delivery - POST /webhooks/deliveries/{id}/redeliver
+func (h *Handler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	delivery, err := h.service.Redeliver(r.Context(), id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to redeliver webhook", "id", id, "error", err)
+		appErr.WriteError(w, r, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Webhook delivery requeued", "id", id)
+	respondJSON(w, http.StatusAccepted, delivery)
+}
+
+func respondJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,214 @@
+package webhooks
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	appErr "example.com/myapp/internal/errors"
+)
+
+// InMemoryRepository is an in-memory implementation of Repository
+type InMemoryRepository struct {
+	mu            sync.RWMutex
+	subscriptions map[string]*Subscription
+	deliveries    map[string]*Delivery
+}
+
+// NewInMemoryRepository creates a new in-memory webhook repository
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		subscriptions: make(map[string]*Subscription),
+		deliveries:    make(map[string]*Delivery),
+	}
+}
+
+// SaveSubscription stores a subscription, replacing any with the same ID
+func (r *InMemoryRepository) SaveSubscription(ctx context.Context, sub *Subscription) error {
+	if err := ctx.Err(); err != nil {
+		return appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if sub.ID == "" {
+		return appErr.BadRequest("subscription ID is required")
+	}
+	r.subscriptions[sub.ID] = cloneSubscription(sub)
+	return nil
+}
+
+// GetSubscription retrieves a subscription by ID
+func (r *InMemoryRepository) GetSubscription(ctx context.Context, id string) (*Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sub, exists := r.subscriptions[id]
+	if !exists {
+		return nil, appErr.NotFound("webhook subscription not found")
+	}
+	return cloneSubscription(sub), nil
+}
+
+// GetAllSubscriptions retrieves all subscriptions, oldest first
+func (r *InMemoryRepository) GetAllSubscriptions(ctx context.Context) ([]*Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subs := make([]*Subscription, 0, len(r.subscriptions))
+	for _, sub := range r.subscriptions {
+		subs = append(subs, cloneSubscription(sub))
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+	})
+	return subs, nil
+}
+
+// DeleteSubscription removes a subscription. Its deliveries are kept.
+func (r *InMemoryRepository) DeleteSubscription(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.subscriptions[id]; !exists {
+		return appErr.NotFound("webhook subscription not found")
+	}
+	delete(r.subscriptions, id)
+	return nil
+}
+
+// AddDelivery stores a delivery unless one with the same ID exists
+func (r *InMemoryRepository) AddDelivery(ctx context.Context, delivery *Delivery) error {
+	if err := ctx.Err(); err != nil {
+		return appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if delivery.ID == "" {
+		return appErr.BadRequest("delivery ID is required")
+	}
+	if _, exists := r.deliveries[delivery.ID]; exists {
+		return appErr.Conflict("webhook delivery already exists")
+	}
+	r.deliveries[delivery.ID] = cloneDelivery(delivery)
+	return nil
+}
+
+// SaveDelivery stores a delivery, replacing any with the same ID
+func (r *InMemoryRepository) SaveDelivery(ctx context.Context, delivery *Delivery) error {
+	if err := ctx.Err(); err != nil {
+		return appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if delivery.ID == "" {
+		return appErr.BadRequest("delivery ID is required")
+	}
+	r.deliveries[delivery.ID] = cloneDelivery(delivery)
+	return nil
+}
+
+// GetDelivery retrieves a delivery by ID
+func (r *InMemoryRepository) GetDelivery(ctx context.Context, id string) (*Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	delivery, exists := r.deliveries[id]
+	if !exists {
+		return nil, appErr.NotFound("webhook delivery not found")
+	}
+	return cloneDelivery(delivery), nil
+}
+
+// ListDeliveries retrieves matching deliveries, newest first
+func (r *InMemoryRepository) ListDeliveries(ctx context.Context, filter DeliveryFilter) ([]*Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := make([]*Delivery, 0)
+	for _, d := range r.deliveries {
+		if filter.Matches(d) {
+			deliveries = append(deliveries, cloneDelivery(d))
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+	if filter.Limit > 0 && len(deliveries) > filter.Limit {
+		deliveries = deliveries[:filter.Limit]
+	}
+	return deliveries, nil
+}
+
+// ClaimDue marks due pending deliveries and deliveries with an expired
+// lease as delivering
+func (r *InMemoryRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []*Delivery
+	for _, d := range r.deliveries {
+		if (d.Status == StatusPending || d.Status == StatusDelivering) && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*Delivery, 0, len(due))
+	for _, d := range due {
+		d.Status = StatusDelivering
+		d.NextAttemptAt = now.Add(lease)
+		d.UpdatedAt = now
+		claimed = append(claimed, cloneDelivery(d))
+	}
+	return claimed, nil
+}
+
+func cloneSubscription(sub *Subscription) *Subscription {
+	c := *sub
+	c.EventTypes = make([]string, len(sub.EventTypes))
+	copy(c.EventTypes, sub.EventTypes)
+	return &c
+}
+
+func cloneDelivery(delivery *Delivery) *Delivery {
+	c := *delivery
+	c.Attempts = make([]Attempt, len(delivery.Attempts))
+	copy(c.Attempts, delivery.Attempts)
+	return &c
+}
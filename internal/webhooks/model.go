@@ -0,0 +1,116 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Delivery statuses
+const (
+	StatusPending      = "pending"
+	StatusDelivering   = "delivering"
+	StatusSucceeded    = "succeeded"
+	StatusDeadLettered = "dead_lettered"
+)
+
+// DeliveryStatuses lists every delivery status
+var DeliveryStatuses = []string{StatusPending, StatusDelivering, StatusSucceeded, StatusDeadLettered}
+
+// Subscription is a URL that receives events of the listed types. An empty
+// EventTypes list receives every event.
+type Subscription struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Secret     string    `json:"-"` // HMAC key; only returned when the subscription is created
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Wants reports whether the subscription receives events of eventType
+func (s *Subscription) Wants(eventType string) bool {
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is one event sent to one subscription, with every attempt made.
+// FailedAttempts counts failures since the delivery was queued or last
+// redelivered and drives backoff and dead-lettering. While delivering,
+// NextAttemptAt is when the claim's lease expires.
+type Delivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	FailedAttempts int             `json:"failed_attempts"`
+	Attempts       []Attempt       `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	// TraceParent is the W3C trace context the event was published in;
+	// every attempt is traced as its child
+	TraceParent string `json:"trace_parent,omitempty"`
+}
+
+// Attempt is the outcome of one HTTP request for a delivery
+type Attempt struct {
+	At             time.Time `json:"at"`
+	ResponseStatus int       `json:"response_status,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	DurationMs     int64     `json:"duration_ms"`
+}
+
+// DeliveryFilter selects deliveries. Empty fields match everything.
+type DeliveryFilter struct {
+	SubscriptionID string
+	EventType      string
+	Status         string
+	Limit          int
+}
+
+// Matches reports whether d satisfies every criterion of f except Limit
+func (f DeliveryFilter) Matches(d *Delivery) bool {
+	switch {
+	case f.SubscriptionID != "" && d.SubscriptionID != f.SubscriptionID:
+		return false
+	case f.EventType != "" && d.EventType != f.EventType:
+		return false
+	case f.Status != "" && d.Status != f.Status:
+		return false
+	}
+	return true
+}
+
+// CreateSubscriptionRequest registers a webhook. A secret is generated
+// when none is supplied.
+type CreateSubscriptionRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"`
+}
+
+// CreateSubscriptionResponse is the only response that includes the secret
+type CreateSubscriptionResponse struct {
+	*Subscription
+	Secret string `json:"secret"`
+}
+
+// SubscriptionListResponse is the response when listing subscriptions
+type SubscriptionListResponse struct {
+	Total         int             `json:"total"`
+	Subscriptions []*Subscription `json:"subscriptions"`
+}
+
+// DeliveryListResponse is the response when listing deliveries
+type DeliveryListResponse struct {
+	Total      int         `json:"total"`
+	Deliveries []*Delivery `json:"deliveries"`
+}
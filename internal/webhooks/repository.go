@@ -0,0 +1,28 @@
+package webhooks
+
+import (
+	"context"
+	"time"
+)
+
+// Repository defines the interface for webhook persistence. Subscriptions
+// and deliveries are returned as copies.
+type Repository interface {
+	SaveSubscription(ctx context.Context, sub *Subscription) error
+	GetSubscription(ctx context.Context, id string) (*Subscription, error)
+	GetAllSubscriptions(ctx context.Context) ([]*Subscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+
+	// AddDelivery stores a new delivery, returning a Conflict AppError when
+	// one with the same ID exists
+	AddDelivery(ctx context.Context, delivery *Delivery) error
+	SaveDelivery(ctx context.Context, delivery *Delivery) error
+	GetDelivery(ctx context.Context, id string) (*Delivery, error)
+	// ListDeliveries returns matching deliveries, newest first
+	ListDeliveries(ctx context.Context, filter DeliveryFilter) ([]*Delivery, error)
+	// ClaimDue moves up to limit deliveries to StatusDelivering and returns
+	// them, oldest first: pending ones whose next attempt is due at now and
+	// delivering ones whose lease has expired. Claimed deliveries are leased
+	// until now+lease by setting NextAttemptAt.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Delivery, error)
+}
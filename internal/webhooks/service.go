@@ -0,0 +1,179 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	appErr "example.com/myapp/internal/errors"
+)
+
+// Query limits for the delivery log
+const (
+	DefaultDeliveryLimit = 100
+	MaxDeliveryLimit     = 1000
+)
+
+type Service struct {
+	repo       Repository
+	dispatcher *Dispatcher
+}
+
+func NewService(repo Repository, dispatcher *Dispatcher) *Service {
+	return &Service{
+		repo:       repo,
+		dispatcher: dispatcher,
+	}
+}
+
+// CreateSubscription registers a webhook URL, generating a secret when the
+// request does not supply one
+func (s *Service) CreateSubscription(ctx context.Context, req *CreateSubscriptionRequest) (*Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, appErr.Internal("failed to generate webhook secret", err)
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	sub := &Subscription{
+		ID:         uuid.New().String(),
+		URL:        req.URL,
+		EventTypes: dedupe(req.EventTypes),
+		Secret:     secret,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := s.repo.SaveSubscription(ctx, sub); err != nil {
+		slog.ErrorContext(ctx, "Failed to save webhook subscription", "error", err)
+		return nil, appErr.Internal("failed to save webhook subscription", err)
+	}
+	return sub, nil
+}
+
+// GetSubscription retrieves a subscription by ID
+func (s *Service) GetSubscription(ctx context.Context, id string) (*Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	sub, err := s.repo.GetSubscription(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get webhook subscription", "id", id, "error", err)
+		return nil, err
+	}
+	return sub, nil
+}
+
+// GetAllSubscriptions retrieves every subscription
+func (s *Service) GetAllSubscriptions(ctx context.Context) ([]*Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	subs, err := s.repo.GetAllSubscriptions(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get webhook subscriptions", "error", err)
+		return nil, appErr.Internal("failed to retrieve webhook subscriptions", err)
+	}
+	return subs, nil
+}
+
+// DeleteSubscription removes a subscription. Pending deliveries to it are
+// dead-lettered when they are next attempted.
+func (s *Service) DeleteSubscription(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return appErr.Internal("context cancelled", err)
+	}
+
+	if err := s.repo.DeleteSubscription(ctx, id); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete webhook subscription", "id", id, "error", err)
+		return err
+	}
+	return nil
+}
+
+// ListDeliveries returns the delivery log, newest first
+func (s *Service) ListDeliveries(ctx context.Context, filter DeliveryFilter) ([]*Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	deliveries, err := s.repo.ListDeliveries(ctx, filter)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list webhook deliveries", "error", err)
+		return nil, appErr.Internal("failed to retrieve webhook deliveries", err)
+	}
+	return deliveries, nil
+}
+
+// GetDelivery retrieves a delivery with its attempts
+func (s *Service) GetDelivery(ctx context.Context, id string) (*Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	delivery, err := s.repo.GetDelivery(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get webhook delivery", "id", id, "error", err)
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Redeliver requeues a dead-lettered delivery with a fresh retry budget
+func (s *Service) Redeliver(ctx context.Context, id string) (*Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, appErr.Internal("context cancelled", err)
+	}
+
+	delivery, err := s.repo.GetDelivery(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get webhook delivery for redelivery", "id", id, "error", err)
+		return nil, err
+	}
+	if delivery.Status != StatusDeadLettered {
+		return nil, appErr.Conflict("only dead-lettered deliveries can be redelivered")
+	}
+	if _, err := s.repo.GetSubscription(ctx, delivery.SubscriptionID); err != nil {
+		return nil, appErr.Conflict("the delivery's subscription no longer exists")
+	}
+
+	now := time.Now().UTC()
+	delivery.Status = StatusPending
+	delivery.FailedAttempts = 0
+	delivery.NextAttemptAt = now
+	delivery.UpdatedAt = now
+	if err := s.repo.SaveDelivery(ctx, delivery); err != nil {
+		slog.ErrorContext(ctx, "Failed to requeue webhook delivery", "id", id, "error", err)
+		return nil, appErr.Internal("failed to requeue webhook delivery", err)
+	}
+	s.dispatcher.Notify()
+	return delivery, nil
+}
+
+// dedupe drops repeated values, keeping the first occurrence
+func dedupe(values []string) []string {
+	result := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,52 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderDeliveryID     = "X-Webhook-Delivery"
+	HeaderEventType      = "X-Webhook-Event"
+	HeaderSignature      = "X-Webhook-Signature"
+	HeaderIdempotencyKey = "Idempotency-Key" // the event ID; identical across redeliveries
+)
+
+// Sign returns the signature header value for a payload sent at timestamp
+// (Unix seconds): "t=<timestamp>,v1=<hex HMAC-SHA256 of "<timestamp>.<payload>">".
+// Receivers should recompute the HMAC with their secret, compare in
+// constant time and reject stale timestamps to prevent replays.
+func Sign(secret string, timestamp int64, payload []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	return "t=" + ts + ",v1=" + computeMAC(secret, ts, payload)
+}
+
+// Verify checks a signature header produced by Sign
+func Verify(secret, header string, payload []byte) bool {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			sig = value
+		}
+	}
+	if ts == "" || sig == "" {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(computeMAC(secret, ts, payload)))
+}
+
+func computeMAC(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,103 @@
+package webhooks
+
+import (
+	"errors"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestCheckTarget(t *testing.T) {
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{url: "https://hooks.example.com/in", allowed: true},
+		{url: "https://hooks.example.com:8443/in", allowed: true},
+		{url: "https://93.184.216.34/in", allowed: true},
+		{url: "https://[2606:2800:220:1::]/in", allowed: true},
+		{url: "http://hooks.example.com/in"},
+		{url: "ftp://hooks.example.com/in"},
+		{url: "https:///in"},
+		{url: "https://localhost/in"},
+		{url: "https://LOCALHOST./in"},
+		{url: "https://api.localhost/in"},
+		{url: "https://127.0.0.1/in"},
+		{url: "https://[::1]/in"},
+		{url: "https://10.1.2.3/in"},
+		{url: "https://172.16.0.1/in"},
+		{url: "https://192.168.1.1/in"},
+		{url: "https://169.254.169.254/latest/meta-data"},
+		{url: "https://[fe80::1]/in"},
+		{url: "https://[fd00::1]/in"},
+		{url: "https://0.0.0.0/in"},
+		{url: "https://100.64.0.1/in"},
+		{url: "https://[::ffff:127.0.0.1]/in"},
+		{url: "https://0.1.2.3/in"},
+		{url: "https://100.127.255.254/in"},
+		{url: "https://192.0.0.8/in"},
+		{url: "https://198.18.0.1/in"},
+		{url: "https://198.19.255.255/in"},
+		{url: "https://240.0.0.1/in"},
+		{url: "https://255.255.255.255/in"},
+		{url: "https://224.0.0.1/in"},
+		{url: "https://[64:ff9b::a00:1]/in"},
+		{url: "https://[ff02::1]/in"},
+		{url: "https://100.128.0.1/in", allowed: true},
+		{url: "https://198.20.0.1/in", allowed: true},
+		{url: "https://192.0.1.1/in", allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checkTarget(u)
+			if (err == nil) != tt.allowed {
+				t.Errorf("checkTarget() error = %v, want allowed %v", err, tt.allowed)
+			}
+			if err != nil && !errors.Is(err, errNotPublic) {
+				t.Errorf("checkTarget() error = %v, want errNotPublic", err)
+			}
+		})
+	}
+}
+
+func TestValidateSubscriptionURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "https://hooks.example.com/in"},
+		{url: "http://hooks.example.com/in", wantErr: true},
+		{url: "https://127.0.0.1:9000/in", wantErr: true},
+		{url: "https://169.254.169.254/", wantErr: true},
+		{url: "not a url", wantErr: true},
+		{url: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := (&CreateSubscriptionRequest{URL: tt.url}).Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientRefusesInternalAddresses(t *testing.T) {
+	// A public name can resolve to an internal address; the dialer checks
+	// the address actually connected to
+	for _, addr := range []string{"127.0.0.1", "10.0.0.1", "[::1]"} {
+		_, err := newClient().Get("https://" + addr + ":1/")
+		if !errors.Is(err, errNotPublic) {
+			t.Errorf("GET %s error = %v, want errNotPublic", addr, err)
+		}
+	}
+	if err := checkAddr(netip.MustParseAddr("8.8.8.8")); err != nil {
+		t.Errorf("checkAddr(8.8.8.8) = %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package webhooks
+
+import (
+	"fmt"
+	"net/url"
+
+	"example.com/myapp/internal/events"
+	"example.com/myapp/internal/validation"
+)
+
+// Field limits for subscription requests
+const (
+	MaxURLLength    = 2048
+	MinSecretLength = 16
+	MaxSecretLength = 256
+)
+
+// Validate checks a subscription request, reporting every invalid field
+func (req *CreateSubscriptionRequest) Validate() error {
+	v := validation.New()
+	v.Required("url", req.URL).MaxLength("url", req.URL, MaxURLLength)
+	if req.URL != "" {
+		u, err := url.Parse(req.URL)
+		v.Check(err == nil && checkTarget(u) == nil, "url", "must be an https URL of a public host")
+	}
+	if req.Secret != "" {
+		v.MinLength("secret", req.Secret, MinSecretLength).MaxLength("secret", req.Secret, MaxSecretLength)
+	}
+	v.MaxItems("event_types", len(req.EventTypes), len(events.Types))
+	for i, t := range req.EventTypes {
+		v.Required(fmt.Sprintf("event_types[%d]", i), t).OneOf(fmt.Sprintf("event_types[%d]", i), t, events.Types...)
+	}
+	return v.Err()
+}
+
+// ParseDeliveryFilter validates the query parameters of the delivery log
+func ParseDeliveryFilter(values url.Values) (*DeliveryFilter, error) {
+	v := validation.New()
+
+	filter := &DeliveryFilter{
+		SubscriptionID: values.Get("subscription_id"),
+		EventType:      values.Get("event_type"),
+		Status:         values.Get("status"),
+		Limit:          v.Int("limit", values.Get("limit"), DefaultDeliveryLimit),
+	}
+	v.OneOf("event_type", filter.EventType, events.Types...)
+	v.OneOf("status", filter.Status, DeliveryStatuses...)
+	v.Range("limit", filter.Limit, 1, MaxDeliveryLimit)
+
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
@@ -2,37 +2,101 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
-	"time"
 
+	"example.com/myapp/internal/config"
 	"example.com/myapp/internal/container"
+	"example.com/myapp/internal/logging"
+	"example.com/myapp/internal/media"
 	"example.com/myapp/internal/routes"
+	"example.com/myapp/internal/tracing"
 )
 
 func main() {
-	// Setup structured logging
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	if len(os.Args) > 1 && os.Args[1] == "issue-token" {
+		os.Exit(issueToken(os.Args[2:]))
+	}
+
+	// Load configuration from file, environment and flags. Reloads repeat
+	// the same steps, so the file, environment and flags keep their
+	// precedence.
+	load := func() (*config.Config, error) {
+		return config.Load(os.Args[1:], os.Getenv, media.ValidateConfig)
+	}
+	cfg, err := load()
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, "Usage of", os.Args[0]+":")
+		config.Usage(os.Stderr)
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// Setup structured logging; the level follows configuration reloads
+	var logLevel slog.LevelVar
+	setLogLevel := func(cfg *config.Config) {
+		level, _ := config.ParseLogLevel(cfg.Log.Level)
+		logLevel.Set(level)
+	}
+	setLogLevel(cfg)
+	logger := slog.New(logging.NewContextHandler(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: &logLevel})))
 	slog.SetDefault(logger)
 
 	logger.Info("Starting application")
+	if cfg.Auth.TokenSecret == "" {
+		logger.Warn("auth.token_secret is not set; bearer tokens and session cookies are trusted without verification")
+	}
+
+	// Export spans when tracing is enabled
+	var traceProvider *tracing.Provider
+	switch cfg.Tracing.Exporter {
+	case "stdout":
+		traceProvider = tracing.NewProvider(tracing.NewStdoutExporter(os.Stdout), cfg.Tracing.SampleRate)
+	case "otlp":
+		traceProvider = tracing.NewProvider(tracing.NewOTLPExporter(cfg.Tracing.OTLPEndpoint, cfg.Tracing.ServiceName), cfg.Tracing.SampleRate)
+	}
+	tracing.SetProvider(traceProvider)
+
+	settings := config.NewStore(cfg, load)
+	settings.OnReload(setLogLevel)
 
 	// Initialize container with all dependencies
-	c := container.NewContainer()
+	c := container.NewContainer(settings)
+
+	// Start background workers; they stop when the server shuts down
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		c.OutboxRelay.Run(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		c.WebhookDispatcher.Run(workerCtx)
+	}()
 
 	// Setup routes
 	r := routes.SetupRoutes(c)
 
 	// Create HTTP server
 	srv := &http.Server{
-		Addr:         ":8080",
+		Addr:         cfg.Server.Addr,
 		Handler:      r,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
 	// Channel to listen for errors from server
@@ -44,6 +108,17 @@ func main() {
 		serverErrors <- srv.ListenAndServe()
 	}()
 
+	// Reload configuration on SIGHUP; the server keeps running and an
+	// invalid configuration leaves the current one active
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for range hupChan {
+			logger.Info("Received SIGHUP, reloading configuration")
+			settings.Reload()
+		}
+	}()
+
 	// Setup signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
@@ -57,8 +132,12 @@ func main() {
 	case sig := <-sigChan:
 		logger.Info("Received signal, shutting down", "signal", sig)
 
+		// Fail readiness first and give load balancers time to notice
+		// before the listener closes
+		c.Health.Drain(cfg.Server.DrainDelay)
+
 		// Create a context with timeout for graceful shutdown
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 		defer cancel()
 
 		// Shutdown the server
@@ -67,6 +146,17 @@ func main() {
 			os.Exit(1)
 		}
 
+		// Let in-flight webhook deliveries finish
+		stopWorkers()
+		workers.Wait()
+
+		// Export the remaining spans
+		if traceProvider != nil {
+			if err := traceProvider.Shutdown(ctx); err != nil {
+				logger.Error("Trace export shutdown error", "error", err)
+			}
+		}
+
 		logger.Info("Server shutdown complete")
 	}
-}
\ No newline at end of file
+}